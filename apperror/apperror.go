@@ -0,0 +1,109 @@
+// Package apperror defines the typed errors handlers return and the fiber
+// ErrorHandler that renders them as RFC 7807 problem+json responses.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Kind classifies an application error and decides its HTTP status
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+)
+
+var kindStatus = map[Kind]int{
+	KindInternal:     fiber.StatusInternalServerError,
+	KindNotFound:     fiber.StatusNotFound,
+	KindConflict:     fiber.StatusConflict,
+	KindValidation:   fiber.StatusBadRequest,
+	KindUnauthorized: fiber.StatusUnauthorized,
+}
+
+var kindSlug = map[Kind]string{
+	KindInternal:     "internal",
+	KindNotFound:     "not-found",
+	KindConflict:     "conflict",
+	KindValidation:   "validation",
+	KindUnauthorized: "unauthorized",
+}
+
+// Error is an error that is safe to show to clients. Message is rendered as the
+// problem detail, Err is the internal cause and is only ever logged.
+type Error struct {
+	Kind    Kind
+	Message string
+	Fields  map[string]string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status code for the error
+func (e *Error) Status() int {
+	return kindStatus[e.Kind]
+}
+
+// NotFound reports a missing resource, or one that belongs to somebody else
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Conflict reports a request that clashes with the current state of a resource
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+// Validation reports invalid input; fields maps input names to what is wrong with them
+func Validation(message string, fields map[string]string) *Error {
+	return &Error{Kind: KindValidation, Message: message, Fields: fields}
+}
+
+// Unauthorized reports missing or invalid credentials
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+// Internal wraps an unexpected failure. The cause is logged, clients only see message.
+func Internal(message string, err error) *Error {
+	return &Error{Kind: KindInternal, Message: message, Err: err}
+}
+
+// FromValidator turns the error returned by validator.Struct into a validation error
+// listing every failing field
+func FromValidator(err error) *Error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return Validation(err.Error(), nil)
+	}
+
+	fields := map[string]string{}
+	for _, fe := range verrs {
+		name := strings.ToLower(fe.Field())
+		if fe.Param() != "" {
+			fields[name] = fmt.Sprintf("failed on %s=%s", fe.Tag(), fe.Param())
+		} else {
+			fields[name] = fmt.Sprintf("failed on %s", fe.Tag())
+		}
+	}
+	return Validation("Request validation failed", fields)
+}
@@ -0,0 +1,54 @@
+package apperror
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Problem is an RFC 7807 problem details document. Error repeats Detail for
+// clients written against the older {"error": "..."} responses.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// Handler is the fiber ErrorHandler for the whole app. It renders every error
+// returned by a handler as problem+json and logs internal failures.
+func Handler(c *fiber.Ctx, err error) error {
+	problem := Problem{Instance: c.OriginalURL()}
+
+	var appErr *Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &appErr):
+		problem.Status = appErr.Status()
+		problem.Type = "/problems/" + kindSlug[appErr.Kind]
+		problem.Detail = appErr.Message
+		problem.Errors = appErr.Fields
+		if appErr.Kind == KindInternal {
+			log.Printf("%s %s: %v", c.Method(), c.Path(), err)
+		}
+	case errors.As(err, &fiberErr):
+		problem.Status = fiberErr.Code
+		problem.Type = "about:blank"
+		problem.Detail = fiberErr.Message
+	default:
+		log.Printf("%s %s: %v", c.Method(), c.Path(), err)
+		problem.Status = fiber.StatusInternalServerError
+		problem.Type = "/problems/" + kindSlug[KindInternal]
+		problem.Detail = "Internal server error"
+	}
+
+	problem.Title = http.StatusText(problem.Status)
+	problem.Error = problem.Detail
+
+	return c.Status(problem.Status).JSON(problem, "application/problem+json")
+}
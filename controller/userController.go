@@ -1,24 +1,20 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
+	"errors"
 	"log"
 
-	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 
-	"github.com/khanirfan96/To-do-Fullstack-server/database"
-
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
 	helper "github.com/khanirfan96/To-do-Fullstack-server/helpers"
 	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -49,111 +45,243 @@ func VerifyPassword(userPassword string, providedPassword string) (bool, string)
 }
 
 // CreateUser is the api used to tget a single user
-func SignUp() fiber.Handler {
+func SignUp(users repository.UserRepository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-		defer cancel()
+		ctx := c.UserContext()
 		var user models.User
 
 		if err := c.BodyParser(&user); err != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+			return apperror.Validation(err.Error(), nil)
 
 		}
 
 		validationErr := validate.Struct(user)
 		if validationErr != nil {
-			return c.Status(http.StatusBadRequest).JSON(fiber.Map{"error": validationErr.Error()})
+			return apperror.FromValidator(validationErr)
 
 		}
 
-		count, err := database.DB.UserCollection.CountDocuments(ctx, bson.M{"email": user.Email})
+		exists, err := users.EmailExists(ctx, *user.Email)
 		if err != nil {
-			log.Panic(err)
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error occured while checking for the email"})
+			return apperror.Internal("error occured while checking for the email", err)
 		}
 
-		if count > 0 {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "This email already exists"})
+		if exists {
+			return apperror.Conflict("This email already exists")
 		}
 
 		password := HashPassword(*user.Password)
 		user.Password = &password
 
-		count, err = database.DB.UserCollection.CountDocuments(ctx, bson.M{"phone": user.Phone})
-		defer cancel()
+		exists, err = users.PhoneExists(ctx, *user.Phone)
 		if err != nil {
-			log.Panic(err)
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "error occured while checking for the phone number"})
+			return apperror.Internal("error occured while checking for the phone number", err)
 		}
 
-		if count > 0 {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "This phone number already exists"})
+		if exists {
+			return apperror.Conflict("This phone number already exists")
 		}
 
 		user.Created_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		user.Updated_at, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		user.ID = primitive.NewObjectID()
 		user.User_id = user.ID.Hex()
-		token, refreshToken, _ := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
+		user.Body_goal = nil
+		user.Nutrition_targets = nil
+		user.Nutrition_goal = nil
+		token, refreshToken, err := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, user.Token_version)
+		if err != nil {
+			return apperror.Internal("Failed to generate tokens", err)
+		}
 		user.Token = &token
 		user.Refresh_token = &refreshToken
+		user.Token_family = helper.TokenFamily(refreshToken)
 
-		resultInsertionNumber, insertErr := database.DB.UserCollection.InsertOne(ctx, user)
-		if insertErr != nil {
-			return c.Status(http.StatusInternalServerError).JSON(fiber.Map{"error": "User item was not created"})
+		if insertErr := users.Create(ctx, &user); insertErr != nil {
+			return apperror.Internal("User item was not created", insertErr)
 
 		}
 
-		return c.Status(http.StatusOK).JSON(resultInsertionNumber)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"InsertedID": user.ID})
 
 	}
 }
 
 // controller/userController.go
-func Login() fiber.Handler {
+func Login(users repository.UserRepository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-		defer cancel()
-
-		if database.DB.UserCollection == nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not properly initialized"})
-		}
+		ctx := c.UserContext()
 
 		var user models.User
-		var foundUser models.User
 
 		if err := c.BodyParser(&user); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+			return apperror.Validation("Invalid request body", nil)
 		}
 
 		if user.Email == nil || user.Password == nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email and password are required"})
+			return apperror.Validation("Email and password are required", nil)
 		}
 
-		err := database.DB.UserCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&foundUser)
+		foundUser, err := users.FindByEmail(ctx, *user.Email)
 		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid email or password"})
+			if errors.Is(err, repository.ErrNotFound) {
+				return apperror.Unauthorized("Invalid email or password")
 			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+			return apperror.Internal("Database error", err)
 		}
 
 		passwordIsValid, msg := VerifyPassword(*foundUser.Password, *user.Password)
 		if !passwordIsValid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": msg})
+			return apperror.Unauthorized(msg)
 		}
 
-		token, refreshToken, err := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
+		token, refreshToken, err := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, foundUser.Token_version)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to generate tokens"})
+			return apperror.Internal("Failed to generate tokens", err)
 		}
 
-		if err := helper.UpdateAllTokens(token, refreshToken, foundUser.User_id); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": fmt.Sprintf("Failed to update tokens: %v", err),
-			})
+		if err := users.UpdateTokens(ctx, foundUser.User_id, token, refreshToken, helper.TokenFamily(refreshToken)); err != nil {
+			return apperror.Internal("Failed to update tokens", err)
 		}
 
+		foundUser.Token = &token
+		foundUser.Refresh_token = &refreshToken
+
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{"user": foundUser})
 	}
 }
+
+// Refresh exchanges a valid refresh token for a new token pair and rotates the stored refresh token.
+// The id of every rotated refresh token is kept on the denylist, so presenting one again, from
+// whichever family, is reuse and revokes every token issued to the user.
+func Refresh(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+			return apperror.Validation("refresh_token is required", map[string]string{"refresh_token": "failed on required"})
+		}
+
+		claims, msg := helper.ValidateToken(body.RefreshToken)
+		if msg != "" {
+			return apperror.Unauthorized(msg)
+		}
+
+		if claims.Token_type != helper.RefreshToken || claims.Uid == "" || claims.Family == "" || claims.Id == "" {
+			return apperror.Unauthorized("Invalid refresh token")
+		}
+
+		foundUser, err := users.FindByUserID(ctx, claims.Uid)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return apperror.Unauthorized("Invalid refresh token")
+			}
+			return apperror.Internal("Database error", err)
+		}
+
+		if claims.Version != foundUser.Token_version {
+			return apperror.Unauthorized("Refresh token is no longer valid")
+		}
+
+		reused, err := users.IsTokenRevoked(ctx, claims.Id)
+		if err != nil {
+			return apperror.Internal("Failed to verify token", err)
+		}
+
+		if reused {
+			return refreshReused(c, users, foundUser)
+		}
+
+		if foundUser.Refresh_token == nil || *foundUser.Refresh_token != body.RefreshToken {
+			return refreshMismatch(c, users, foundUser, claims.Family)
+		}
+
+		token, refreshToken, err := helper.GenerateTokensForFamily(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, foundUser.Token_version, claims.Family)
+		if err != nil {
+			return apperror.Internal("Failed to generate tokens", err)
+		}
+
+		// The presented token is marked used before it is replaced, so it can never be
+		// exchanged twice without being recognized
+		if err := users.RevokeToken(ctx, claims.Id, foundUser.User_id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+			return apperror.Internal("Failed to update tokens", err)
+		}
+
+		rotated, err := users.RotateTokens(ctx, foundUser.User_id, body.RefreshToken, token, refreshToken)
+		if err != nil {
+			return apperror.Internal("Failed to update tokens", err)
+		}
+
+		if !rotated {
+			// Someone else rotated this token between our read and our write
+			return refreshMismatch(c, users, foundUser, claims.Family)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"token":         token,
+			"refresh_token": refreshToken,
+		})
+	}
+}
+
+// refreshMismatch handles a refresh token that is validly signed and not yet rotated, but no
+// longer the stored one. A token of the active family was superseded by a concurrent rotation,
+// so it is treated as reuse; one of an earlier family was simply replaced by a new login.
+func refreshMismatch(c *fiber.Ctx, users repository.UserRepository, user *models.User, family string) error {
+	if user.Token_family != "" && user.Token_family == family {
+		return refreshReused(c, users, user)
+	}
+
+	return apperror.Unauthorized("Refresh token is no longer valid")
+}
+
+// refreshReused revokes every token of a user whose refresh token was presented twice. Bumping
+// the token version also stops the access tokens already issued to whoever replayed it.
+func refreshReused(c *fiber.Ctx, users repository.UserRepository, user *models.User) error {
+	if err := users.RevokeAllSessions(c.UserContext(), user.User_id); err != nil {
+		log.Printf("Failed to revoke tokens for user %s: %v", user.User_id, err)
+	}
+	return apperror.Unauthorized("Refresh token reuse detected, please log in again")
+}
+
+// Logout revokes the access token used for this request and the stored refresh token
+func Logout(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("Claims").(*helper.SignedDetails)
+		if !ok {
+			return apperror.Unauthorized("No Authorization header provided")
+		}
+
+		if claims.Id != "" {
+			if err := users.RevokeToken(c.UserContext(), claims.Id, claims.Uid, time.Unix(claims.ExpiresAt, 0)); err != nil {
+				return apperror.Internal("Failed to log out", err)
+			}
+		}
+
+		if err := users.RevokeTokens(c.UserContext(), claims.Uid); err != nil {
+			return apperror.Internal("Failed to log out", err)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Logged out successfully"})
+	}
+}
+
+// LogoutEverywhere invalidates every token ever issued to the user
+func LogoutEverywhere(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, ok := c.Locals("Uid").(string)
+		if !ok || uid == "" {
+			return apperror.Unauthorized("No Authorization header provided")
+		}
+
+		if err := users.RevokeAllSessions(c.UserContext(), uid); err != nil {
+			return apperror.Internal("Failed to log out", err)
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Logged out from all devices"})
+	}
+}
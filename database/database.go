@@ -8,15 +8,25 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type DBCollections struct {
-	TodoCollection    *mongo.Collection
-	CalorieCollection *mongo.Collection
-	UserCollection    *mongo.Collection
-	GymCollection     *mongo.Collection
+	TodoCollection         *mongo.Collection
+	CalorieCollection      *mongo.Collection
+	UserCollection         *mongo.Collection
+	GymCollection          *mongo.Collection
+	RevokedTokenCollection *mongo.Collection
+	ProjectCollection      *mongo.Collection
+	WorkoutCollection      *mongo.Collection
+	ExerciseCollection     *mongo.Collection
+	BodyCollection         *mongo.Collection
+	FoodCollection         *mongo.Collection
+	DiaryCollection        *mongo.Collection
 }
 
 var (
@@ -35,6 +45,22 @@ func Initialize() error {
 	}
 
 	setupCollections()
+
+	if err := createIndexes(); err != nil {
+		return fmt.Errorf("failed to create indexes: %v", err)
+	}
+
+	if err := migrate(); err != nil {
+		return fmt.Errorf("failed to migrate documents: %v", err)
+	}
+
+	if err := seedExercises(); err != nil {
+		return fmt.Errorf("failed to seed exercises: %v", err)
+	}
+
+	if err := seedFoods(); err != nil {
+		return fmt.Errorf("failed to seed foods: %v", err)
+	}
 	return nil
 }
 
@@ -74,10 +100,17 @@ func setupCollections() {
 	database := client.Database(dbName)
 
 	DB = DBCollections{
-		TodoCollection:    database.Collection("todolist"),
-		CalorieCollection: database.Collection("calorietracker"),
-		UserCollection:    database.Collection("user"),
-		GymCollection:     database.Collection("gym"),
+		TodoCollection:         database.Collection("todolist"),
+		CalorieCollection:      database.Collection("calorietracker"),
+		UserCollection:         database.Collection("user"),
+		GymCollection:          database.Collection("gym"),
+		RevokedTokenCollection: database.Collection("revokedtokens"),
+		ProjectCollection:      database.Collection("projects"),
+		WorkoutCollection:      database.Collection("workouts"),
+		ExerciseCollection:     database.Collection("exercises"),
+		BodyCollection:         database.Collection("bodymetrics"),
+		FoodCollection:         database.Collection("foods"),
+		DiaryCollection:        database.Collection("fooddiary"),
 	}
 
 	fmt.Printf("Collections initialized:\n")
@@ -85,6 +118,367 @@ func setupCollections() {
 	fmt.Printf("- Calorie Collection: %v\n", DB.CalorieCollection.Name())
 	fmt.Printf("- User Collection: %v\n", DB.UserCollection.Name())
 	fmt.Printf("- Gym Collection: %v\n", DB.GymCollection.Name())
+	fmt.Printf("- Revoked Token Collection: %v\n", DB.RevokedTokenCollection.Name())
+	fmt.Printf("- Project Collection: %v\n", DB.ProjectCollection.Name())
+	fmt.Printf("- Workout Collection: %v\n", DB.WorkoutCollection.Name())
+	fmt.Printf("- Exercise Collection: %v\n", DB.ExerciseCollection.Name())
+	fmt.Printf("- Body Collection: %v\n", DB.BodyCollection.Name())
+	fmt.Printf("- Food Collection: %v\n", DB.FoodCollection.Name())
+	fmt.Printf("- Diary Collection: %v\n", DB.DiaryCollection.Name())
+}
+
+// createIndexes makes sure the indexes the queries rely on exist
+func createIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Denylisted tokens are dropped by MongoDB once they would have expired anyway
+	_, err := DB.RevokedTokenCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "jti", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.UserCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// One index per todo sort order, each prefixed by the owner every listing filters on
+	_, err = DB.TodoCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "due_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "priority", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}, {Key: "due_at", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "project_id", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "tags", Value: 1}}},
+		// Searches match whole words of tasks, without stemming or stop words so that
+		// tasks in any language are found
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "task", Value: "text"}},
+			Options: options.Index().SetDefaultLanguage("none"),
+		},
+		// Completing the same occurrence twice must not spawn its successor twice
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "recurrence.series_id", Value: 1}, {Key: "recurrence.index", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"recurrence.series_id": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.ProjectCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "order", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Every user follows at most one gym plan at a time
+	_, err = DB.GymCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "active", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.WorkoutCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "performed_at", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "exercises.key", Value: 1}, {Key: "performed_at", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Built-in exercises are stored with an empty user_id, so a name is unique among
+	// them and among the exercises of each user
+	_, err = DB.ExerciseCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.BodyCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "measured_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Ingredients are matched against the names and aliases of foods
+	_, err = DB.FoodCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "keys", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.DiaryCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "date", Value: 1}},
+	})
+	return err
+}
+
+// migrate brings documents written by older versions up to the current shape
+func migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Todos created before priorities existed are stored without one; keyset
+	// pagination on priority needs every todo to carry a value
+	_, err := DB.TodoCollection.UpdateMany(ctx,
+		bson.M{"priority": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"priority": 0}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Status used to be a boolean that was left out when false
+	_, err = DB.TodoCollection.UpdateMany(ctx,
+		bson.M{"status": true},
+		bson.M{"$set": bson.M{"status": "done"}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = DB.TodoCollection.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$in": bson.A{false, nil}}},
+		bson.M{"$set": bson.M{"status": "open"}},
+	)
+	if err != nil {
+		return err
+	}
+
+	// Nutrition used to be calculated only, and saved without its source
+	_, err = DB.CalorieCollection.UpdateMany(ctx,
+		bson.M{"nutrition": bson.M{"$ne": nil}, "nutrition.source": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"nutrition.source": models.NutritionCalculated}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := migrateGymPlans(ctx); err != nil {
+		return err
+	}
+
+	// Ingredients used to be one free-text string; decoding parses it into a list
+	cursor, err := DB.CalorieCollection.Find(ctx, bson.M{"ingredients": bson.M{"$type": "string"}})
+	if err != nil {
+		return err
+	}
+	var recipes []models.CalorieTracker
+	if err := cursor.All(ctx, &recipes); err != nil {
+		return err
+	}
+	var writes []mongo.WriteModel
+	for _, recipe := range recipes {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": recipe.ID, "ingredients": bson.M{"$type": "string"}}).
+			SetUpdate(bson.M{"$set": bson.M{"ingredients": recipe.Ingredients}}))
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+	_, err = DB.CalorieCollection.BulkWrite(ctx, writes)
+	return err
+}
+
+// legacyGymName names the gym schedules stored before plans had names
+const legacyGymName = "My schedule"
+
+// migrateGymPlans moves the gym schedules stored before plans belonged to users,
+// which have neither an owner nor a name. With a single user they can only have
+// been theirs and are handed over, the first one becoming the active plan unless
+// the user already follows one. With several users there is no telling whose a
+// schedule was, so they are dropped instead of being kept where nobody sees them.
+func migrateGymPlans(ctx context.Context) error {
+	unowned := bson.M{"user_id": bson.M{"$in": bson.A{nil, ""}}}
+	count, err := DB.GymCollection.CountDocuments(ctx, unowned)
+	if err != nil || count == 0 {
+		return err
+	}
+
+	cursor, err := DB.UserCollection.Find(ctx, bson.M{}, options.Find().SetLimit(2).SetProjection(bson.M{"user_id": 1}))
+	if err != nil {
+		return err
+	}
+	var owners []models.User
+	if err := cursor.All(ctx, &owners); err != nil {
+		return err
+	}
+	if len(owners) != 1 {
+		result, err := DB.GymCollection.DeleteMany(ctx, unowned)
+		if err != nil {
+			return err
+		}
+		log.Printf("Dropped %d gym schedules without an owner", result.DeletedCount)
+		return nil
+	}
+
+	uid := owners[0].User_id
+	now := time.Now()
+	_, err = DB.GymCollection.UpdateMany(ctx,
+		bson.M{"user_id": bson.M{"$in": bson.A{nil, ""}}, "name": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"name": legacyGymName}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = DB.GymCollection.UpdateMany(ctx, unowned,
+		bson.M{"$set": bson.M{"user_id": uid, "active": false, "created_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		return err
+	}
+
+	active, err := DB.GymCollection.CountDocuments(ctx, bson.M{"user_id": uid, "active": true})
+	if err != nil || active > 0 {
+		return err
+	}
+	return DB.GymCollection.FindOneAndUpdate(ctx,
+		bson.M{"user_id": uid},
+		bson.M{"$set": bson.M{"active": true}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	).Err()
+}
+
+// seedExercises brings the built-in exercises in line with models.ExerciseCatalog.
+// Their ids never change, so schedules and workouts referencing them stay valid.
+func seedExercises() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var writes []mongo.WriteModel
+	for _, exercise := range models.ExerciseCatalog() {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": exercise.ID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"name":              exercise.Name,
+					"key":               exercise.Key,
+					"primary_muscles":   exercise.Primary_muscles,
+					"secondary_muscles": exercise.Secondary_muscles,
+					"equipment":         exercise.Equipment,
+					"pattern":           exercise.Pattern,
+					"user_id":           "",
+				},
+				"$setOnInsert": bson.M{"created_at": now, "updated_at": now},
+			}).
+			SetUpsert(true))
+	}
+	_, err := DB.ExerciseCollection.BulkWrite(ctx, writes)
+	return err
+}
+
+// foodBatch bounds the number of foods written per bulk write
+const foodBatch = 1000
+
+// seedFoods loads the built-in food database, followed by the CSV dump named by
+// FOOD_DATA_FILE when it is set (see models.ParseFoods for the formats read).
+// Foods keep their id across imports, so an imported food replaces the built-in
+// one of the same name and importing a dump again updates it. The built-in
+// database is written on every start, but never over a food that was imported.
+func seedFoods() error {
+	catalog := models.FoodCatalog()
+	var imported []models.Food
+	if path := os.Getenv("FOOD_DATA_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		imported, err = models.ParseFoods(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		for i := range imported {
+			imported[i].Source = models.FoodImported
+		}
+		fmt.Printf("Importing %d foods from %s\n", len(imported), path)
+	}
+
+	replaced, err := importedFoods(catalog)
+	if err != nil {
+		return err
+	}
+	builtIn := catalog[:0]
+	for _, food := range catalog {
+		if !replaced[food.ID] {
+			builtIn = append(builtIn, food)
+		}
+	}
+
+	if err := writeFoods(builtIn); err != nil {
+		return err
+	}
+	return writeFoods(imported)
+}
+
+// importedFoods returns the ids of the given foods that are stored as imported
+func importedFoods(foods []models.Food) (map[primitive.ObjectID]bool, error) {
+	ids := make([]primitive.ObjectID, len(foods))
+	for i, food := range foods {
+		ids[i] = food.ID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cursor, err := DB.FoodCollection.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "source": models.FoodImported},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var stored []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	replaced := map[primitive.ObjectID]bool{}
+	for _, food := range stored {
+		replaced[food.ID] = true
+	}
+	return replaced, nil
+}
+
+// writeFoods stores foods in batches, replacing the stored foods of the same id
+func writeFoods(foods []models.Food) error {
+	for start := 0; start < len(foods); start += foodBatch {
+		end := min(start+foodBatch, len(foods))
+		var writes []mongo.WriteModel
+		for _, food := range foods[start:end] {
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": food.ID}).
+				SetReplacement(food).
+				SetUpsert(true))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		_, err := DB.FoodCollection.BulkWrite(ctx, writes)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetContext returns a context with timeout
@@ -1,16 +1,17 @@
 package helper
 
 import (
-	"context"
-	"fmt"
-	"log"
 	"os"
 	"time"
 
-	"github.com/khanirfan96/To-do-Fullstack-server/database"
-
 	jwt "github.com/dgrijalva/jwt-go"
-	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Token types carried in the Token_type claim
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
 )
 
 type SignedDetails struct {
@@ -18,27 +19,43 @@ type SignedDetails struct {
 	First_name string
 	Last_name  string
 	Uid        string
+	Token_type string
+	Family     string
+	Version    int
 	jwt.StandardClaims
 }
 
-// var userCollection = database.DB.UserCollection
-
 var SECRET_KEY string = os.Getenv("SECRET_KEY")
 
 // GenerateAllTokens generates both teh detailed token and refresh token
-func GenerateAllTokens(email string, firstName string, lastName string, uid string) (signedToken string, signedRefreshToken string, err error) {
+func GenerateAllTokens(email string, firstName string, lastName string, uid string, version int) (signedToken string, signedRefreshToken string, err error) {
+	return GenerateTokensForFamily(email, firstName, lastName, uid, version, primitive.NewObjectID().Hex())
+}
+
+// GenerateTokensForFamily generates a token pair whose refresh token belongs to the given family.
+// Every refresh token issued by rotating an earlier one keeps the family of the login that started it.
+// version is the user's token version; bumping it on the user document invalidates every older token.
+func GenerateTokensForFamily(email string, firstName string, lastName string, uid string, version int, family string) (signedToken string, signedRefreshToken string, err error) {
 	claims := &SignedDetails{
 		Email:      email,
 		First_name: firstName,
 		Last_name:  lastName,
 		Uid:        uid,
+		Token_type: AccessToken,
+		Version:    version,
 		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
 		},
 	}
 
 	refreshClaims := &SignedDetails{
+		Uid:        uid,
+		Token_type: RefreshToken,
+		Family:     family,
+		Version:    version,
 		StandardClaims: jwt.StandardClaims{
+			Id:        primitive.NewObjectID().Hex(),
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
 		},
 	}
@@ -46,15 +63,13 @@ func GenerateAllTokens(email string, firstName string, lastName string, uid stri
 	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(SECRET_KEY))
 
 	if err != nil {
-		log.Panic(err)
-		return
+		return "", "", err
 	}
 
 	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(SECRET_KEY))
 
 	if err != nil {
-		log.Panic(err)
-		return
+		return "", "", err
 	}
 
 	return token, refreshToken, err
@@ -89,33 +104,25 @@ func ValidateToken(signedToken string) (claims *SignedDetails, msg string) {
 	return claims, msg
 }
 
-// UpdateAllTokens renews the user tokens when they login
-func UpdateAllTokens(signedToken string, signedRefreshToken string, userId string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	if database.DB.UserCollection == nil {
-		return fmt.Errorf("user collection is not initialized")
-	}
-
-	updateObj := bson.M{
-		"$set": bson.M{
-			"token":         signedToken,
-			"refresh_token": signedRefreshToken,
-			"updated_at":    time.Now(),
+// TokenFamily returns the family of a refresh token we signed, ignoring its expiry.
+// An empty string means the token could not be read.
+func TokenFamily(signedRefreshToken string) string {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(
+		signedRefreshToken,
+		&SignedDetails{},
+		func(token *jwt.Token) (interface{}, error) {
+			return []byte(SECRET_KEY), nil
 		},
-	}
-
-	filter := bson.M{"user_id": userId}
-
-	result, err := database.DB.UserCollection.UpdateOne(ctx, filter, updateObj)
+	)
 	if err != nil {
-		return fmt.Errorf("failed to update tokens: %v", err)
+		return ""
 	}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("no document was updated")
+	claims, ok := token.Claims.(*SignedDetails)
+	if !ok || claims.Token_type != RefreshToken {
+		return ""
 	}
 
-	return nil
+	return claims.Family
 }
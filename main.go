@@ -4,12 +4,20 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/khanirfan96/To-do-Fullstack-server/database"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository/mongodb"
 	"github.com/khanirfan96/To-do-Fullstack-server/router"
 )
 
 func main() {
 	fmt.Println("FullStack TODO Application")
-	r := router.Router()
+
+	if err := database.Initialize(); err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	defer database.Close()
+
+	r := router.Router(mongodb.New(database.DB))
 
 	fmt.Println("Server is getting Started.....")
 	log.Fatal(r.Listen(":8000"))
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// Analytics cover the last defaultAnalyticsWeeks weeks unless asked otherwise, and
+// never more than maxAnalyticsWeeks
+const (
+	defaultAnalyticsWeeks = 12
+	maxAnalyticsWeeks     = 104
+)
+
+// GetMuscleVolume returns the weekly working sets and volume per muscle
+func GetMuscleVolume(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		query, err := parseAnalyticsQuery(c)
+		if err != nil {
+			return err
+		}
+		volumes, err := workouts.MuscleVolume(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load training volume", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(volumes)
+	}
+}
+
+// GetStrengthTrends returns the weekly best estimated one rep max per exercise.
+// exercise narrows the result to a comma separated list of exercise keys.
+func GetStrengthTrends(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		query, err := parseAnalyticsQuery(c)
+		if err != nil {
+			return err
+		}
+		trends, err := workouts.Trends(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load strength trends", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(trends)
+	}
+}
+
+// GetTrainingFrequency returns how often the user trained each week and on average
+func GetTrainingFrequency(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		query, err := parseAnalyticsQuery(c)
+		if err != nil {
+			return err
+		}
+		weeks, err := workouts.Frequency(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load training frequency", err)
+		}
+
+		total := 0
+		for _, week := range weeks {
+			total += week.Workouts
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"weeks":             weeks,
+			"workouts_per_week": math.Round(float64(total)/float64(weeksIn(query))*10) / 10,
+		})
+	}
+}
+
+// GetMuscleBalance returns the average weekly working sets per muscle together
+// with warnings about neglected and imbalanced muscle groups
+func GetMuscleBalance(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		query, err := parseAnalyticsQuery(c)
+		if err != nil {
+			return err
+		}
+		volumes, err := workouts.MuscleVolume(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load training volume", err)
+		}
+
+		weeks := weeksIn(query)
+		totals := models.MuscleTotals(volumes)
+		weekly := map[string]float64{}
+		for muscle, sets := range totals {
+			weekly[muscle] = math.Round(sets/float64(weeks)*10) / 10
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"weeks":         weeks,
+			"sets_per_week": weekly,
+			"warnings":      models.BalanceWarnings(totals, weeks),
+		})
+	}
+}
+
+// parseAnalyticsQuery reads the from, to, tz and exercise query parameters. The
+// default range covers the current week and the weeks before it. Weeks are cut in
+// UTC unless tz names the zone of the user, whatever zone the server runs in.
+func parseAnalyticsQuery(c *fiber.Ctx) (repository.AnalyticsQuery, error) {
+	loc := time.UTC
+	if c.Query("tz") != "" {
+		var err error
+		if loc, err = requestLocation(c); err != nil {
+			return repository.AnalyticsQuery{}, err
+		}
+	}
+	now := time.Now().In(loc)
+	query := repository.AnalyticsQuery{
+		From:     models.StartOfWeek(now, loc).AddDate(0, 0, -7*(defaultAnalyticsWeeks-1)),
+		To:       now,
+		Location: loc,
+	}
+	fields := map[string]string{}
+
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		at, err := parseQueryTime(value, loc)
+		if err != nil {
+			fields[param] = "must be an RFC 3339 time or a YYYY-MM-DD date"
+			continue
+		}
+		if param == "from" {
+			query.From = at
+		} else {
+			query.To = at
+		}
+	}
+	if len(fields) == 0 {
+		switch {
+		case !query.From.Before(query.To):
+			fields["to"] = "must be after from"
+		case weeksIn(query) > maxAnalyticsWeeks:
+			fields["from"] = "must be at most 104 weeks before to"
+		}
+	}
+
+	if exercises := c.Query("exercise"); exercises != "" {
+		for _, key := range strings.Split(exercises, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				query.Keys = append(query.Keys, models.ExerciseKey(key))
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return query, apperror.Validation("Invalid query parameters", fields)
+	}
+	return query, nil
+}
+
+// weeksIn counts the calendar weeks the query range touches
+func weeksIn(query repository.AnalyticsQuery) int {
+	start := models.StartOfWeek(query.From, query.Location)
+	weeks := int(math.Ceil(query.To.Sub(start).Hours() / (7 * 24)))
+	return max(weeks, 1)
+}
@@ -1,26 +1,40 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
 	helper "github.com/khanirfan96/To-do-Fullstack-server/helpers"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // Authz validates token and authorizes users
-func Authentication() fiber.Handler {
+func Authentication(users repository.UserRepository) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		clientToken := c.Get("token")
 		if clientToken == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "No Authorization header provided",
-			})
+			return apperror.Unauthorized("No Authorization header provided")
+		}
+
+		claims, msg := helper.ValidateToken(clientToken)
+		if msg != "" {
+			return apperror.Unauthorized(msg)
+		}
+
+		if claims.Token_type == helper.RefreshToken {
+			return apperror.Unauthorized("Refresh tokens cannot be used for authentication")
+		}
+
+		revoked, err := tokenRevoked(c.UserContext(), users, claims)
+		if err != nil {
+			return apperror.Internal("Failed to verify token", err)
 		}
 
-		claims, err := helper.ValidateToken(clientToken)
-		if err != "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": err,
-			})
+		if revoked {
+			return apperror.Unauthorized("token has been revoked")
 		}
 
 		c.Set("email", claims.Email)
@@ -29,8 +43,31 @@ func Authentication() fiber.Handler {
 		c.Set("uid", claims.Uid)
 
 		c.Locals("Uid", claims.Uid)
+		c.Locals("Claims", claims)
 
 		return c.Next()
 
 	}
 }
+
+// tokenRevoked reports whether a validly signed token has since been revoked, either by
+// logout (its jti is on the denylist) or by logout everywhere (the user's token version moved on)
+func tokenRevoked(ctx context.Context, users repository.UserRepository, claims *helper.SignedDetails) (bool, error) {
+	user, err := users.FindByUserID(ctx, claims.Uid)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if user.Token_version != claims.Version {
+		return true, nil
+	}
+
+	if claims.Id == "" {
+		return false, nil
+	}
+
+	return users.IsTokenRevoked(ctx, claims.Id)
+}
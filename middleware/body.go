@@ -0,0 +1,315 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Moving averages span defaultAverageDays unless the window parameter says
+// otherwise; trends look back over defaultTrendWeeks
+const (
+	defaultAverageDays = 7
+	maxAverageDays     = 90
+	defaultTrendWeeks  = 4
+	maxTrendWeeks      = 52
+)
+
+// GetBodyMetrics lists the measurements taken between from and to, oldest first,
+// each with the moving average of its metrics over the window days before it
+func GetBodyMetrics(body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		loc, err := requestLocation(c)
+		if err != nil {
+			return err
+		}
+		fields := map[string]string{}
+		window := intQuery(c, "window", defaultAverageDays, maxAverageDays, fields)
+		var from, to *time.Time
+		for _, param := range []string{"from", "to"} {
+			value := c.Query(param)
+			if value == "" {
+				continue
+			}
+			at, err := parseQueryTime(value, loc)
+			if err != nil {
+				fields[param] = "must be an RFC 3339 time or a YYYY-MM-DD date"
+				continue
+			}
+			if param == "from" {
+				from = &at
+			} else {
+				to = &at
+			}
+		}
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		metrics, err := bodyHistory(c, body, uid, from, to, window)
+		if err != nil {
+			return apperror.Internal("Failed to load body metrics", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(metrics)
+	}
+}
+
+// GetBodyMetric returns a single measurement
+func GetBodyMetric(body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		metric, err := body.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Body metric not found", "Failed to load body metric")
+		}
+		return c.Status(fiber.StatusOK).JSON(metric)
+	}
+}
+
+// CreateBodyMetric stores a measurement
+func CreateBodyMetric(body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		metric, err := bodyMetricBody(c)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		metric.ID = primitive.NilObjectID
+		metric.User_id = uid
+		metric.Created_at = now
+		metric.Updated_at = now
+		if err := body.Create(c.UserContext(), metric); err != nil {
+			return apperror.Internal("Failed to create body metric", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(metric)
+	}
+}
+
+// UpdateBodyMetric replaces a measurement; metrics left out are removed
+func UpdateBodyMetric(body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		metric, err := bodyMetricBody(c)
+		if err != nil {
+			return err
+		}
+
+		metric.Updated_at = time.Now()
+		updated, err := body.Update(c.UserContext(), uid, c.Params("id"), *metric)
+		if err != nil {
+			return storeError(err, "Body metric not found", "Failed to update body metric")
+		}
+		return c.Status(fiber.StatusOK).JSON(updated)
+	}
+}
+
+// DeleteBodyMetric removes a measurement
+func DeleteBodyMetric(body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		if err := body.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Body metric not found", "Failed to delete body metric")
+		}
+		return c.Status(fiber.StatusOK).JSON(id)
+	}
+}
+
+// GetBodyTrend summarizes every metric over the last weeks: the latest reading, the
+// moving average and the rate of change per week
+func GetBodyTrend(body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		fields := map[string]string{}
+		weeks := intQuery(c, "weeks", defaultTrendWeeks, maxTrendWeeks, fields)
+		window := intQuery(c, "window", defaultAverageDays, maxAverageDays, fields)
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		since := time.Now().AddDate(0, 0, -7*weeks)
+		metrics, err := bodyHistory(c, body, uid, &since, nil, window)
+		if err != nil {
+			return apperror.Internal("Failed to load body metrics", err)
+		}
+		trends := []models.MetricTrend{}
+		for _, metric := range models.BodyMetrics {
+			trends = append(trends, models.Trend(metrics, metric))
+		}
+		return c.Status(fiber.StatusOK).JSON(trends)
+	}
+}
+
+// GetBodyGoal returns the body goal of the user and the progress towards it
+func GetBodyGoal(users repository.UserRepository, body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		user, err := users.FindByUserID(c.UserContext(), uid)
+		if err != nil {
+			return storeError(err, "User not found", "Failed to load user")
+		}
+		if user.Body_goal == nil {
+			return apperror.NotFound("No body goal set")
+		}
+		progress, err := goalProgress(c, body, uid, *user.Body_goal)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(progress)
+	}
+}
+
+// SetBodyGoal replaces the body goal of the user. The goal starts from the current
+// average weight unless a start weight is given.
+func SetBodyGoal(users repository.UserRepository, body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var goal models.BodyGoal
+		if err := c.BodyParser(&goal); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(goal); err != nil {
+			return apperror.FromValidator(err)
+		}
+		now := time.Now()
+		if goal.Target_date != nil && !goal.Target_date.After(now) {
+			return apperror.Validation("Request validation failed", map[string]string{"target_date": "must be in the future"})
+		}
+
+		goal.Started_at = now
+		if goal.Start_weight == nil {
+			current, _, err := currentWeight(c, body, uid)
+			if err != nil {
+				return err
+			}
+			goal.Start_weight = current
+		}
+		if err := users.SetBodyGoal(c.UserContext(), uid, &goal); err != nil {
+			return storeError(err, "User not found", "Failed to save body goal")
+		}
+
+		progress, err := goalProgress(c, body, uid, goal)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(progress)
+	}
+}
+
+// DeleteBodyGoal removes the body goal of the user
+func DeleteBodyGoal(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		if err := users.SetBodyGoal(c.UserContext(), uid, nil); err != nil {
+			return storeError(err, "User not found", "Failed to remove body goal")
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Body goal removed"})
+	}
+}
+
+// bodyMetricBody parses and validates a measurement
+func bodyMetricBody(c *fiber.Ctx) (*models.BodyMetric, error) {
+	var metric models.BodyMetric
+	if err := c.BodyParser(&metric); err != nil {
+		return nil, apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+	}
+	if err := validate.Struct(metric); err != nil {
+		return nil, apperror.FromValidator(err)
+	}
+	if metric.Empty() {
+		return nil, apperror.Validation("Request validation failed", map[string]string{"weight": "at least one metric is required"})
+	}
+	metric.Averages = nil
+	return &metric, nil
+}
+
+// bodyHistory loads the measurements in [from, to) with their moving averages,
+// reading far enough back for the first average to span the whole window
+func bodyHistory(c *fiber.Ctx, body repository.BodyRepository, uid string, from *time.Time, to *time.Time, window int) ([]models.BodyMetric, error) {
+	var since *time.Time
+	if from != nil {
+		earlier := from.AddDate(0, 0, -window)
+		since = &earlier
+	}
+	metrics, err := body.List(c.UserContext(), uid, since, to)
+	if err != nil {
+		return nil, err
+	}
+	models.MovingAverages(metrics, window)
+
+	first := 0
+	for from != nil && first < len(metrics) && metrics[first].Measured_at.Before(*from) {
+		first++
+	}
+	return metrics[first:], nil
+}
+
+// currentWeight returns the moving average weight at the latest weigh-in and the
+// weekly rate of change over the recent weeks, either nil without enough readings
+func currentWeight(c *fiber.Ctx, body repository.BodyRepository, uid string) (*float64, *float64, error) {
+	since := time.Now().AddDate(0, 0, -7*defaultTrendWeeks)
+	metrics, err := bodyHistory(c, body, uid, &since, nil, defaultAverageDays)
+	if err != nil {
+		return nil, nil, apperror.Internal("Failed to load body metrics", err)
+	}
+	trend := models.Trend(metrics, models.MetricWeight)
+	return trend.Average, trend.Rate, nil
+}
+
+func goalProgress(c *fiber.Ctx, body repository.BodyRepository, uid string, goal models.BodyGoal) (models.GoalProgress, error) {
+	current, rate, err := currentWeight(c, body, uid)
+	if err != nil {
+		return models.GoalProgress{}, err
+	}
+	return goal.Progress(current, rate, time.Now()), nil
+}
+
+// intQuery reads an optional integer query parameter between 1 and limit, noting
+// an invalid value in fields
+func intQuery(c *fiber.Ctx, param string, fallback int, limit int, fields map[string]string) int {
+	raw := c.Query(param)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > limit {
+		fields[param] = "must be between 1 and " + strconv.Itoa(limit)
+		return fallback
+	}
+	return n
+}
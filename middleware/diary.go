@@ -0,0 +1,326 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Diary ranges default to the last defaultDiaryDays days and span at most
+// maxDiaryDays
+const (
+	defaultDiaryDays = 7
+	maxDiaryDays     = 92
+)
+
+// GetDiaryDay returns what was eaten on the date given, today by default, meal by
+// meal with the totals, the daily targets of the user and what is left of them
+func GetDiaryDay(diary repository.DiaryRepository, users repository.UserRepository, body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		today, err := diaryToday(c)
+		if err != nil {
+			return err
+		}
+		fields := map[string]string{}
+		day := dateQuery(c, "date", today, fields)
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		days, err := diaryDays(c, diary, users, body, uid, day, day)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(days[0])
+	}
+}
+
+// GetDiaryDays returns the diary of every day from one date to another, both
+// included, by default the last week up to today
+func GetDiaryDays(diary repository.DiaryRepository, users repository.UserRepository, body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		today, err := diaryToday(c)
+		if err != nil {
+			return err
+		}
+		fields := map[string]string{}
+		to := dateQuery(c, "to", today, fields)
+		from := dateQuery(c, "from", to.AddDate(0, 0, 1-defaultDiaryDays), fields)
+		if len(fields) == 0 {
+			switch {
+			case from.After(to):
+				fields["from"] = "must not be after to"
+			case to.Sub(from) >= maxDiaryDays*24*time.Hour:
+				fields["from"] = "must be at most " + strconv.Itoa(maxDiaryDays) + " days before to"
+			}
+		}
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		days, err := diaryDays(c, diary, users, body, uid, from, to)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(days)
+	}
+}
+
+// GetFoodEntry returns a single diary entry
+func GetFoodEntry(diary repository.DiaryRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		entry, err := diary.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Diary entry not found", "Failed to load diary entry")
+		}
+		entry.Tally()
+		return c.Status(fiber.StatusOK).JSON(entry)
+	}
+}
+
+// CreateFoodEntry logs servings of a recipe or of an ad-hoc food. A recipe is
+// given by recipe_id; an ad-hoc food either as text such as "150 g chicken
+// breast", looked up in the food database, or as a name with the nutrition of a
+// serving.
+func CreateFoodEntry(diary repository.DiaryRepository, recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		entry, err := foodEntryBody(c, recipes, foods, uid)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		entry.ID = primitive.NilObjectID
+		entry.User_id = uid
+		entry.Created_at = now
+		entry.Updated_at = now
+		if err := diary.Create(c.UserContext(), entry); err != nil {
+			return apperror.Internal("Failed to create diary entry", err)
+		}
+		entry.Tally()
+		return c.Status(fiber.StatusOK).JSON(entry)
+	}
+}
+
+// UpdateFoodEntry replaces a diary entry. The nutrition of a recipe is copied
+// again, so the entry picks up changes made to the recipe since it was logged.
+func UpdateFoodEntry(diary repository.DiaryRepository, recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		entry, err := foodEntryBody(c, recipes, foods, uid)
+		if err != nil {
+			return err
+		}
+
+		entry.Updated_at = time.Now()
+		updated, err := diary.Update(c.UserContext(), uid, c.Params("id"), *entry)
+		if err != nil {
+			return storeError(err, "Diary entry not found", "Failed to update diary entry")
+		}
+		updated.Tally()
+		return c.Status(fiber.StatusOK).JSON(updated)
+	}
+}
+
+// DeleteFoodEntry removes a diary entry
+func DeleteFoodEntry(diary repository.DiaryRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		if err := diary.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Diary entry not found", "Failed to delete diary entry")
+		}
+		return c.Status(fiber.StatusOK).JSON(id)
+	}
+}
+
+// GetNutritionTargets returns the daily nutrition targets of the user
+func GetNutritionTargets(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		user, err := users.FindByUserID(c.UserContext(), uid)
+		if err != nil {
+			return storeError(err, "User not found", "Failed to load user")
+		}
+		if user.Nutrition_targets == nil {
+			return apperror.NotFound("No nutrition targets set")
+		}
+		return c.Status(fiber.StatusOK).JSON(user.Nutrition_targets)
+	}
+}
+
+// SetNutritionTargets replaces the daily nutrition targets of the user
+func SetNutritionTargets(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var targets models.Nutrients
+		if err := c.BodyParser(&targets); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(targets); err != nil {
+			return apperror.FromValidator(err)
+		}
+		targets = targets.Round()
+		if err := users.SetNutritionTargets(c.UserContext(), uid, &targets); err != nil {
+			return storeError(err, "User not found", "Failed to save nutrition targets")
+		}
+		return c.Status(fiber.StatusOK).JSON(targets)
+	}
+}
+
+// DeleteNutritionTargets removes the daily nutrition targets of the user
+func DeleteNutritionTargets(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		if err := users.SetNutritionTargets(c.UserContext(), uid, nil); err != nil {
+			return storeError(err, "User not found", "Failed to remove nutrition targets")
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Nutrition targets removed"})
+	}
+}
+
+// foodEntryBody parses and validates a diary entry and works out the nutrition of
+// one serving of what was eaten
+func foodEntryBody(c *fiber.Ctx, recipes repository.RecipeRepository, foods repository.FoodRepository, uid string) (*models.FoodEntry, error) {
+	var body struct {
+		models.FoodEntry
+		Text string `json:"text" validate:"max=500"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return nil, apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+	}
+	if body.Date == "" {
+		today, err := diaryToday(c)
+		if err != nil {
+			return nil, err
+		}
+		body.Date = today.Format(time.DateOnly)
+	}
+	if body.Servings == 0 {
+		body.Servings = 1
+	}
+	if err := validate.Struct(body); err != nil {
+		return nil, apperror.FromValidator(err)
+	}
+	entry := body.FoodEntry
+
+	switch {
+	case entry.Recipe_id != nil:
+		recipe, err := recipes.Get(c.UserContext(), uid, entry.Recipe_id.Hex())
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, apperror.Validation("Request validation failed", map[string]string{"recipe_id": "no such recipe"})
+			}
+			return nil, apperror.Internal("Failed to load recipe", err)
+		}
+		recipe.Derive()
+		if recipe.Dish != nil {
+			entry.Name = *recipe.Dish
+		}
+		entry.Per_serving = models.Nutrients{}
+		if recipe.Per_serving != nil {
+			entry.Per_serving = *recipe.Per_serving
+		}
+	case body.Text != "":
+		ingredient := models.ParseIngredient(body.Text)
+		matches, err := foods.Match(c.UserContext(), []string{ingredient.Item})
+		if err != nil {
+			return nil, apperror.Internal("Failed to load foods", err)
+		}
+		food, ok := matches[ingredient.Item]
+		if !ok {
+			return nil, apperror.Validation("Request validation failed", map[string]string{"text": "no food matches " + ingredient.Item})
+		}
+		grams, ok := food.Grams(ingredient)
+		if !ok {
+			return nil, apperror.Validation("Request validation failed", map[string]string{"text": "needs an amount such as 150 g"})
+		}
+		entry.Name = ingredient.String()
+		entry.Per_serving = food.Per_100g.Scale(grams / 100).Round()
+	case entry.Name == "":
+		return nil, apperror.Validation("Request validation failed", map[string]string{"name": "a recipe_id, text or name is required"})
+	default:
+		entry.Per_serving = entry.Per_serving.Round()
+	}
+	return &entry, nil
+}
+
+// diaryDays loads the diary of the days from one date to another with the
+// targets of the user
+func diaryDays(c *fiber.Ctx, diary repository.DiaryRepository, users repository.UserRepository, body repository.BodyRepository, uid string, from time.Time, to time.Time) ([]models.DiaryDay, error) {
+	user, err := users.FindByUserID(c.UserContext(), uid)
+	if err != nil {
+		return nil, storeError(err, "User not found", "Failed to load user")
+	}
+	targets, err := dailyTargets(c, body, user)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := diary.List(c.UserContext(), uid, from.Format(time.DateOnly), to.Format(time.DateOnly))
+	if err != nil {
+		return nil, apperror.Internal("Failed to load diary", err)
+	}
+	return models.DiaryDays(entries, from, to, targets), nil
+}
+
+// diaryToday is the date it is now in the time zone of the request
+func diaryToday(c *fiber.Ctx) (time.Time, error) {
+	loc, err := requestLocation(c)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// dateQuery reads an optional YYYY-MM-DD query parameter, noting an invalid value
+// in fields
+func dateQuery(c *fiber.Ctx, param string, fallback time.Time, fields map[string]string) time.Time {
+	raw := c.Query(param)
+	if raw == "" {
+		return fallback
+	}
+	day, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		fields[param] = "must be a YYYY-MM-DD date"
+		return fallback
+	}
+	return day
+}
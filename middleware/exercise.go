@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetExercises searches the exercise catalog. q matches part of the name, muscle,
+// equipment and pattern filter on the catalog vocabularies.
+func GetExercises(exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		query := repository.ExerciseQuery{
+			Text:      strings.TrimSpace(c.Query("q")),
+			Muscle:    c.Query("muscle"),
+			Equipment: c.Query("equipment"),
+			Pattern:   c.Query("pattern"),
+		}
+		fields := map[string]string{}
+		for param, allowed := range map[string][]string{
+			"muscle":    models.Muscles,
+			"equipment": models.Equipment,
+			"pattern":   models.Patterns,
+		} {
+			if value := c.Query(param); value != "" && !slices.Contains(allowed, value) {
+				fields[param] = "must be one of " + strings.Join(allowed, ", ")
+			}
+		}
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		payload, err := exercises.List(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load exercises", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(payload)
+	}
+}
+
+// GetExerciseFilters lists the values the catalog can be filtered by
+func GetExerciseFilters() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"muscles":   models.Muscles,
+			"equipment": models.Equipment,
+			"patterns":  models.Patterns,
+		})
+	}
+}
+
+// GetExercise returns a single exercise of the catalog
+func GetExercise(exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		exercise, err := exercises.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Exercise not found", "Failed to load exercise")
+		}
+		return c.Status(fiber.StatusOK).JSON(exercise)
+	}
+}
+
+// CreateExercise adds an exercise of the user's own to the catalog
+func CreateExercise(exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		exercise, err := exerciseBody(c)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		exercise.ID = primitive.NilObjectID
+		exercise.User_id = uid
+		exercise.Created_at = now
+		exercise.Updated_at = now
+		if err := exercises.Create(c.UserContext(), exercise); err != nil {
+			if errors.Is(err, repository.ErrDuplicate) {
+				return apperror.Conflict("An exercise with this name already exists")
+			}
+			return apperror.Internal("Failed to create exercise", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(exercise)
+	}
+}
+
+// UpdateExercise replaces one of the user's own exercises. Built-in exercises
+// cannot be changed.
+func UpdateExercise(exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		exercise, err := exerciseBody(c)
+		if err != nil {
+			return err
+		}
+		if err := ownExercise(c, exercises, uid, "Failed to update exercise"); err != nil {
+			return err
+		}
+
+		exercise.Updated_at = time.Now()
+		updated, err := exercises.Update(c.UserContext(), uid, c.Params("id"), *exercise)
+		if err != nil {
+			if errors.Is(err, repository.ErrDuplicate) {
+				return apperror.Conflict("An exercise with this name already exists")
+			}
+			return storeError(err, "Exercise not found", "Failed to update exercise")
+		}
+		return c.Status(fiber.StatusOK).JSON(updated)
+	}
+}
+
+// DeleteExercise removes one of the user's own exercises. Workouts keep the name
+// they logged it under.
+func DeleteExercise(exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		if err := ownExercise(c, exercises, uid, "Failed to delete exercise"); err != nil {
+			return err
+		}
+		id := c.Params("id")
+		if err := exercises.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Exercise not found", "Failed to delete exercise")
+		}
+		return c.Status(fiber.StatusOK).JSON(id)
+	}
+}
+
+// exerciseBody parses and validates an exercise
+func exerciseBody(c *fiber.Ctx) (*models.Exercise, error) {
+	var exercise models.Exercise
+	if err := c.BodyParser(&exercise); err != nil {
+		return nil, apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+	}
+	exercise.Normalize()
+	if err := validate.Struct(exercise); err != nil {
+		return nil, apperror.FromValidator(err)
+	}
+	return &exercise, nil
+}
+
+// ownExercise makes sure the exercise in the id parameter is not a built-in one
+func ownExercise(c *fiber.Ctx, exercises repository.ExerciseRepository, uid string, failed string) error {
+	exercise, err := exercises.Get(c.UserContext(), uid, c.Params("id"))
+	if err != nil {
+		return storeError(err, "Exercise not found", failed)
+	}
+	if exercise.User_id == "" {
+		return apperror.Conflict("Built-in exercises cannot be changed")
+	}
+	return nil
+}
+
+// catalogExercises loads the referenced exercises, failing validation on field
+// when the user cannot see one of them
+func catalogExercises(c *fiber.Ctx, exercises repository.ExerciseRepository, uid string, ids []primitive.ObjectID, field string) (map[primitive.ObjectID]models.Exercise, error) {
+	found, err := exercises.Find(c.UserContext(), uid, ids)
+	if err != nil {
+		return nil, apperror.Internal("Failed to load exercises", err)
+	}
+	byID := map[primitive.ObjectID]models.Exercise{}
+	for _, exercise := range found {
+		byID[exercise.ID] = exercise
+	}
+	for _, id := range ids {
+		if _, ok := byID[id]; !ok {
+			return nil, apperror.Validation("Exercise not found", map[string]string{field: "must reference exercises of the catalog"})
+		}
+	}
+	return byID, nil
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// Page sizes of food searches
+const (
+	defaultFoodLimit = 20
+	maxFoodLimit     = 100
+)
+
+// GetFoods searches the food database ingredients are matched against, by q in
+// the name or an alias
+func GetFoods(foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, err := currentUser(c); err != nil {
+			return err
+		}
+		fields := map[string]string{}
+		limit := intQuery(c, "limit", defaultFoodLimit, maxFoodLimit, fields)
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		payload, err := foods.Search(c.UserContext(), strings.TrimSpace(c.Query("q")), limit)
+		if err != nil {
+			return apperror.Internal("Failed to load foods", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(payload)
+	}
+}
+
+// calculateNutrition recalculates the nutrition of a recipe from its ingredients,
+// keeping values entered by hand while none of them can be counted
+func calculateNutrition(ctx context.Context, foods repository.FoodRepository, recipe *models.CalorieTracker) error {
+	entered := recipe.EnteredNutrition()
+	matches, err := matchIngredients(ctx, foods, recipe.Ingredients)
+	if err != nil {
+		return err
+	}
+
+	recipe.ApplyNutrition(models.CalculateNutrition(recipe.Ingredients, matches), entered)
+	return nil
+}
+
+// matchIngredients looks up the food of every ingredient, keyed by item
+func matchIngredients(ctx context.Context, foods repository.FoodRepository, ingredients models.Ingredients) (map[string]models.Food, error) {
+	items := make([]string, len(ingredients))
+	for i, ingredient := range ingredients {
+		items[i] = ingredient.Item
+	}
+	return foods.Match(ctx, items)
+}
@@ -1,41 +1,185 @@
 package middleware
 
 import (
-	"context"
-	"log"
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/khanirfan96/To-do-Fullstack-server/database"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func GetGym(c *fiber.Ctx) error {
-	payload := getAllGymSchedule(database.DB.GymCollection)
-	return c.Status(fiber.StatusOK).JSON(payload)
+// GetGym lists every weekly plan of the user
+func GetGym(gym repository.GymRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		payload, err := gym.List(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to load gym schedule", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(payload)
+	}
+}
+
+// GetActiveGym returns the plan the user currently follows
+func GetActiveGym(gym repository.GymRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		plan, err := gym.Active(c.UserContext(), uid)
+		if err != nil {
+			return storeError(err, "No active gym schedule", "Failed to load gym schedule")
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
+}
+
+// CreateGym stores a new weekly plan. The user's first plan is always active.
+func CreateGym(gym repository.GymRepository, exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var plan models.Gym
+		if err := c.BodyParser(&plan); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(plan); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if err := scheduledExercises(c, exercises, uid, plan.Exercises); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		plan.ID = primitive.NilObjectID
+		plan.Program = nil
+		plan.User_id = uid
+		plan.Created_at = now
+		plan.Updated_at = now
+		if err := gym.Create(c.UserContext(), &plan); err != nil {
+			return gymError(err, "Failed to create gym schedule")
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
 }
 
-func getAllGymSchedule(gymcoll *mongo.Collection) []primitive.M {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+// UpdateGym replaces the name, days and exercises of a plan. A plan following a
+// program keeps it, and the program rewrites the schedule as it advances.
+func UpdateGym(gym repository.GymRepository, exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var plan models.Gym
+		if err := c.BodyParser(&plan); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(plan); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if err := scheduledExercises(c, exercises, uid, plan.Exercises); err != nil {
+			return err
+		}
+
+		current, err := gym.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Gym schedule not found", "Failed to update gym schedule")
+		}
+		plan.Program = current.Program
 
-	cursor, err := gymcoll.Find(ctx, bson.D{{}})
-	if err != nil {
-		log.Fatal(err)
+		updated, err := gym.Update(c.UserContext(), uid, c.Params("id"), plan)
+		if err != nil {
+			return storeError(err, "Gym schedule not found", "Failed to update gym schedule")
+		}
+		return c.Status(fiber.StatusOK).JSON(updated)
 	}
-	var gym []primitive.M
-	for cursor.Next(ctx) {
-		var gymschedule bson.M
-		e := cursor.Decode(&gymschedule)
-		if e != nil {
-			log.Fatal(e)
+}
+
+// ActivateGym makes a plan the one the user follows
+func ActivateGym(gym repository.GymRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		plan, err := gym.Activate(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return gymError(err, "Failed to activate gym schedule")
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
+}
+
+// DeleteGym removes a plan. When the active plan goes, the oldest remaining plan
+// takes its place.
+func DeleteGym(gym repository.GymRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		plan, err := gym.Get(c.UserContext(), uid, id)
+		if err != nil {
+			return storeError(err, "Gym schedule not found", "Failed to delete gym schedule")
+		}
+		if err := gym.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Gym schedule not found", "Failed to delete gym schedule")
 		}
-		gym = append(gym, gymschedule)
+
+		response := fiber.Map{"id": id}
+		if plan.Active {
+			next, err := promoteGym(c, gym, uid)
+			if err != nil {
+				return apperror.Internal("Failed to activate the next gym schedule", err)
+			}
+			if next != nil {
+				response["active"] = next.ID
+			}
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+// gymError turns a repository error into an application error. A plan activated
+// by another request at the same time is a conflict the client can retry.
+func gymError(err error, failed string) error {
+	if errors.Is(err, repository.ErrConflict) {
+		return apperror.Conflict("Another gym schedule was activated at the same time, please retry")
 	}
-	if err := cursor.Err(); err != nil {
-		log.Fatal(err)
+	return storeError(err, "Gym schedule not found", failed)
+}
+
+// promoteGym activates the first remaining plan of the user, if there is one
+func promoteGym(c *fiber.Ctx, gym repository.GymRepository, uid string) (*models.Gym, error) {
+	plans, err := gym.List(c.UserContext(), uid)
+	if err != nil || len(plans) == 0 {
+		return nil, err
+	}
+	next, err := gym.Activate(c.UserContext(), uid, plans[0].ID.Hex())
+	if errors.Is(err, repository.ErrNotFound) {
+		// Deleted concurrently; the user is left without an active plan
+		return nil, nil
+	}
+	return next, err
+}
+
+// scheduledExercises makes sure every exercise of a plan is in the user's catalog
+func scheduledExercises(c *fiber.Ctx, exercises repository.ExerciseRepository, uid string, scheduled []models.ScheduledExercise) error {
+	ids := make([]primitive.ObjectID, len(scheduled))
+	for i, s := range scheduled {
+		ids[i] = s.Exercise_id
 	}
-	defer cancel()
-	return gym
+	_, err := catalogExercises(c, exercises, uid, ids, "exercise_id")
+	return err
 }
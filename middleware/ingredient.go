@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateIngredient adds an ingredient to a recipe, either parsed from a line of
+// text such as "2 cups flour, sifted" or given as quantity, unit, item and note.
+// It goes at position when one is given and is appended otherwise.
+func CreateIngredient(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Text     string   `json:"text" validate:"max=500"`
+			Quantity *float64 `json:"quantity"`
+			Unit     string   `json:"unit"`
+			Item     string   `json:"item"`
+			Note     string   `json:"note"`
+			Position *int     `json:"position" validate:"omitempty,min=0"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+
+		ingredient := models.Ingredient{Quantity: body.Quantity, Unit: body.Unit, Item: body.Item, Note: body.Note}
+		if body.Text != "" {
+			ingredient = models.ParseIngredient(body.Text)
+		}
+		if err := validate.Struct(ingredient); err != nil {
+			return apperror.FromValidator(err)
+		}
+
+		recipe, err := recipes.UpdateIngredients(c.UserContext(), uid, c.Params("id"), func(recipe *models.CalorieTracker) error {
+			if _, err := models.AddIngredient(&recipe.Ingredients, ingredient, body.Position); err != nil {
+				return err
+			}
+			return calculateNutrition(c.UserContext(), foods, recipe)
+		})
+		if err != nil {
+			return ingredientError(err)
+		}
+		recipe.Derive()
+		return c.JSON(recipe)
+	}
+}
+
+// DeleteIngredient removes one ingredient from a recipe
+func DeleteIngredient(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		target, err := ingredientID(c.Params("ingredientid"), "ingredientid")
+		if err != nil {
+			return err
+		}
+
+		recipe, err := recipes.UpdateIngredients(c.UserContext(), uid, c.Params("id"), func(recipe *models.CalorieTracker) error {
+			if !models.RemoveIngredient(&recipe.Ingredients, target) {
+				return models.ErrIngredientNotFound
+			}
+			return calculateNutrition(c.UserContext(), foods, recipe)
+		})
+		if err != nil {
+			return ingredientError(err)
+		}
+		recipe.Derive()
+		return c.JSON(recipe)
+	}
+}
+
+// ReorderIngredients puts the ingredients of a recipe in the given order, which
+// must list every one of them
+func ReorderIngredients(recipes repository.RecipeRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Order []string `json:"order" validate:"required"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		ids := make([]primitive.ObjectID, len(body.Order))
+		for i, raw := range body.Order {
+			if ids[i], err = ingredientID(raw, "order"); err != nil {
+				return err
+			}
+		}
+
+		recipe, err := recipes.UpdateIngredients(c.UserContext(), uid, c.Params("id"), func(recipe *models.CalorieTracker) error {
+			return models.ReorderIngredients(recipe.Ingredients, ids)
+		})
+		if err != nil {
+			return ingredientError(err)
+		}
+		recipe.Derive()
+		return c.JSON(recipe)
+	}
+}
+
+func ingredientID(raw string, field string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return id, apperror.Validation("Invalid ingredient id", map[string]string{field: "must be a valid id"})
+	}
+	return id, nil
+}
+
+func ingredientError(err error) error {
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		return err
+	case errors.Is(err, models.ErrIngredientNotFound):
+		return apperror.NotFound("Ingredient not found")
+	case errors.Is(err, models.ErrIngredientLimit):
+		return apperror.Validation(err.Error(), map[string]string{"ingredients": err.Error()})
+	case errors.Is(err, models.ErrIngredientOrder):
+		return apperror.Validation(err.Error(), map[string]string{"order": err.Error()})
+	case errors.Is(err, repository.ErrConflict):
+		return apperror.Conflict("Recipe was changed by another request, please retry")
+	}
+	return storeError(err, "Recipe not found", "Failed to update ingredients")
+}
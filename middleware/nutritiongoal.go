@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// GetNutritionGoal returns the nutrition goal of the user with the BMR, TDEE and
+// daily targets it works out to at the current weight
+func GetNutritionGoal(users repository.UserRepository, body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		user, err := users.FindByUserID(c.UserContext(), uid)
+		if err != nil {
+			return storeError(err, "User not found", "Failed to load user")
+		}
+		if user.Nutrition_goal == nil {
+			return apperror.NotFound("No nutrition goal set")
+		}
+		plan, err := nutritionPlan(c, body, uid, *user.Nutrition_goal)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
+}
+
+// SetNutritionGoal replaces the nutrition goal of the user. Without a weight the
+// goal follows the moving average of the measured weight.
+func SetNutritionGoal(users repository.UserRepository, body repository.BodyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var goal models.NutritionGoal
+		if err := c.BodyParser(&goal); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(goal); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if goal.Macros != nil && math.Abs(goal.Macros.Sum()-100) > 0.5 {
+			return apperror.Validation("Request validation failed", map[string]string{"macros": "must add up to 100 percent"})
+		}
+
+		plan, err := nutritionPlan(c, body, uid, goal)
+		if err != nil {
+			return err
+		}
+		if err := users.SetNutritionGoal(c.UserContext(), uid, &goal); err != nil {
+			return storeError(err, "User not found", "Failed to save nutrition goal")
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
+}
+
+// DeleteNutritionGoal removes the nutrition goal of the user
+func DeleteNutritionGoal(users repository.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		if err := users.SetNutritionGoal(c.UserContext(), uid, nil); err != nil {
+			return storeError(err, "User not found", "Failed to remove nutrition goal")
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Nutrition goal removed"})
+	}
+}
+
+// nutritionPlan works out a goal at the current weight of the user
+func nutritionPlan(c *fiber.Ctx, body repository.BodyRepository, uid string, goal models.NutritionGoal) (models.NutritionPlan, error) {
+	plan, err := calculateGoal(c, body, uid, goal)
+	if errors.Is(err, models.ErrNoWeight) {
+		return plan, apperror.Validation("Request validation failed", map[string]string{"weight": "is required until a weight is measured"})
+	}
+	return plan, err
+}
+
+// dailyTargets are the targets the diary of the user is compared against: the
+// calories and macros of the nutrition goal over the targets set by hand. A goal
+// that cannot be worked out for lack of a weight is left out.
+func dailyTargets(c *fiber.Ctx, body repository.BodyRepository, user *models.User) (*models.Nutrients, error) {
+	if user.Nutrition_goal == nil {
+		return user.Nutrition_targets, nil
+	}
+	plan, err := calculateGoal(c, body, user.User_id, *user.Nutrition_goal)
+	if errors.Is(err, models.ErrNoWeight) {
+		return user.Nutrition_targets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	targets := plan.WithTargets(user.Nutrition_targets)
+	return &targets, nil
+}
+
+// calculateGoal works out a goal, looking up the current weight when the goal
+// gives none
+func calculateGoal(c *fiber.Ctx, body repository.BodyRepository, uid string, goal models.NutritionGoal) (models.NutritionPlan, error) {
+	var weight *float64
+	if goal.Weight == nil {
+		current, _, err := currentWeight(c, body, uid)
+		if err != nil {
+			return models.NutritionPlan{}, err
+		}
+		weight = current
+	}
+	return goal.Calculate(weight)
+}
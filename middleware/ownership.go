@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+var validate = newValidator()
+
+// newValidator returns the validator shared by the handlers. The muscle, equipment
+// and pattern tags accept the vocabularies of the exercise catalog.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	for tag, values := range map[string][]string{
+		"muscle":    models.Muscles,
+		"equipment": models.Equipment,
+		"pattern":   models.Patterns,
+	} {
+		values := values
+		v.RegisterValidation(tag, func(fl validator.FieldLevel) bool {
+			return slices.Contains(values, fl.Field().String())
+		})
+	}
+	return v
+}
+
+// currentUser returns the Uid placed in c.Locals by Authentication()
+func currentUser(c *fiber.Ctx) (string, error) {
+	uid, ok := c.Locals("Uid").(string)
+	if !ok || uid == "" {
+		return "", apperror.Unauthorized("No Authorization header provided")
+	}
+	return uid, nil
+}
+
+// storeError turns a repository error into an application error. Missing and
+// foreign documents become notFound, anything else is an internal failure.
+func storeError(err error, notFound string, failed string) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperror.NotFound(notFound)
+	}
+	return apperror.Internal(failed, err)
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// startingShare is the part of an estimated one rep max a program starts at when no
+// starting weight is given
+const startingShare = 0.7
+
+// GetPrograms lists the program templates
+func GetPrograms() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(models.ProgramTemplates())
+	}
+}
+
+// StartProgram creates an active gym plan following a program template. Weights
+// maps exercise ids of the template to starting weights; exercises left out start
+// at a share of the user's best estimated one rep max.
+func StartProgram(gym repository.GymRepository, workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Template string             `json:"template" validate:"required"`
+			Name     string             `json:"name" validate:"max=100"`
+			Weights  map[string]float64 `json:"weights" validate:"dive,min=0,max=2000"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		template, ok := models.FindProgramTemplate(body.Template)
+		if !ok {
+			return apperror.Validation("Request validation failed", map[string]string{"template": "must be one of the program templates"})
+		}
+
+		weights, err := startingWeights(c, workouts, uid, template, body.Weights)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		plan := models.Gym{
+			Name:       body.Name,
+			Active:     true,
+			Program:    template.Start(weights, now),
+			Created_at: now,
+			Updated_at: now,
+			User_id:    uid,
+		}
+		if plan.Name == "" {
+			plan.Name = template.Name
+		}
+		plan.Program.Schedule(template, &plan)
+		if err := gym.Create(c.UserContext(), &plan); err != nil {
+			return gymError(err, "Failed to create gym schedule")
+		}
+		return c.Status(fiber.StatusOK).JSON(plan)
+	}
+}
+
+// startingWeights resolves the starting weight of every exercise of a template
+func startingWeights(c *fiber.Ctx, workouts repository.WorkoutRepository, uid string, template *models.ProgramTemplate, given map[string]float64) (map[primitive.ObjectID]float64, error) {
+	slots := template.Slots()
+	weights := map[primitive.ObjectID]float64{}
+	fields := map[string]string{}
+	for key, weight := range given {
+		id, err := primitive.ObjectIDFromHex(key)
+		if err != nil || !hasSlot(slots, id) {
+			fields["weights."+key] = "must be an exercise of the program"
+			continue
+		}
+		weights[id] = weight
+	}
+
+	var missing []string
+	for _, slot := range slots {
+		if _, ok := weights[slot.Exercise_id]; !ok {
+			missing = append(missing, slot.Exercise_id.Hex())
+		}
+	}
+	if len(missing) > 0 {
+		records, err := workouts.Records(c.UserContext(), uid, nil, missing)
+		if err != nil {
+			return nil, apperror.Internal("Failed to load personal records", err)
+		}
+		for _, r := range records {
+			id, err := primitive.ObjectIDFromHex(r.Key)
+			if err == nil && r.Best_e1rm.Value > 0 {
+				weights[id] = math.Floor(r.Best_e1rm.Value*startingShare/models.LoadStep) * models.LoadStep
+			}
+		}
+		for _, slot := range slots {
+			if _, ok := weights[slot.Exercise_id]; !ok {
+				fields["weights."+slot.Exercise_id.Hex()] = "no starting weight for " + slot.Exercise + " and no logged workout to derive it from"
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, apperror.Validation("Request validation failed", fields)
+	}
+	return weights, nil
+}
+
+func hasSlot(slots []models.ProgramSlot, id primitive.ObjectID) bool {
+	for _, slot := range slots {
+		if slot.Exercise_id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNextSession prescribes the next session of the program the active plan follows
+func GetNextSession(gym repository.GymRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		plan, err := gym.Active(c.UserContext(), uid)
+		if err != nil {
+			return storeError(err, "No active gym schedule", "Failed to load gym schedule")
+		}
+		template, ok := planTemplate(plan)
+		if !ok {
+			return apperror.NotFound("The active gym schedule does not follow a program")
+		}
+		return c.Status(fiber.StatusOK).JSON(plan.Program.Next(template))
+	}
+}
+
+// planTemplate returns the template the plan follows, if any
+func planTemplate(plan *models.Gym) (*models.ProgramTemplate, bool) {
+	if plan == nil || plan.Program == nil {
+		return nil, false
+	}
+	return models.FindProgramTemplate(plan.Program.Template)
+}
+
+// advanceProgram records a workout logged against a plan as the next session of its
+// program, adjusting the loads and rewriting the schedule. The program is evaluated
+// on the stored plan, so workouts logged at the same time each count once.
+func advanceProgram(c *fiber.Ctx, gym repository.GymRepository, uid string, plan *models.Gym, workout *models.Workout) ([]models.LiftAdjustment, error) {
+	if _, ok := planTemplate(plan); !ok {
+		return nil, nil
+	}
+	var adjustments []models.LiftAdjustment
+	_, err := gym.UpdateProgram(c.UserContext(), uid, plan.ID.Hex(), func(plan *models.Gym) bool {
+		adjustments = nil
+		template, ok := planTemplate(plan)
+		if !ok {
+			return false
+		}
+		adjustments = plan.Program.Record(template, workout)
+		if len(adjustments) == 0 {
+			return false
+		}
+		plan.Program.Schedule(template, plan)
+		return true
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		// The plan was deleted after the workout was checked against it
+		return nil, nil
+	}
+	if err != nil || len(adjustments) == 0 {
+		return nil, err
+	}
+	return adjustments, nil
+}
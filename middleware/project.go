@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func GetProjects(projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		list, err := projects.List(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to load projects", err)
+		}
+		return c.JSON(list)
+	}
+}
+
+// CreateProject adds a project after the user's existing ones
+func CreateProject(projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var project models.Project
+		if err := c.BodyParser(&project); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		if err := validate.Struct(project); err != nil {
+			return apperror.FromValidator(err)
+		}
+
+		now := time.Now()
+		project.ID = primitive.NilObjectID
+		project.User_id = uid
+		project.Created_at = now
+		project.Updated_at = now
+		if err := projects.Create(c.UserContext(), &project); err != nil {
+			return apperror.Internal("Failed to create project", err)
+		}
+		return c.JSON(project)
+	}
+}
+
+// UpdateProject renames or recolors a project. An empty color removes it.
+func UpdateProject(projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Name  *string `json:"name" validate:"omitempty,min=1,max=100"`
+			Color *string `json:"color" validate:"omitempty,len=0|hexcolor"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+
+		project, err := projects.Update(c.UserContext(), uid, c.Params("id"), models.ProjectPatch{Name: body.Name, Color: body.Color})
+		if err != nil {
+			return storeError(err, "Project not found", "Failed to update project")
+		}
+		return c.JSON(project)
+	}
+}
+
+// ReorderProjects sets the display order of the user's projects. The body must
+// list every project exactly once.
+func ReorderProjects(projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Order []string `json:"order" validate:"required"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+
+		list, err := projects.List(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to load projects", err)
+		}
+		ids, err := objectIDs(body.Order, "order")
+		if err != nil {
+			return err
+		}
+		if !sameProjects(list, ids) {
+			return apperror.Validation("Request validation failed", map[string]string{"order": "must list every project exactly once"})
+		}
+
+		if err := projects.Reorder(c.UserContext(), uid, ids); err != nil {
+			return apperror.Internal("Failed to reorder projects", err)
+		}
+		list, err = projects.List(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to load projects", err)
+		}
+		return c.JSON(list)
+	}
+}
+
+// DeleteProject removes a project. Its todos are kept and move out of the project.
+func DeleteProject(projects repository.ProjectRepository, todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		project, err := projects.Get(c.UserContext(), uid, id)
+		if err != nil {
+			return storeError(err, "Project not found", "Failed to delete project")
+		}
+
+		detached, err := todos.DetachProject(c.UserContext(), uid, project.ID)
+		if err != nil {
+			return apperror.Internal("Failed to delete project", err)
+		}
+		if err := projects.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Project not found", "Failed to delete project")
+		}
+		return c.JSON(fiber.Map{
+			"id":             id,
+			"todos_detached": detached,
+		})
+	}
+}
+
+// MoveTodos files a batch of todos under a project, or takes them out of their
+// project when project_id is null or missing
+func MoveTodos(todos repository.TodoRepository, projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Ids        []string `json:"ids" validate:"required,min=1,max=500"`
+			Project_id *string  `json:"project_id"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		ids, err := objectIDs(body.Ids, "ids")
+		if err != nil {
+			return err
+		}
+		project, err := projectRef(c, projects, uid, body.Project_id)
+		if err != nil {
+			return err
+		}
+
+		moved, err := todos.Move(c.UserContext(), uid, ids, project)
+		if err != nil {
+			return apperror.Internal("Failed to move tasks", err)
+		}
+		return c.JSON(fiber.Map{"moved": moved})
+	}
+}
+
+// projectRef resolves a project_id from a request body. Empty ids mean no project,
+// ids of missing or foreign projects are rejected.
+func projectRef(c *fiber.Ctx, projects repository.ProjectRepository, uid string, id *string) (*primitive.ObjectID, error) {
+	if id == nil || *id == "" {
+		return nil, nil
+	}
+	project, err := projects.Get(c.UserContext(), uid, *id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperror.Validation("Project not found", map[string]string{"project_id": "must be one of your projects"})
+		}
+		return nil, apperror.Internal("Failed to load project", err)
+	}
+	return &project.ID, nil
+}
+
+// objectIDs parses a list of hex ids from a request body
+func objectIDs(raw []string, field string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, len(raw))
+	for i, s := range raw {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, apperror.Validation("Request validation failed", map[string]string{field: "must only contain valid ids"})
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func sameProjects(list []models.Project, ids []primitive.ObjectID) bool {
+	if len(list) != len(ids) {
+		return false
+	}
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, project := range list {
+		if !seen[project.ID] {
+			return false
+		}
+	}
+	return true
+}
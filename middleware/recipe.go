@@ -1,219 +1,164 @@
 package middleware
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/khanirfan96/To-do-Fullstack-server/database"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
 	"github.com/khanirfan96/To-do-Fullstack-server/models"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
 )
 
-func GetRecipe(c *fiber.Ctx) error {
-	uid := c.Locals("Uid").(string)
-	payload := getAllCalories(database.DB.CalorieCollection, uid)
-	return c.Status(fiber.StatusOK).JSON(payload)
-}
-
-func getAllCalories(calCol *mongo.Collection, id string) []primitive.M {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	filter := bson.M{"user_id": id}
-
-	cursor, err := calCol.Find(ctx, filter)
-	if err != nil {
-		log.Fatal(err)
-	}
-	var calories []primitive.M
-	for cursor.Next(ctx) {
-		var calorie bson.M
-		e := cursor.Decode(&calorie)
-		if e != nil {
-			log.Fatal(e)
+func GetRecipe(recipes repository.RecipeRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
 		}
-		calories = append(calories, calorie)
-
-	}
-	if err := cursor.Err(); err != nil {
-		log.Fatal(err)
+		payload, err := recipes.List(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to load recipes", err)
+		}
+		for i := range payload {
+			payload[i].Derive()
+		}
+		return c.Status(fiber.StatusOK).JSON(payload)
 	}
-	defer cancel()
-	return calories
 }
 
-func CreateRecipe(c *fiber.Ctx) error {
-	var recipe models.CalorieTracker
-	if err := c.BodyParser(&recipe); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot parse json",
+func CreateRecipe(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var recipe models.CalorieTracker
+		if err := c.BodyParser(&recipe); err != nil {
+			return apperror.Validation("Cannot parse json", nil)
+		}
+		models.NormalizeIngredients(recipe.Ingredients)
+		if err := validate.Struct(recipe); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if err := calculateNutrition(c.UserContext(), foods, &recipe); err != nil {
+			return apperror.Internal("Failed to calculate nutrition", err)
+		}
+		recipe.User_id = uid
+		if err := recipes.Create(c.UserContext(), &recipe); err != nil {
+			return apperror.Internal("Failed to create recipe", err)
+		}
+		recipe.Derive()
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Recipe created successfully",
+			"id":      recipe,
 		})
 	}
-	insertOneRecipe(recipe, database.DB.CalorieCollection)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Recipe created successfully",
-		"id":      recipe,
-	})
 }
 
-func insertOneRecipe(recipe models.CalorieTracker, recipeColl *mongo.Collection) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	insertCalorieResult, err := recipeColl.InsertOne(ctx, recipe)
-	if err != nil {
-		log.Fatal(err)
+func DeleteAllRecipe(recipes repository.RecipeRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		count, err := recipes.DeleteAll(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to delete recipes", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"Message": "All Entries Deleted",
+			"Count":   count,
+		})
 	}
-	defer cancel()
-	fmt.Println("Inserted a Recipe ", insertCalorieResult.InsertedID)
 }
 
-func DeleteAllRecipe(c *fiber.Ctx) error {
-	count := deleteAllRecipe(database.DB.CalorieCollection)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"Message": "All Entries Deleted",
-		"Count":   count,
-	})
-}
-
-func deleteAllRecipe(recipeColl *mongo.Collection) int64 {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	deletedAll, err := recipeColl.DeleteMany(ctx, bson.D{{}})
-
-	if err != nil {
-		fmt.Println(err)
+func DeleteOneRecipe(recipes repository.RecipeRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		if err := recipes.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Recipe not found", "Failed to delete recipe")
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"Message": fmt.Sprintf("Deleted entry with ID: %s", id),
+			"ID":      id,
+		})
 	}
-	fmt.Println("Deleted All Recipes ", deletedAll.DeletedCount)
-	defer cancel()
-	return deletedAll.DeletedCount
-}
-
-func DeleteOneRecipe(c *fiber.Ctx) error {
-	id := c.Params("id")
-	fmt.Println("id", id)
-	deleteOneRecipe(id, database.DB.CalorieCollection)
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"Message": fmt.Sprintf("Deleted entry with ID: %s", id),
-		"ID":      id,
-	})
 }
 
-func deleteOneRecipe(id string, recipeColl *mongo.Collection) {
-	ids, _ := primitive.ObjectIDFromHex(id)
-	filter := bson.M{"_id": ids}
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-
-	deletedId, err := recipeColl.DeleteOne(ctx, filter)
-
-	defer cancel()
-
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	fmt.Println("Deleted Recipe ", deletedId)
-}
+func UpdateRecipe(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
 
-func UpdateRecipe(c *fiber.Ctx) error {
-	id := c.Params("id")
+		var request models.CalorieTracker
 
-	var request models.CalorieTracker
+		if err := c.BodyParser(&request); err != nil {
+			return apperror.Validation("Invalid request body", nil)
+		}
+		models.NormalizeIngredients(request.Ingredients)
+		if err := validate.Struct(request); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if err := calculateNutrition(c.UserContext(), foods, &request); err != nil {
+			return apperror.Internal("Failed to calculate nutrition", err)
+		}
 
-	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
+		modifiedCount, err := recipes.Update(c.UserContext(), uid, id, request)
 
-	modifiedCount, err := updateRecipe(id, request, database.DB.CalorieCollection)
+		if err != nil {
+			return storeError(err, "Recipe not found", "Failed to update recipe")
+		}
 
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to update recipe: %v", err),
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"id":      id,
+			"message": "Recipe updated successfully",
+			"updated": modifiedCount,
 		})
 	}
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"id":      id,
-		"message": "Recipe updated successfully",
-		"updated": modifiedCount,
-	})
 }
 
-func updateRecipe(id string, body models.CalorieTracker, recipeColl *mongo.Collection) (int64, error) {
-	recipeId, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return 0, fmt.Errorf("invalid recipe ID format")
-	}
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-	update := bson.M{
-		"$set": bson.M{
-			"dish":        body.Dish,
-			"ingredients": body.Ingredients,
-			"calories":    body.Calories,
-			"fat":         body.Fat,
-		},
-	}
-	result, err := recipeColl.UpdateOne(ctx, bson.M{"_id": recipeId}, update)
-	if err != nil {
-		return 0, err
-	}
-
-	return result.ModifiedCount, nil
-}
+func UpdateIngredeints(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
 
-func UpdateIngredeints(c *fiber.Ctx) error {
-	id := c.Params("id")
+		var body struct {
+			Ingredients models.Ingredients `json:"ingredients" validate:"max=100,dive"`
+		}
 
-	var ingredients models.CalorieTracker
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body", nil)
+		}
+		models.NormalizeIngredients(body.Ingredients)
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
 
-	if err := c.BodyParser(&ingredients); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+		recipe, err := recipes.UpdateIngredients(c.UserContext(), uid, id, func(recipe *models.CalorieTracker) error {
+			recipe.Ingredients = body.Ingredients
+			return calculateNutrition(c.UserContext(), foods, recipe)
 		})
-	}
 
-	modifiedIngredient, err := updateIngredients(id, ingredients, database.DB.CalorieCollection)
+		if err != nil {
+			return ingredientError(err)
+		}
 
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to update recipe: %v", err),
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"id":          id,
+			"message":     "Ingredients updated successfully",
+			"updated":     1,
+			"ingredients": recipe.Ingredients,
 		})
-	}
-
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"id":      id,
-		"message": "Ingredients updated successfully",
-		"updated": modifiedIngredient,
-	})
-
-}
 
-func updateIngredients(id string, ingredients models.CalorieTracker, recipeColl *mongo.Collection) (int64, error) {
-	ingredientId, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return 0, fmt.Errorf("invalid ingredient id%v", ingredientId)
-
-	}
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	update := bson.M{
-		"$set": bson.M{
-			"ingredients": ingredients.Ingredients,
-		},
 	}
-
-	result, err := recipeColl.UpdateOne(ctx, bson.M{"_id": ingredientId}, update)
-
-	if err != nil {
-		return 0, err
-	}
-
-	return result.ModifiedCount, nil
 }
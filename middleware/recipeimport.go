@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// maxImportSize is the largest page or document a recipe is imported from
+const maxImportSize = 4 << 20
+
+// BodyLimit is the largest request body the server reads. It leaves room around
+// an import of maxImportSize, so that ImportRecipe rather than the server turns
+// down larger ones.
+const BodyLimit = maxImportSize + 1<<20
+
+// ImportRecipe creates a recipe from the schema.org Recipe of a web page or JSON-LD
+// document, sent as the request body or uploaded as the file form field. The
+// response lists the properties of the Recipe that could not be mapped.
+func ImportRecipe(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		data, err := importBody(c)
+		if err != nil {
+			return err
+		}
+
+		imported, err := models.ImportRecipe(data)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecipe) {
+				return apperror.Validation(err.Error(), map[string]string{"file": "must hold a schema.org Recipe"})
+			}
+			return apperror.Validation("Cannot read recipe: "+err.Error(), nil)
+		}
+		recipe := imported.Recipe
+		if err := validate.Struct(recipe); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if err := calculateNutrition(c.UserContext(), foods, &recipe); err != nil {
+			return apperror.Internal("Failed to calculate nutrition", err)
+		}
+		recipe.User_id = uid
+		if err := recipes.Create(c.UserContext(), &recipe); err != nil {
+			return apperror.Internal("Failed to create recipe", err)
+		}
+		recipe.Derive()
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":  "Recipe imported successfully",
+			"id":       recipe,
+			"unmapped": imported.Unmapped,
+		})
+	}
+}
+
+// importBody returns the uploaded file when the request is a form, and the
+// request body otherwise
+func importBody(c *fiber.Ctx) ([]byte, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		if len(c.Body()) == 0 {
+			return nil, apperror.Validation("Request validation failed", map[string]string{"file": "a page or JSON-LD document is required"})
+		}
+		if len(c.Body()) > maxImportSize {
+			return nil, apperror.Validation("Request validation failed", map[string]string{"file": "must be at most 4 MB"})
+		}
+		return c.Body(), nil
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, apperror.Validation("Request validation failed", map[string]string{"file": "a page or JSON-LD document is required"})
+	}
+	if header.Size > maxImportSize {
+		return nil, apperror.Validation("Request validation failed", map[string]string{"file": "must be at most 4 MB"})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, apperror.Internal("Failed to read upload", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, apperror.Internal("Failed to read upload", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultOccurrences is how many occurrences GetOccurrences expands without a limit
+const defaultOccurrences = 10
+
+// GetOccurrences expands the recurrence of a todo into the occurrences that follow it
+func GetOccurrences(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		limit := defaultOccurrences
+		if raw := c.Query("limit"); raw != "" {
+			limit, err = strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > models.MaxOccurrences {
+				return apperror.Validation("Invalid query parameters", map[string]string{"limit": "must be between 1 and " + strconv.Itoa(models.MaxOccurrences)})
+			}
+		}
+
+		todo, err := todos.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Task not found", "Failed to load task")
+		}
+		if todo.Recurrence == nil || todo.Due_at == nil {
+			return apperror.NotFound("Task does not repeat")
+		}
+
+		occurrences, err := todo.Recurrence.After(*todo.Due_at, limit)
+		if err != nil {
+			return recurrenceError(err)
+		}
+		return c.JSON(fiber.Map{
+			"rule":        todo.Recurrence.Rule,
+			"time_zone":   todo.Recurrence.Time_zone,
+			"occurrences": occurrences,
+		})
+	}
+}
+
+// startRecurrence checks a recurrence sent by the client and starts a new series at due
+func startRecurrence(recurrence *models.Recurrence, due *time.Time) error {
+	if due == nil {
+		return apperror.Validation("Repeating tasks need a due date", map[string]string{"due_at": "failed on required_with=recurrence"})
+	}
+	if err := validate.Struct(recurrence); err != nil {
+		return apperror.FromValidator(err)
+	}
+	if err := recurrence.Normalize(); err != nil {
+		return recurrenceError(err)
+	}
+	recurrence.Start = *due
+	recurrence.Series_id = primitive.NewObjectID()
+	recurrence.Index = 1
+	return nil
+}
+
+// nextOccurrence creates the todo following a completed repeating todo. It returns
+// nil when the series has ended or the next todo was already created earlier.
+func nextOccurrence(c *fiber.Ctx, todos repository.TodoRepository, todo *models.ToDoList) (*models.ToDoList, error) {
+	if todo.Recurrence == nil || todo.Due_at == nil {
+		return nil, nil
+	}
+	occurrences, err := todo.Recurrence.After(*todo.Due_at, 1)
+	if err != nil || len(occurrences) == 0 {
+		return nil, err
+	}
+
+	now := time.Now()
+	due := occurrences[0].Due_at.UTC()
+	recurrence := *todo.Recurrence
+	recurrence.Index = occurrences[0].Index
+	next := models.ToDoList{
+		Task:       todo.Task,
+		Status:     models.StatusOpen,
+		Priority:   todo.Priority,
+		Due_at:     &due,
+		Reminders:  todo.Reminders,
+		Created_at: now,
+		Updated_at: now,
+		Subtasks:   models.ResetSubtasks(todo.Subtasks),
+		Project_id: todo.Project_id,
+		Tags:       todo.Tags,
+		Recurrence: &recurrence,
+		User_id:    todo.User_id,
+	}
+	created, err := todos.CreateOccurrence(c.UserContext(), &next)
+	if err != nil || !created {
+		return nil, err
+	}
+	return &next, nil
+}
+
+func recurrenceError(err error) error {
+	if errors.Is(err, models.ErrInvalidRecurrence) {
+		return apperror.Validation(err.Error(), map[string]string{"recurrence": err.Error()})
+	}
+	return apperror.Internal("Failed to expand recurrence", err)
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// maxScaledServings is the most servings a recipe can be scaled to
+const maxScaledServings = 1000
+
+// ScaleRecipe returns a recipe scaled to the servings query parameter, by default
+// its own servings, with the nutrition recalculated for the new amounts. With
+// units set to metric or imperial the amounts are converted to that system, and
+// with weigh=true volumes are turned into weights where the density of the food is
+// known. The stored recipe is not changed.
+func ScaleRecipe(recipes repository.RecipeRepository, foods repository.FoodRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		fields := map[string]string{}
+		servings := intQuery(c, "servings", 0, maxScaledServings, fields)
+		units := c.Query("units")
+		if units != "" && units != models.UnitsMetric && units != models.UnitsImperial {
+			fields["units"] = "must be metric or imperial"
+		}
+		weigh := c.QueryBool("weigh")
+		if weigh && units == "" {
+			fields["weigh"] = "needs units to weigh in"
+		}
+		if len(fields) > 0 {
+			return apperror.Validation("Invalid query parameters", fields)
+		}
+
+		recipe, err := recipes.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Recipe not found", "Failed to load recipe")
+		}
+		recipe.Derive()
+		if servings == 0 {
+			servings = recipe.Servings
+		}
+		scaled := models.ScaledRecipe{Original_servings: recipe.Servings}
+		scaled.Factor = recipe.Scale(servings)
+
+		matches, err := matchIngredients(c.UserContext(), foods, recipe.Ingredients)
+		if err != nil {
+			return apperror.Internal("Failed to load foods", err)
+		}
+		entered := recipe.EnteredNutrition()
+		recipe.ApplyNutrition(models.CalculateNutrition(recipe.Ingredients, matches), entered)
+		recipe.Derive()
+
+		if units != "" {
+			for i, ingredient := range recipe.Ingredients {
+				var food *models.Food
+				if match, ok := matches[ingredient.Item]; ok {
+					food = &match
+				}
+				converted, ok := models.ConvertUnits(ingredient, units, food, weigh)
+				if !ok {
+					scaled.Unconverted = append(scaled.Unconverted, ingredient.String())
+				}
+				recipe.Ingredients[i] = converted
+			}
+		}
+		scaled.CalorieTracker = *recipe
+		return c.Status(fiber.StatusOK).JSON(scaled)
+	}
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// CreateShoppingList merges the ingredients of the given recipes, each scaled to
+// the servings asked for, into a shopping list grouped by aisle. Amounts are
+// written in metric units unless units is imperial. With a project name the list
+// is also saved as a new project holding one todo per item, tagged with its aisle.
+func CreateShoppingList(recipes repository.RecipeRepository, foods repository.FoodRepository, projects repository.ProjectRepository, todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Recipes []struct {
+				ID       string `json:"id" validate:"required"`
+				Servings int    `json:"servings" validate:"min=0,max=1000"`
+			} `json:"recipes" validate:"required,min=1,max=50,dive"`
+			Units   string `json:"units" validate:"omitempty,oneof=metric imperial"`
+			Project string `json:"project" validate:"max=100"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if body.Units == "" {
+			body.Units = models.UnitsMetric
+		}
+
+		var scaled []models.CalorieTracker
+		var ingredients models.Ingredients
+		for i, wanted := range body.Recipes {
+			recipe, err := recipes.Get(c.UserContext(), uid, wanted.ID)
+			if errors.Is(err, repository.ErrNotFound) {
+				field := "recipes[" + strconv.Itoa(i) + "].id"
+				return apperror.Validation("Request validation failed", map[string]string{field: "no such recipe"})
+			}
+			if err != nil {
+				return apperror.Internal("Failed to load recipe", err)
+			}
+			recipe.Derive()
+			if wanted.Servings > 0 {
+				recipe.Scale(wanted.Servings)
+			}
+			scaled = append(scaled, *recipe)
+			ingredients = append(ingredients, recipe.Ingredients...)
+		}
+		matches, err := matchIngredients(c.UserContext(), foods, ingredients)
+		if err != nil {
+			return apperror.Internal("Failed to load foods", err)
+		}
+		list := models.ShoppingList(scaled, matches, body.Units)
+		if body.Project == "" {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{"aisles": list})
+		}
+
+		now := time.Now()
+		project := models.Project{Name: body.Project, User_id: uid, Created_at: now, Updated_at: now}
+		if err := projects.Create(c.UserContext(), &project); err != nil {
+			return apperror.Internal("Failed to create project", err)
+		}
+		created := []models.ToDoList{}
+		for _, aisle := range list {
+			for _, item := range aisle.Items {
+				todo := models.ToDoList{
+					Task:       item.String(),
+					Status:     models.StatusOpen,
+					Project_id: &project.ID,
+					Tags:       models.NormalizeTags([]string{item.Aisle}),
+					Created_at: now,
+					Updated_at: now,
+					User_id:    uid,
+				}
+				if err := todos.Create(c.UserContext(), &todo); err != nil {
+					discardShoppingProject(c.UserContext(), projects, todos, uid, project, created)
+					return apperror.Internal("Failed to create task", err)
+				}
+				created = append(created, todo)
+			}
+		}
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"aisles": list, "project": project, "todos": created})
+	}
+}
+
+// discardShoppingProject removes a shopping project whose todos could not all be
+// created, so that no half-filled list is left behind. The cleanup outlives a
+// request that was cancelled; what cannot be removed is logged.
+func discardShoppingProject(ctx context.Context, projects repository.ProjectRepository, todos repository.TodoRepository, uid string, project models.Project, created []models.ToDoList) {
+	ctx = context.WithoutCancel(ctx)
+	for _, todo := range created {
+		if err := todos.Delete(ctx, uid, todo.ID.Hex()); err != nil {
+			log.Printf("Failed to remove task %s of shopping project %s: %v", todo.ID.Hex(), project.ID.Hex(), err)
+		}
+	}
+	if err := projects.Delete(ctx, uid, project.ID.Hex()); err != nil {
+		log.Printf("Failed to remove shopping project %s: %v", project.ID.Hex(), err)
+	}
+}
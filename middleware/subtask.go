@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateSubtask adds a subtask to a todo, below parent_id when it is given
+func CreateSubtask(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Title     string  `json:"title" validate:"required,max=500"`
+			Parent_id *string `json:"parent_id"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		parent, err := optionalSubtaskID(body.Parent_id, "parent_id")
+		if err != nil {
+			return err
+		}
+
+		todo, err := todos.UpdateSubtasks(c.UserContext(), uid, c.Params("id"), func(todo *models.ToDoList) error {
+			if err := editableTodo(todo); err != nil {
+				return err
+			}
+			_, err := models.AddSubtask(&todo.Subtasks, parent, body.Title)
+			return err
+		})
+		if err != nil {
+			return subtaskError(err)
+		}
+		todo.RollUp()
+		return c.JSON(todo)
+	}
+}
+
+// UpdateSubtask renames a subtask or changes its status. Finishing a subtask
+// finishes its children and reopening one reopens its finished parents.
+func UpdateSubtask(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Title  *string            `json:"title" validate:"omitempty,min=1,max=500"`
+			Status *models.TodoStatus `json:"status"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if body.Status != nil && (*body.Status == models.StatusArchived || !body.Status.Valid()) {
+			return apperror.Validation("Request validation failed", map[string]string{"status": "must be open, in_progress or done"})
+		}
+		subID, err := subtaskID(c.Params("subid"), "subid")
+		if err != nil {
+			return err
+		}
+
+		todo, err := todos.UpdateSubtasks(c.UserContext(), uid, c.Params("id"), func(todo *models.ToDoList) error {
+			if err := editableTodo(todo); err != nil {
+				return err
+			}
+			subtask, _ := models.FindSubtask(todo.Subtasks, subID)
+			if subtask == nil {
+				return models.ErrSubtaskNotFound
+			}
+			if body.Title != nil {
+				subtask.Title = *body.Title
+			}
+			if body.Status != nil {
+				return models.SetSubtaskStatus(todo.Subtasks, subID, *body.Status, time.Now())
+			}
+			return nil
+		})
+		if err != nil {
+			return subtaskError(err)
+		}
+		todo.RollUp()
+		return c.JSON(todo)
+	}
+}
+
+// DeleteSubtask removes a subtask and everything nested below it
+func DeleteSubtask(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		subID, err := subtaskID(c.Params("subid"), "subid")
+		if err != nil {
+			return err
+		}
+
+		todo, err := todos.UpdateSubtasks(c.UserContext(), uid, c.Params("id"), func(todo *models.ToDoList) error {
+			if err := editableTodo(todo); err != nil {
+				return err
+			}
+			if !models.RemoveSubtask(&todo.Subtasks, subID) {
+				return models.ErrSubtaskNotFound
+			}
+			return nil
+		})
+		if err != nil {
+			return subtaskError(err)
+		}
+		todo.RollUp()
+		return c.JSON(todo)
+	}
+}
+
+// ReorderSubtasks sets the order of the subtasks directly below parent_id, or of
+// the top level subtasks when no parent is given
+func ReorderSubtasks(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Parent_id *string  `json:"parent_id"`
+			Order     []string `json:"order" validate:"required"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		parent, err := optionalSubtaskID(body.Parent_id, "parent_id")
+		if err != nil {
+			return err
+		}
+		ids := make([]primitive.ObjectID, len(body.Order))
+		for i, raw := range body.Order {
+			if ids[i], err = subtaskID(raw, "order"); err != nil {
+				return err
+			}
+		}
+
+		todo, err := todos.UpdateSubtasks(c.UserContext(), uid, c.Params("id"), func(todo *models.ToDoList) error {
+			return models.ReorderSubtasks(todo.Subtasks, parent, ids)
+		})
+		if err != nil {
+			return subtaskError(err)
+		}
+		todo.RollUp()
+		return c.JSON(todo)
+	}
+}
+
+// completeSubtasks finishes every open subtask of a todo that was just completed
+func completeSubtasks(c *fiber.Ctx, todos repository.TodoRepository, uid string, todo *models.ToDoList) (*models.ToDoList, error) {
+	if todo.Status != models.StatusDone || len(todo.Subtasks) == 0 {
+		return todo, nil
+	}
+	return todos.UpdateSubtasks(c.UserContext(), uid, todo.ID.Hex(), func(todo *models.ToDoList) error {
+		models.CompleteSubtasks(todo.Subtasks, time.Now())
+		return nil
+	})
+}
+
+// editableTodo rejects checklist changes on todos that are done or archived
+func editableTodo(todo *models.ToDoList) error {
+	if todo.Status.Closed() {
+		return apperror.Conflict("Subtasks of a " + string(todo.Status) + " task cannot be changed, reopen it first")
+	}
+	return nil
+}
+
+func subtaskID(raw string, field string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return id, apperror.Validation("Invalid subtask id", map[string]string{field: "must be a valid id"})
+	}
+	return id, nil
+}
+
+func optionalSubtaskID(raw *string, field string) (*primitive.ObjectID, error) {
+	if raw == nil || *raw == "" {
+		return nil, nil
+	}
+	id, err := subtaskID(*raw, field)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+func subtaskError(err error) error {
+	var appErr *apperror.Error
+	switch {
+	case errors.As(err, &appErr):
+		return err
+	case errors.Is(err, models.ErrSubtaskNotFound):
+		return apperror.NotFound("Subtask not found")
+	case errors.Is(err, models.ErrSubtaskTooDeep):
+		return apperror.Validation(err.Error(), map[string]string{"parent_id": err.Error()})
+	case errors.Is(err, models.ErrSubtaskOrder):
+		return apperror.Validation(err.Error(), map[string]string{"order": err.Error()})
+	case errors.Is(err, repository.ErrConflict):
+		return apperror.Conflict("Task was changed by another request, please retry")
+	}
+	return storeError(err, "Task not found", "Failed to update subtasks")
+}
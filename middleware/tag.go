@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+// GetTags lists the user's tags with the number of todos carrying each
+func GetTags(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		tags, err := todos.Tags(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to load tags", err)
+		}
+		return c.JSON(tags)
+	}
+}
+
+// RenameTag renames a tag on every todo, merging it into an existing tag of the new name
+func RenameTag(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		tag, err := tagParam(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Name string `json:"name" validate:"required,max=50"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		body.Name = models.NormalizeTag(body.Name)
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+
+		var count int64
+		if body.Name != tag {
+			if count, err = todos.RenameTag(c.UserContext(), uid, tag, body.Name); err != nil {
+				return apperror.Internal("Failed to rename tag", err)
+			}
+		}
+		return c.JSON(fiber.Map{"tag": body.Name, "updated": count})
+	}
+}
+
+// DeleteTag removes a tag from every todo carrying it
+func DeleteTag(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		tag, err := tagParam(c)
+		if err != nil {
+			return err
+		}
+		count, err := todos.RemoveTag(c.UserContext(), uid, tag)
+		if err != nil {
+			return apperror.Internal("Failed to delete tag", err)
+		}
+		return c.JSON(fiber.Map{"tag": tag, "updated": count})
+	}
+}
+
+// tagParam reads the :tag path parameter, which clients percent-encode
+func tagParam(c *fiber.Ctx) (string, error) {
+	tag, err := url.PathUnescape(c.Params("tag"))
+	if err != nil || models.NormalizeTag(tag) == "" {
+		return "", apperror.Validation("Invalid tag", map[string]string{"tag": "must be a non-empty tag"})
+	}
+	return models.NormalizeTag(tag), nil
+}
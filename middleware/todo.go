@@ -1,167 +1,378 @@
 package middleware
 
 import (
-	"context"
-	"fmt"
-	"log"
+	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/khanirfan96/To-do-Fullstack-server/database"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
 	"github.com/khanirfan96/To-do-Fullstack-server/models"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
 )
 
-func GetTodo(c *fiber.Ctx) error {
-	uid := c.Locals("Uid").(string)
-	payload := getAllTasks(database.DB.TodoCollection, uid)
-	return c.JSON(payload)
-}
+func GetTodo(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		loc, err := requestLocation(c)
+		if err != nil {
+			return err
+		}
+		query, err := parseTodoQuery(c, loc)
+		if err != nil {
+			return err
+		}
 
-func CreateTodo(c *fiber.Ctx) error {
-	var task models.ToDoList
-	if err := c.BodyParser(&task); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot parse JSON",
-		})
+		page, err := todos.List(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load tasks", err)
+		}
+		for i := range page.Items {
+			page.Items[i].RollUp()
+		}
+
+		if c.Query("group") == "due" {
+			groups := groupByDue(page.Items, time.Now().In(loc))
+			groups.Next_cursor = page.Next_cursor
+			return c.JSON(groups)
+		}
+		return c.JSON(page)
 	}
-	insertOneTask(task, database.DB.TodoCollection)
-	return c.JSON(task)
 }
 
-func UpdateTodo(c *fiber.Ctx) error {
-	id := c.Params("id")
-	var body struct {
-		NewTask string `json:"task"`
-	}
+func CreateTodo(todos repository.TodoRepository, projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var task models.ToDoList
+		if err := c.BodyParser(&task); err != nil {
+			return apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+		}
+		task.Task = strings.TrimSpace(task.Task)
+		task.Tags = models.NormalizeTags(task.Tags)
+		if err := validate.Struct(task); err != nil {
+			return apperror.FromValidator(err)
+		}
+		if task.Project_id != nil {
+			projectID := task.Project_id.Hex()
+			if task.Project_id, err = projectRef(c, projects, uid, &projectID); err != nil {
+				return err
+			}
+		}
 
-	if err := c.BodyParser(&body); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		now := time.Now()
+		task.User_id = uid
+		task.Created_at = now
+		task.Updated_at = now
+		task.Completed_at = nil
+		if task.Status == "" {
+			task.Status = models.StatusOpen
+		}
+		if task.Status == models.StatusDone {
+			task.Completed_at = &now
+		}
+		task.Revision = 0
+		task.Next = nil
+		if task.Recurrence != nil {
+			if err := startRecurrence(task.Recurrence, task.Due_at); err != nil {
+				return err
+			}
+		}
+		if err := models.NormalizeSubtasks(task.Subtasks, 1, now); err != nil {
+			return apperror.Validation(err.Error(), map[string]string{"subtasks": err.Error()})
+		}
+		if task.Status == models.StatusDone {
+			models.CompleteSubtasks(task.Subtasks, now)
+		}
+		if err := todos.Create(c.UserContext(), &task); err != nil {
+			return apperror.Internal("Failed to create task", err)
+		}
+		task.RollUp()
+		return c.JSON(task)
 	}
+}
+
+func UpdateTodo(todos repository.TodoRepository, projects repository.ProjectRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		var body struct {
+			NewTask          *string            `json:"task" validate:"omitempty,min=1,max=500"`
+			Priority         *models.Priority   `json:"priority"`
+			Due_at           *time.Time         `json:"due_at"`
+			Clear_due        bool               `json:"clear_due_at"`
+			Reminders        *[]int             `json:"reminders" validate:"omitempty,max=10,dive,min=0,max=43200"`
+			Project_id       *string            `json:"project_id"`
+			Clear_project    bool               `json:"clear_project"`
+			Tags             *[]string          `json:"tags" validate:"omitempty,max=20,dive,max=50"`
+			Recurrence       *models.Recurrence `json:"recurrence"`
+			Clear_recurrence bool               `json:"clear_recurrence"`
+		}
+
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if body.NewTask != nil {
+			task := strings.TrimSpace(*body.NewTask)
+			body.NewTask = &task
+		}
+		if err := validate.Struct(body); err != nil {
+			return apperror.FromValidator(err)
+		}
+		setsReminders := body.Reminders != nil && len(*body.Reminders) > 0
+		if body.Clear_due && setsReminders {
+			return remindersWithoutDue()
+		}
 
-	if err := taskComplete(id, body.NewTask, database.DB.TodoCollection); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to update task: %v", err),
+		project, err := projectRef(c, projects, uid, body.Project_id)
+		if err != nil {
+			return err
+		}
+
+		// Reminders and a repeat rule are anchored on the due date, so they have to be
+		// looked at together with the one the task already has
+		if body.Recurrence != nil || (body.Clear_due && !body.Clear_recurrence) || (setsReminders && body.Due_at == nil) {
+			current, err := todos.Get(c.UserContext(), uid, id)
+			if err != nil {
+				return storeError(err, "Task not found", "Failed to update task")
+			}
+			due := current.Due_at
+			if body.Due_at != nil {
+				due = body.Due_at
+			}
+			if body.Clear_due {
+				due = nil
+			}
+			if setsReminders && due == nil {
+				return remindersWithoutDue()
+			}
+			if body.Recurrence != nil && !body.Clear_recurrence {
+				if err := startRecurrence(body.Recurrence, due); err != nil {
+					return err
+				}
+			} else if due == nil && current.Recurrence != nil {
+				return apperror.Validation("Repeating tasks need a due date", map[string]string{"due_at": "failed on required_with=recurrence"})
+			}
+		}
+
+		patch := models.TodoPatch{
+			Task:             body.NewTask,
+			Priority:         body.Priority,
+			Due_at:           body.Due_at,
+			Clear_due:        body.Clear_due,
+			Reminders:        body.Reminders,
+			Project_id:       project,
+			Clear_project:    body.Clear_project,
+			Tags:             body.Tags,
+			Recurrence:       body.Recurrence,
+			Clear_recurrence: body.Clear_recurrence,
+		}
+		if err := todos.Update(c.UserContext(), uid, id, patch); err != nil {
+			return storeError(err, "Task not found", "Failed to update task")
+		}
+
+		return c.JSON(fiber.Map{
+			"id":      id,
+			"message": "Task updated successfully",
 		})
 	}
-
-	return c.JSON(fiber.Map{
-		"id":      id,
-		"message": "Task updated successfully",
-	})
 }
 
-func UndoTodo(c *fiber.Ctx) error {
-	id := c.Params("id")
-	undoTask(id, database.DB.TodoCollection)
-	return c.JSON(id)
+// remindersWithoutDue rejects reminders on a task that has no due date to count back from
+func remindersWithoutDue() error {
+	return apperror.Validation("Reminders need a due date", map[string]string{"reminders": "failed on required_with=due_at"})
 }
 
-func DeleteOneTodo(c *fiber.Ctx) error {
-	id := c.Params("id")
-	deleteOneTask(id, database.DB.TodoCollection)
-	return c.JSON(id)
+// TransitionTodo moves a todo to the given lifecycle state, rejecting moves the
+// lifecycle does not allow with 409 Conflict. Completing a todo completes its subtasks
+// and creates the next occurrence of a repeating todo.
+func TransitionTodo(todos repository.TodoRepository, to models.TodoStatus) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		todo, err := transitionTodo(c, todos, uid, c.Params("id"), to)
+		if err != nil {
+			return transitionError(err)
+		}
+		if todo, err = completeTodo(c, todos, uid, todo); err != nil {
+			return err
+		}
+		return c.JSON(todo)
+	}
 }
 
-func DeleteAllTodo(c *fiber.Ctx) error {
-	count := deleteAllTask(database.DB.TodoCollection)
-	return c.JSON(count)
+// SetTodoStatus moves a todo to the status named in the request body
+func SetTodoStatus(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		var body struct {
+			Status models.TodoStatus `json:"status"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return apperror.Validation("Invalid request body: "+err.Error(), nil)
+		}
+		if body.Status == "" {
+			return apperror.Validation("Request validation failed", map[string]string{"status": "failed on required"})
+		}
+		todo, err := transitionTodo(c, todos, uid, c.Params("id"), body.Status)
+		if err != nil {
+			return transitionError(err)
+		}
+		if todo, err = completeTodo(c, todos, uid, todo); err != nil {
+			return err
+		}
+		return c.JSON(todo)
+	}
 }
 
-func getAllTasks(coll *mongo.Collection, id string) []primitive.M {
-
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	filter := bson.M{"user_id": id}
-
-	cursor, err := coll.Find(ctx, filter)
-	if err != nil {
-		log.Fatal(err)
-	}
-	var results []primitive.M
-	for cursor.Next(ctx) {
-		var result bson.M
-		e := cursor.Decode(&result)
-		if e != nil {
-			log.Fatal(e)
-		}
-		results = append(results, result)
+// transitionTodo moves a todo to another status. Completing a repeating todo that is
+// already done is let through, so a request that failed to create the next occurrence
+// can be retried; creating occurrences is idempotent.
+func transitionTodo(c *fiber.Ctx, todos repository.TodoRepository, uid string, id string, to models.TodoStatus) (*models.ToDoList, error) {
+	todo, err := todos.Transition(c.UserContext(), uid, id, to)
+	var transitionErr *models.TransitionError
+	if !errors.As(err, &transitionErr) || transitionErr.From != models.StatusDone || to != models.StatusDone {
+		return todo, err
 	}
-	if err := cursor.Err(); err != nil {
-		log.Fatal(err)
+	current, getErr := todos.Get(c.UserContext(), uid, id)
+	if getErr != nil || current.Recurrence == nil || current.Status != models.StatusDone {
+		return nil, err
 	}
-	defer cancel()
-	return results
+	return current, nil
 }
 
-func taskComplete(task string, newTask string, coll *mongo.Collection) error {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	id, err := primitive.ObjectIDFromHex(task)
-	defer cancel()
-	if err != nil {
-		return fmt.Errorf("invalid ID: %v", err)
+// completeTodo applies what follows a todo reaching done: its subtasks are finished
+// and a repeating todo gets its next occurrence
+func completeTodo(c *fiber.Ctx, todos repository.TodoRepository, uid string, todo *models.ToDoList) (*models.ToDoList, error) {
+	if todo.Status == models.StatusDone {
+		completed, err := completeSubtasks(c, todos, uid, todo)
+		if err != nil {
+			return nil, subtaskError(err)
+		}
+		if completed.Next, err = nextOccurrence(c, todos, completed); err != nil {
+			return nil, apperror.Internal("Failed to create the next occurrence", err)
+		}
+		if completed.Next != nil {
+			completed.Next.RollUp()
+		}
+		todo = completed
 	}
+	todo.RollUp()
+	return todo, nil
+}
 
-	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"status": true, "task": newTask}}
-
-	result, err := coll.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return fmt.Errorf("failed to update task: %v", err)
+func transitionError(err error) error {
+	var transitionErr *models.TransitionError
+	if errors.As(err, &transitionErr) {
+		return apperror.Conflict(transitionErr.Error())
 	}
+	return storeError(err, "Task not found", "Failed to update task status")
+}
 
-	if result.ModifiedCount == 0 {
-		return fmt.Errorf("no document matched the given ID")
+func DeleteOneTodo(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		if err := todos.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Task not found", "Failed to delete task")
+		}
+		return c.JSON(id)
 	}
-
-	return nil
 }
 
-func insertOneTask(task models.ToDoList, coll *mongo.Collection) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	insertResult, err := coll.InsertOne(ctx, task)
-	if err != nil {
-		log.Fatal(err)
+func DeleteAllTodo(todos repository.TodoRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		count, err := todos.DeleteAll(c.UserContext(), uid)
+		if err != nil {
+			return apperror.Internal("Failed to delete tasks", err)
+		}
+		return c.JSON(count)
 	}
-	defer cancel()
-	fmt.Println("Inserted a single record: ", insertResult.InsertedID)
 }
 
-func undoTask(task string, coll *mongo.Collection) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	id, _ := primitive.ObjectIDFromHex(task)
-	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"status": true}}
-	result, err := coll.UpdateOne(ctx, filter, update)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer cancel()
-	fmt.Println("Modified count", result.ModifiedCount)
+// todoGroups buckets todos by their due date relative to the caller's today
+type todoGroups struct {
+	Overdue     []models.ToDoList `json:"overdue"`
+	Today       []models.ToDoList `json:"today"`
+	Upcoming    []models.ToDoList `json:"upcoming"`
+	Unscheduled []models.ToDoList `json:"unscheduled"`
+	Completed   []models.ToDoList `json:"completed"`
+	Next_cursor string            `json:"next_cursor,omitempty"`
 }
 
-func deleteOneTask(task string, coll *mongo.Collection) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	id, _ := primitive.ObjectIDFromHex(task)
-	filter := bson.M{"_id": id}
-	deletedID, err := coll.DeleteOne(ctx, filter)
+// requestLocation reads the optional tz query parameter used to decide what "today" is
+func requestLocation(c *fiber.Ctx) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
 	if err != nil {
-		log.Fatal(err)
+		return nil, apperror.Validation("Unknown time zone", map[string]string{"tz": err.Error()})
 	}
-	defer cancel()
-	fmt.Println("Deleted Task: ", deletedID)
+	return loc, nil
 }
 
-func deleteAllTask(coll *mongo.Collection) int64 {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	deletedAll, err := coll.DeleteMany(ctx, bson.D{{}})
-	if err != nil {
-		log.Fatal(err)
+// groupByDue splits todos into overdue, today, upcoming and unscheduled. Todos still
+// needing work past their due time are overdue; closed todos due before today are
+// only completed.
+func groupByDue(todos []models.ToDoList, now time.Time) todoGroups {
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endOfDay := startOfDay.AddDate(0, 0, 1)
+
+	groups := todoGroups{
+		Overdue:     []models.ToDoList{},
+		Today:       []models.ToDoList{},
+		Upcoming:    []models.ToDoList{},
+		Unscheduled: []models.ToDoList{},
+		Completed:   []models.ToDoList{},
+	}
+	for _, todo := range todos {
+		switch {
+		case todo.Due_at == nil:
+			groups.Unscheduled = append(groups.Unscheduled, todo)
+		case !todo.Status.Closed() && todo.Due_at.Before(now):
+			groups.Overdue = append(groups.Overdue, todo)
+		case todo.Due_at.Before(startOfDay):
+			groups.Completed = append(groups.Completed, todo)
+		case todo.Due_at.Before(endOfDay):
+			groups.Today = append(groups.Today, todo)
+		default:
+			groups.Upcoming = append(groups.Upcoming, todo)
+		}
 	}
-	defer cancel()
-	fmt.Println("Deleted All Tasks: ", deletedAll.DeletedCount)
-	return deletedAll.DeletedCount
+
+	for _, group := range [][]models.ToDoList{groups.Overdue, groups.Today, groups.Upcoming, groups.Completed} {
+		sort.SliceStable(group, func(i, j int) bool {
+			if !group[i].Due_at.Equal(*group[j].Due_at) {
+				return group[i].Due_at.Before(*group[j].Due_at)
+			}
+			return group[i].Priority > group[j].Priority
+		})
+	}
+	sort.SliceStable(groups.Unscheduled, func(i, j int) bool {
+		return groups.Unscheduled[i].Priority > groups.Unscheduled[j].Priority
+	})
+	return groups
 }
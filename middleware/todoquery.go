@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var todoSortFields = []string{repository.SortCreated, repository.SortUpdated, repository.SortDue, repository.SortPriority}
+
+// parseTodoQuery reads the filter, sort and paging parameters of GET /api/gettodo:
+// status and priority (comma separated), due_after, due_before, q, project (an id or
+// none), tag (comma separated, all must match), sort (prefix - for descending), limit
+// and cursor. Dates without a time are taken in loc. Without a
+// status filter archived todos are left out.
+func parseTodoQuery(c *fiber.Ctx, loc *time.Location) (repository.TodoQuery, error) {
+	query := repository.TodoQuery{Sort: repository.TodoSort{Field: repository.SortCreated}}
+	fields := map[string]string{}
+
+	if statuses := c.Query("status"); statuses != "" {
+		for _, name := range strings.Split(statuses, ",") {
+			status, err := models.ParseTodoStatus(strings.TrimSpace(name))
+			if err != nil {
+				fields["status"] = err.Error()
+				break
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	} else {
+		// Archived todos stay out of the way unless asked for
+		query.Statuses = []models.TodoStatus{models.StatusOpen, models.StatusInProgress, models.StatusDone}
+	}
+
+	if priorities := c.Query("priority"); priorities != "" {
+		for _, name := range strings.Split(priorities, ",") {
+			priority, err := models.ParsePriority(strings.TrimSpace(name))
+			if err != nil {
+				fields["priority"] = err.Error()
+				break
+			}
+			query.Priorities = append(query.Priorities, priority)
+		}
+	}
+
+	for _, param := range []string{"due_after", "due_before"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		at, err := parseQueryTime(value, loc)
+		if err != nil {
+			fields[param] = "must be an RFC 3339 time or a YYYY-MM-DD date"
+			continue
+		}
+		if param == "due_after" {
+			query.Due_after = &at
+		} else {
+			query.Due_before = &at
+		}
+	}
+
+	query.Text = strings.TrimSpace(c.Query("q"))
+
+	switch project := c.Query("project"); project {
+	case "":
+	case "none":
+		query.No_project = true
+	default:
+		id, err := primitive.ObjectIDFromHex(project)
+		if err != nil {
+			fields["project"] = "must be a project id or none"
+		}
+		query.Project = &id
+	}
+
+	if tags := c.Query("tag"); tags != "" {
+		query.Tags = models.NormalizeTags(strings.Split(tags, ","))
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		query.Sort.Desc = strings.HasPrefix(sort, "-")
+		query.Sort.Field = strings.TrimPrefix(sort, "-")
+		if !slices.Contains(todoSortFields, query.Sort.Field) {
+			fields["sort"] = "must be one of " + strings.Join(todoSortFields, ", ") + ", optionally prefixed with -"
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > repository.MaxTodoLimit {
+			fields["limit"] = "must be between 1 and " + strconv.Itoa(repository.MaxTodoLimit)
+		}
+		query.Limit = n
+	}
+
+	if len(fields) > 0 {
+		return query, apperror.Validation("Invalid query parameters", fields)
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		decoded, err := repository.DecodeTodoCursor(cursor, query.Sort)
+		if err != nil {
+			if errors.Is(err, repository.ErrInvalidCursor) {
+				return query, apperror.Validation("Invalid query parameters", map[string]string{"cursor": err.Error()})
+			}
+			return query, err
+		}
+		query.Cursor = decoded
+	}
+
+	return query, nil
+}
+
+// parseQueryTime accepts an RFC 3339 time or a plain date, which means midnight in loc
+func parseQueryTime(value string, loc *time.Location) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.ParseInLocation(time.DateOnly, value, loc)
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetWorkouts lists the workouts of the user, newest first. from and to narrow the
+// listing to the workouts performed in between.
+func GetWorkouts(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		loc, err := requestLocation(c)
+		if err != nil {
+			return err
+		}
+		query, err := parseWorkoutQuery(c, loc)
+		if err != nil {
+			return err
+		}
+		payload, err := workouts.List(c.UserContext(), uid, query)
+		if err != nil {
+			return apperror.Internal("Failed to load workouts", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(payload)
+	}
+}
+
+// parseWorkoutQuery reads the from, to and limit query parameters
+func parseWorkoutQuery(c *fiber.Ctx, loc *time.Location) (repository.WorkoutQuery, error) {
+	var query repository.WorkoutQuery
+	fields := map[string]string{}
+
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		at, err := parseQueryTime(value, loc)
+		if err != nil {
+			fields[param] = "must be an RFC 3339 time or a YYYY-MM-DD date"
+			continue
+		}
+		if param == "from" {
+			query.From = &at
+		} else {
+			query.To = &at
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > repository.MaxWorkoutLimit {
+			fields["limit"] = "must be between 1 and " + strconv.Itoa(repository.MaxWorkoutLimit)
+		}
+		query.Limit = n
+	}
+
+	if len(fields) > 0 {
+		return query, apperror.Validation("Invalid query parameters", fields)
+	}
+	return query, nil
+}
+
+// GetWorkout returns a single workout
+func GetWorkout(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		workout, err := workouts.Get(c.UserContext(), uid, c.Params("id"))
+		if err != nil {
+			return storeError(err, "Workout not found", "Failed to load workout")
+		}
+		return c.Status(fiber.StatusOK).JSON(workout)
+	}
+}
+
+// CreateWorkout logs a workout and reports the personal records it set
+func CreateWorkout(workouts repository.WorkoutRepository, gym repository.GymRepository, exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		workout, plan, err := workoutBody(c, gym, exercises, uid)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		workout.ID = primitive.NilObjectID
+		workout.User_id = uid
+		workout.Created_at = now
+		workout.Updated_at = now
+		if workout.Records, err = detectRecords(c, workouts, uid, workout); err != nil {
+			return apperror.Internal("Failed to create workout", err)
+		}
+		if err := workouts.Create(c.UserContext(), workout); err != nil {
+			return apperror.Internal("Failed to create workout", err)
+		}
+		if workout.Progression, err = advanceProgram(c, gym, uid, plan, workout); err != nil {
+			return apperror.Internal("Failed to advance the program", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(workout)
+	}
+}
+
+// UpdateWorkout replaces a workout and reports the personal records it now holds
+// against the workouts performed before it. The program of its plan is not
+// evaluated again.
+func UpdateWorkout(workouts repository.WorkoutRepository, gym repository.GymRepository, exercises repository.ExerciseRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		workout, _, err := workoutBody(c, gym, exercises, uid)
+		if err != nil {
+			return err
+		}
+
+		workout.Updated_at = time.Now()
+		updated, err := workouts.Update(c.UserContext(), uid, c.Params("id"), *workout)
+		if err != nil {
+			return storeError(err, "Workout not found", "Failed to update workout")
+		}
+		if updated.Records, err = detectRecords(c, workouts, uid, updated); err != nil {
+			return apperror.Internal("Failed to update workout", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(updated)
+	}
+}
+
+// DeleteWorkout removes a workout
+func DeleteWorkout(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		id := c.Params("id")
+		if err := workouts.Delete(c.UserContext(), uid, id); err != nil {
+			return storeError(err, "Workout not found", "Failed to delete workout")
+		}
+		return c.Status(fiber.StatusOK).JSON(id)
+	}
+}
+
+// GetRecords returns the all-time bests of every exercise the user has logged
+func GetRecords(workouts repository.WorkoutRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		uid, err := currentUser(c)
+		if err != nil {
+			return err
+		}
+		records, err := workouts.Records(c.UserContext(), uid, nil, nil)
+		if err != nil {
+			return apperror.Internal("Failed to load personal records", err)
+		}
+		return c.Status(fiber.StatusOK).JSON(records)
+	}
+}
+
+// workoutBody parses and validates a workout and derives its exercise totals. A
+// referenced plan must belong to the user and is returned along; exercises
+// referencing the catalog take their name from it.
+func workoutBody(c *fiber.Ctx, gym repository.GymRepository, exercises repository.ExerciseRepository, uid string) (*models.Workout, *models.Gym, error) {
+	var workout models.Workout
+	if err := c.BodyParser(&workout); err != nil {
+		return nil, nil, apperror.Validation("Cannot parse JSON: "+err.Error(), nil)
+	}
+	if err := validate.Struct(workout); err != nil {
+		return nil, nil, apperror.FromValidator(err)
+	}
+	var plan *models.Gym
+	if workout.Plan_id != nil {
+		var err error
+		plan, err = gym.Get(c.UserContext(), uid, workout.Plan_id.Hex())
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, nil, apperror.Validation("Gym schedule not found", map[string]string{"plan_id": "must be one of your gym schedules"})
+			}
+			return nil, nil, apperror.Internal("Failed to load gym schedule", err)
+		}
+	}
+
+	var ids []primitive.ObjectID
+	for _, e := range workout.Exercises {
+		if e.Exercise_id != nil {
+			ids = append(ids, *e.Exercise_id)
+		}
+	}
+	catalog, err := catalogExercises(c, exercises, uid, ids, "exercise_id")
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, e := range workout.Exercises {
+		if e.Exercise_id != nil {
+			workout.Exercises[i].Name = catalog[*e.Exercise_id].Name
+		}
+	}
+
+	workout.Records = nil
+	workout.Progression = nil
+	workout.Summarize()
+	return &workout, plan, nil
+}
+
+// detectRecords compares a workout with every workout the user performed before it
+func detectRecords(c *fiber.Ctx, workouts repository.WorkoutRepository, uid string, workout *models.Workout) ([]models.PersonalRecord, error) {
+	earlier, err := workouts.Records(c.UserContext(), uid, &workout.Performed_at, workout.Keys())
+	if err != nil {
+		return nil, err
+	}
+	return workout.DetectRecords(earlier), nil
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SecondaryShare is how much of its sets and volume an exercise credits to the
+// muscles it trains secondarily
+const SecondaryShare = 0.5
+
+// Imbalance warning kinds
+const (
+	WarningImbalance = "imbalance"
+	WarningNeglected = "neglected"
+	WarningLowVolume = "low_volume"
+)
+
+// MinWeeklySets is the weekly working sets below which a major muscle is reported
+// as undertrained
+const MinWeeklySets = 4
+
+// MajorMuscles are the muscles every balanced program trains directly
+var MajorMuscles = []string{"chest", "upper_back", "lats", "front_delts", "side_delts", "quadriceps", "hamstrings", "glutes"}
+
+// muscleBalances pairs opposing muscle groups with the ratio of working sets
+// between them beyond which they count as imbalanced
+var muscleBalances = []struct {
+	name  string
+	one   []string
+	other []string
+	ratio float64
+}{
+	{"push to pull", []string{"chest", "front_delts"}, []string{"upper_back", "lats", "rear_delts"}, 1.5},
+	{"quadriceps to hamstrings", []string{"quadriceps"}, []string{"hamstrings"}, 2},
+	{"biceps to triceps", []string{"biceps"}, []string{"triceps"}, 1.5},
+}
+
+// MuscleVolume is the work a muscle received in one week. Sets counts working sets
+// and Volume weight times reps, both with secondary muscles credited at
+// SecondaryShare; Sessions counts the workouts training the muscle.
+type MuscleVolume struct {
+	Week     time.Time `json:"week"`
+	Muscle   string    `json:"muscle"`
+	Sets     float64   `json:"sets"`
+	Volume   float64   `json:"volume"`
+	Sessions int       `json:"sessions"`
+}
+
+// ExerciseTrend is the weekly best estimated one rep max of an exercise
+type ExerciseTrend struct {
+	Key      string       `json:"key" bson:"_id"`
+	Exercise string       `json:"exercise"`
+	Points   []TrendPoint `json:"points"`
+	Change   float64      `json:"change"`
+}
+
+// TrendPoint is the best performance of an exercise in one week
+type TrendPoint struct {
+	Week       time.Time `json:"week"`
+	E1rm       float64   `json:"e1rm"`
+	Top_weight float64   `json:"top_weight"`
+}
+
+// WeeklyFrequency counts how often the user trained in one week
+type WeeklyFrequency struct {
+	Week     time.Time `json:"week" bson:"_id"`
+	Workouts int       `json:"workouts"`
+	Days     int       `json:"days"`
+	Minutes  int       `json:"minutes"`
+}
+
+// BalanceWarning points out muscles trained too little or out of proportion
+type BalanceWarning struct {
+	Kind    string   `json:"kind"`
+	Muscles []string `json:"muscles"`
+	Ratio   float64  `json:"ratio,omitempty"`
+	Message string   `json:"message"`
+}
+
+// StartOfWeek returns midnight of the Monday of the week at falls in, in loc
+func StartOfWeek(at time.Time, loc *time.Location) time.Time {
+	at = at.In(loc)
+	offset := (int(at.Weekday()) + 6) % 7
+	return time.Date(at.Year(), at.Month(), at.Day()-offset, 0, 0, 0, 0, loc)
+}
+
+// Summarize sets the change of the trend from its first to its last point
+func (t *ExerciseTrend) Summarize() {
+	if len(t.Points) > 0 {
+		t.Change = roundTo(t.Points[len(t.Points)-1].E1rm-t.Points[0].E1rm, 2)
+	}
+}
+
+// MuscleTotals adds up the weekly sets of every muscle
+func MuscleTotals(volumes []MuscleVolume) map[string]float64 {
+	totals := map[string]float64{}
+	for _, v := range volumes {
+		totals[v.Muscle] += v.Sets
+	}
+	return totals
+}
+
+// BalanceWarnings inspects the working sets per muscle over a number of weeks.
+// It warns about major muscles left untrained or below MinWeeklySets a week and
+// about opposing muscle groups trained out of proportion.
+func BalanceWarnings(totals map[string]float64, weeks int) []BalanceWarning {
+	warnings := []BalanceWarning{}
+	if weeks < 1 {
+		weeks = 1
+	}
+
+	trained := false
+	for _, sets := range totals {
+		trained = trained || sets > 0
+	}
+	if !trained {
+		return warnings
+	}
+
+	for _, muscle := range MajorMuscles {
+		weekly := totals[muscle] / float64(weeks)
+		switch {
+		case totals[muscle] == 0:
+			warnings = append(warnings, BalanceWarning{
+				Kind:    WarningNeglected,
+				Muscles: []string{muscle},
+				Message: fmt.Sprintf("%s was not trained", muscle),
+			})
+		case weekly < MinWeeklySets:
+			warnings = append(warnings, BalanceWarning{
+				Kind:    WarningLowVolume,
+				Muscles: []string{muscle},
+				Message: fmt.Sprintf("%s got %.1f working sets a week, fewer than %d", muscle, weekly, MinWeeklySets),
+			})
+		}
+	}
+
+	for _, balance := range muscleBalances {
+		one, other := 0.0, 0.0
+		for _, m := range balance.one {
+			one += totals[m]
+		}
+		for _, m := range balance.other {
+			other += totals[m]
+		}
+		if one == 0 || other == 0 {
+			// An untrained side is already reported as neglected
+			continue
+		}
+		ratio := one / other
+		if ratio < 1 {
+			ratio = 1 / ratio
+		}
+		if ratio <= balance.ratio {
+			continue
+		}
+		stronger, weaker := balance.one, balance.other
+		if other > one {
+			stronger, weaker = weaker, stronger
+		}
+		warnings = append(warnings, BalanceWarning{
+			Kind:    WarningImbalance,
+			Muscles: append(append([]string{}, stronger...), weaker...),
+			Ratio:   roundTo(ratio, 2),
+			Message: fmt.Sprintf("%s is %.1f to 1: %s get more work than %s", balance.name, ratio, strings.Join(stronger, ", "), strings.Join(weaker, ", ")),
+		})
+	}
+	return warnings
+}
+
+// SortMuscleVolumes orders volumes by week, then muscle
+func SortMuscleVolumes(volumes []MuscleVolume) {
+	sort.Slice(volumes, func(i, j int) bool {
+		if !volumes[i].Week.Equal(volumes[j].Week) {
+			return volumes[i].Week.Before(volumes[j].Week)
+		}
+		return volumes[i].Muscle < volumes[j].Muscle
+	})
+}
+
+// RoundVolume rounds sets and volume for presentation
+func (v *MuscleVolume) RoundVolume() {
+	v.Sets = math.Round(v.Sets*10) / 10
+	v.Volume = roundTo(v.Volume, 1)
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Body metrics. Weight is in kilograms, body fat in percent and the measurements
+// in centimetres.
+const (
+	MetricWeight  = "weight"
+	MetricBodyFat = "body_fat"
+	MetricWaist   = "waist"
+	MetricChest   = "chest"
+	MetricArm     = "arm"
+)
+
+// BodyMetrics lists every metric a measurement can carry
+var BodyMetrics = []string{MetricWeight, MetricBodyFat, MetricWaist, MetricChest, MetricArm}
+
+// BodyMetric is one measurement of the user's body. Any metric may be left out,
+// but not all of them. Averages holds the moving average of every metric at the
+// time of the measurement.
+type BodyMetric struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id"`
+	Measured_at time.Time          `json:"measured_at" validate:"required"`
+	Weight      *float64           `json:"weight,omitempty" bson:"weight,omitempty" validate:"omitempty,gt=0,max=500"`
+	Body_fat    *float64           `json:"body_fat,omitempty" bson:"body_fat,omitempty" validate:"omitempty,gt=0,lt=100"`
+	Waist       *float64           `json:"waist,omitempty" bson:"waist,omitempty" validate:"omitempty,gt=0,max=300"`
+	Chest       *float64           `json:"chest,omitempty" bson:"chest,omitempty" validate:"omitempty,gt=0,max=300"`
+	Arm         *float64           `json:"arm,omitempty" bson:"arm,omitempty" validate:"omitempty,gt=0,max=150"`
+	Notes       string             `json:"notes,omitempty" bson:"notes,omitempty" validate:"max=500"`
+	Averages    map[string]float64 `json:"averages,omitempty" bson:"-"`
+	Created_at  time.Time          `json:"created_at"`
+	Updated_at  time.Time          `json:"updated_at"`
+	User_id     string             `json:"user_id"`
+}
+
+// BodyGoal is the body weight the user works towards, kept on the user.
+// Start_weight is the weight when the goal was set.
+type BodyGoal struct {
+	Weight       float64    `json:"weight" validate:"required,gt=0,max=500"`
+	Body_fat     *float64   `json:"body_fat,omitempty" bson:"body_fat,omitempty" validate:"omitempty,gt=0,lt=100"`
+	Target_date  *time.Time `json:"target_date,omitempty" bson:"target_date,omitempty"`
+	Start_weight *float64   `json:"start_weight,omitempty" bson:"start_weight,omitempty" validate:"omitempty,gt=0,max=500"`
+	Started_at   time.Time  `json:"started_at"`
+}
+
+// MetricTrend summarizes one metric over a period. Rate is the change per week
+// of the least squares line through the measurements.
+type MetricTrend struct {
+	Metric   string   `json:"metric"`
+	Latest   *float64 `json:"latest"`
+	Average  *float64 `json:"average"`
+	Rate     *float64 `json:"rate_per_week"`
+	Readings int      `json:"readings"`
+}
+
+// ProjectionHorizon is how many weeks ahead a goal is projected. A trend too slow
+// to reach the goal within it gets no projected date.
+const ProjectionHorizon = 5 * 52
+
+// GoalProgress tells how far the user got towards the body goal and when it will
+// be reached at the current rate
+type GoalProgress struct {
+	Goal           BodyGoal   `json:"goal"`
+	Current        *float64   `json:"current"`
+	Remaining      *float64   `json:"remaining"`
+	Percent        *int       `json:"percent"`
+	Rate           *float64   `json:"rate_per_week"`
+	Required_rate  *float64   `json:"required_rate_per_week,omitempty"`
+	Projected_date *time.Time `json:"projected_date,omitempty"`
+	On_track       *bool      `json:"on_track,omitempty"`
+}
+
+// Value returns the named metric of the measurement, nil when it was not taken
+func (m *BodyMetric) Value(metric string) *float64 {
+	switch metric {
+	case MetricWeight:
+		return m.Weight
+	case MetricBodyFat:
+		return m.Body_fat
+	case MetricWaist:
+		return m.Waist
+	case MetricChest:
+		return m.Chest
+	case MetricArm:
+		return m.Arm
+	}
+	return nil
+}
+
+// Empty reports whether the measurement carries no metric at all
+func (m *BodyMetric) Empty() bool {
+	for _, metric := range BodyMetrics {
+		if m.Value(metric) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SortBodyMetrics orders measurements oldest first
+func SortBodyMetrics(metrics []BodyMetric) {
+	sort.SliceStable(metrics, func(i, j int) bool { return metrics[i].Measured_at.Before(metrics[j].Measured_at) })
+}
+
+// MovingAverages sets the averages of every measurement over the measurements taken
+// in the days up to and including it. metrics must be sorted oldest first.
+func MovingAverages(metrics []BodyMetric, days int) {
+	window := time.Duration(days) * 24 * time.Hour
+	for i := range metrics {
+		metrics[i].Averages = map[string]float64{}
+		for _, metric := range BodyMetrics {
+			sum, count := 0.0, 0
+			for j := i; j >= 0 && metrics[i].Measured_at.Sub(metrics[j].Measured_at) < window; j-- {
+				if value := metrics[j].Value(metric); value != nil {
+					sum += *value
+					count++
+				}
+			}
+			if count > 0 && metrics[i].Value(metric) != nil {
+				metrics[i].Averages[metric] = roundTo(sum/float64(count), 2)
+			}
+		}
+	}
+}
+
+// Trend summarizes a metric over measurements sorted oldest first. The average is
+// the latest moving average, so MovingAverages must have run.
+func Trend(metrics []BodyMetric, metric string) MetricTrend {
+	trend := MetricTrend{Metric: metric}
+	var days, values []float64
+	for i := range metrics {
+		value := metrics[i].Value(metric)
+		if value == nil {
+			continue
+		}
+		trend.Readings++
+		latest := *value
+		trend.Latest = &latest
+		if average, ok := metrics[i].Averages[metric]; ok {
+			trend.Average = &average
+		}
+		days = append(days, metrics[i].Measured_at.Sub(metrics[0].Measured_at).Hours()/24)
+		values = append(values, *value)
+	}
+	if slope, ok := leastSquares(days, values); ok {
+		rate := roundTo(slope*7, 2)
+		trend.Rate = &rate
+	}
+	return trend
+}
+
+// leastSquares returns the slope of the line fitted through the points. It needs
+// at least two points a day or more apart.
+func leastSquares(xs []float64, ys []float64) (float64, bool) {
+	if len(xs) < 2 || xs[len(xs)-1]-xs[0] < 1 {
+		return 0, false
+	}
+	n := float64(len(xs))
+	var sumX, sumY, sumXY, sumXX float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+		sumXY += xs[i] * ys[i]
+		sumXX += xs[i] * xs[i]
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// Progress measures the goal against the current weight and its weekly rate of
+// change, either of which may be unknown
+func (g BodyGoal) Progress(current *float64, rate *float64, now time.Time) GoalProgress {
+	progress := GoalProgress{Goal: g, Current: current, Rate: rate}
+	if current == nil {
+		return progress
+	}
+
+	remaining := roundTo(g.Weight-*current, 2)
+	progress.Remaining = &remaining
+	if g.Start_weight != nil {
+		total := g.Weight - *g.Start_weight
+		percent := 100
+		if total != 0 {
+			percent = int(math.Round((*current - *g.Start_weight) / total * 100))
+			percent = min(max(percent, 0), 100)
+		}
+		progress.Percent = &percent
+	}
+	if remaining == 0 {
+		return progress
+	}
+
+	if rate != nil && *rate != 0 && (*rate > 0) == (remaining > 0) {
+		if weeks := remaining / *rate; weeks <= ProjectionHorizon {
+			projected := now.AddDate(0, 0, int(math.Ceil(weeks*7)))
+			progress.Projected_date = &projected
+		}
+	}
+	if g.Target_date != nil {
+		if weeks := g.Target_date.Sub(now).Hours() / (24 * 7); weeks > 0 {
+			required := roundTo(remaining/weeks, 2)
+			progress.Required_rate = &required
+		}
+		onTrack := progress.Projected_date != nil && !progress.Projected_date.After(*g.Target_date)
+		progress.On_track = &onTrack
+	}
+	return progress
+}
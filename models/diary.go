@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Meals are the slots of a diary day, in the order they are eaten
+var Meals = []string{"breakfast", "lunch", "dinner", "snack"}
+
+// FoodEntry is something the user ate on a day: servings of one of their recipes,
+// or of an ad-hoc food. The nutrition of a serving is copied from the recipe when
+// the entry is saved, so changing the recipe later leaves past days as they were.
+// Total is the nutrition of all servings eaten.
+type FoodEntry struct {
+	ID          primitive.ObjectID  `json:"_id" bson:"_id"`
+	Date        string              `json:"date" validate:"required,datetime=2006-01-02"`
+	Meal        string              `json:"meal" validate:"required,oneof=breakfast lunch dinner snack"`
+	Recipe_id   *primitive.ObjectID `json:"recipe_id,omitempty" bson:"recipe_id,omitempty"`
+	Name        string              `json:"name" validate:"max=200"`
+	Servings    float64             `json:"servings" validate:"min=0,max=100"`
+	Per_serving Nutrients           `json:"per_serving" bson:"per_serving"`
+	Total       Nutrients           `json:"total" bson:"-"`
+	Created_at  time.Time           `json:"created_at"`
+	Updated_at  time.Time           `json:"updated_at"`
+	User_id     string              `json:"user_id"`
+}
+
+// MealLog is one meal of a diary day
+type MealLog struct {
+	Meal    string      `json:"meal"`
+	Entries []FoodEntry `json:"entries"`
+	Total   Nutrients   `json:"total"`
+}
+
+// DiaryDay is everything eaten on a day, meal by meal, next to the daily targets
+// of the user, what is left of them and how close the day came
+type DiaryDay struct {
+	Date      string     `json:"date"`
+	Meals     []MealLog  `json:"meals"`
+	Total     Nutrients  `json:"total"`
+	Targets   *Nutrients `json:"targets"`
+	Remaining *Nutrients `json:"remaining"`
+	Adherence *Adherence `json:"adherence"`
+}
+
+// Tally fills in the total of the entry, one serving when none is given
+func (e *FoodEntry) Tally() {
+	if e.Servings <= 0 {
+		e.Servings = 1
+	}
+	e.Total = e.Per_serving.Scale(e.Servings).Round()
+}
+
+// DiaryDays groups entries into the days from one date to another, both included.
+// Every day and every meal is present, empty ones too.
+func DiaryDays(entries []FoodEntry, from time.Time, to time.Time, targets *Nutrients) []DiaryDay {
+	byDate := map[string][]FoodEntry{}
+	for _, entry := range entries {
+		entry.Tally()
+		byDate[entry.Date] = append(byDate[entry.Date], entry)
+	}
+
+	days := []DiaryDay{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		diary := DiaryDay{Date: date, Targets: targets}
+		for _, meal := range Meals {
+			log := MealLog{Meal: meal, Entries: []FoodEntry{}}
+			for _, entry := range byDate[date] {
+				if entry.Meal == meal {
+					log.Entries = append(log.Entries, entry)
+					log.Total = log.Total.Add(entry.Total)
+				}
+			}
+			log.Total = log.Total.Round()
+			diary.Meals = append(diary.Meals, log)
+			diary.Total = diary.Total.Add(log.Total)
+		}
+		diary.Total = diary.Total.Round()
+		if targets != nil {
+			remaining := Remaining(*targets, diary.Total)
+			adherence := Adhere(*targets, diary.Total)
+			diary.Remaining, diary.Adherence = &remaining, &adherence
+		}
+		days = append(days, diary)
+	}
+	return days
+}
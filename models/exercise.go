@@ -0,0 +1,161 @@
+package models
+
+import (
+	"crypto/sha1"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Muscle groups an exercise can train
+var Muscles = []string{
+	"chest", "upper_back", "lats", "traps", "front_delts", "side_delts", "rear_delts",
+	"biceps", "triceps", "forearms", "abs", "obliques", "lower_back",
+	"glutes", "quadriceps", "hamstrings", "adductors", "calves",
+}
+
+// Equipment an exercise is performed with
+var Equipment = []string{"barbell", "dumbbell", "kettlebell", "machine", "cable", "bodyweight", "band", "other"}
+
+// Movement patterns exercises are grouped by
+var Patterns = []string{
+	"horizontal_push", "vertical_push", "horizontal_pull", "vertical_pull",
+	"squat", "hinge", "lunge", "carry", "core", "isolation",
+}
+
+// Exercise is an entry of the exercise catalog. Built-in exercises have no
+// User_id and are shared by everyone; users add their own next to them.
+type Exercise struct {
+	ID                primitive.ObjectID `json:"_id" bson:"_id"`
+	Name              string             `json:"name" validate:"required,max=100"`
+	Key               string             `json:"-"`
+	Primary_muscles   []string           `json:"primary_muscles" validate:"required,min=1,max=5,dive,muscle"`
+	Secondary_muscles []string           `json:"secondary_muscles" validate:"max=8,dive,muscle"`
+	Equipment         string             `json:"equipment" validate:"required,equipment"`
+	Pattern           string             `json:"pattern" validate:"required,pattern"`
+	Created_at        time.Time          `json:"created_at"`
+	Updated_at        time.Time          `json:"updated_at"`
+	User_id           string             `json:"user_id,omitempty"`
+}
+
+// ScheduledExercise places a catalog exercise on a day of a gym plan
+type ScheduledExercise struct {
+	Day         string             `json:"day" validate:"required,oneof=monday tuesday wednesday thursday friday saturday sunday"`
+	Exercise_id primitive.ObjectID `json:"exercise_id" validate:"required"`
+	Sets        int                `json:"sets,omitempty" bson:"sets,omitempty" validate:"min=0,max=20"`
+	Reps        int                `json:"reps,omitempty" bson:"reps,omitempty" validate:"min=0,max=100"`
+	Weight      float64            `json:"weight,omitempty" bson:"weight,omitempty" validate:"min=0,max=2000"`
+}
+
+// Normalize derives the key of the exercise from its name and drops duplicate muscles
+func (e *Exercise) Normalize() {
+	e.Name = strings.Join(strings.Fields(e.Name), " ")
+	e.Key = ExerciseKey(e.Name)
+	e.Primary_muscles = distinct(e.Primary_muscles)
+	e.Secondary_muscles = distinct(e.Secondary_muscles)
+}
+
+// Trains reports whether the exercise works the muscle, primarily or not
+func (e *Exercise) Trains(muscle string) bool {
+	for _, m := range e.Primary_muscles {
+		if m == muscle {
+			return true
+		}
+	}
+	for _, m := range e.Secondary_muscles {
+		if m == muscle {
+			return true
+		}
+	}
+	return false
+}
+
+func distinct(values []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// CatalogID is the id of a built-in exercise. It is derived from the name so the
+// catalog keeps its ids across seeding runs and storage backends.
+func CatalogID(name string) primitive.ObjectID {
+	sum := sha1.Sum([]byte("exercise:" + ExerciseKey(name)))
+	var id primitive.ObjectID
+	copy(id[:], sum[:])
+	return id
+}
+
+// ExerciseCatalog returns the built-in exercises
+func ExerciseCatalog() []Exercise {
+	exercises := make([]Exercise, len(catalog))
+	for i, entry := range catalog {
+		exercises[i] = Exercise{
+			ID:                CatalogID(entry.name),
+			Name:              entry.name,
+			Primary_muscles:   strings.Fields(entry.primary),
+			Secondary_muscles: strings.Fields(entry.secondary),
+			Equipment:         entry.equipment,
+			Pattern:           entry.pattern,
+		}
+		exercises[i].Normalize()
+	}
+	return exercises
+}
+
+var catalog = []struct {
+	name      string
+	primary   string
+	secondary string
+	equipment string
+	pattern   string
+}{
+	{"Bench Press", "chest", "front_delts triceps", "barbell", "horizontal_push"},
+	{"Incline Bench Press", "chest front_delts", "triceps", "barbell", "horizontal_push"},
+	{"Dumbbell Bench Press", "chest", "front_delts triceps", "dumbbell", "horizontal_push"},
+	{"Incline Dumbbell Press", "chest front_delts", "triceps", "dumbbell", "horizontal_push"},
+	{"Push-Up", "chest", "front_delts triceps abs", "bodyweight", "horizontal_push"},
+	{"Dip", "chest triceps", "front_delts", "bodyweight", "vertical_push"},
+	{"Overhead Press", "front_delts", "side_delts triceps upper_back", "barbell", "vertical_push"},
+	{"Dumbbell Shoulder Press", "front_delts", "side_delts triceps", "dumbbell", "vertical_push"},
+	{"Lateral Raise", "side_delts", "traps", "dumbbell", "isolation"},
+	{"Cable Fly", "chest", "front_delts", "cable", "isolation"},
+	{"Triceps Pushdown", "triceps", "", "cable", "isolation"},
+	{"Skull Crusher", "triceps", "", "barbell", "isolation"},
+	{"Pull-Up", "lats", "biceps upper_back rear_delts", "bodyweight", "vertical_pull"},
+	{"Chin-Up", "lats biceps", "upper_back", "bodyweight", "vertical_pull"},
+	{"Lat Pulldown", "lats", "biceps upper_back", "cable", "vertical_pull"},
+	{"Barbell Row", "upper_back lats", "rear_delts biceps lower_back", "barbell", "horizontal_pull"},
+	{"Dumbbell Row", "lats upper_back", "rear_delts biceps", "dumbbell", "horizontal_pull"},
+	{"Seated Cable Row", "upper_back lats", "rear_delts biceps", "cable", "horizontal_pull"},
+	{"Face Pull", "rear_delts", "upper_back traps", "cable", "horizontal_pull"},
+	{"Barbell Curl", "biceps", "forearms", "barbell", "isolation"},
+	{"Dumbbell Curl", "biceps", "forearms", "dumbbell", "isolation"},
+	{"Hammer Curl", "biceps forearms", "", "dumbbell", "isolation"},
+	{"Shrug", "traps", "forearms", "barbell", "isolation"},
+	{"Squat", "quadriceps glutes", "adductors lower_back", "barbell", "squat"},
+	{"Front Squat", "quadriceps", "glutes upper_back abs", "barbell", "squat"},
+	{"Goblet Squat", "quadriceps glutes", "abs", "kettlebell", "squat"},
+	{"Leg Press", "quadriceps glutes", "adductors", "machine", "squat"},
+	{"Deadlift", "hamstrings glutes lower_back", "traps forearms quadriceps", "barbell", "hinge"},
+	{"Romanian Deadlift", "hamstrings glutes", "lower_back forearms", "barbell", "hinge"},
+	{"Hip Thrust", "glutes", "hamstrings", "barbell", "hinge"},
+	{"Kettlebell Swing", "glutes hamstrings", "lower_back abs", "kettlebell", "hinge"},
+	{"Power Clean", "glutes hamstrings quadriceps", "traps upper_back", "barbell", "hinge"},
+	{"Walking Lunge", "quadriceps glutes", "hamstrings adductors", "dumbbell", "lunge"},
+	{"Bulgarian Split Squat", "quadriceps glutes", "adductors", "dumbbell", "lunge"},
+	{"Leg Extension", "quadriceps", "", "machine", "isolation"},
+	{"Leg Curl", "hamstrings", "calves", "machine", "isolation"},
+	{"Standing Calf Raise", "calves", "", "machine", "isolation"},
+	{"Farmer's Carry", "forearms traps", "abs glutes", "dumbbell", "carry"},
+	{"Plank", "abs", "obliques", "bodyweight", "core"},
+	{"Hanging Leg Raise", "abs", "obliques forearms", "bodyweight", "core"},
+	{"Cable Crunch", "abs", "obliques", "cable", "core"},
+	{"Back Extension", "lower_back", "glutes hamstrings", "bodyweight", "hinge"},
+}
@@ -0,0 +1,482 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Food is an entry of the food composition database ingredients are matched
+// against. Nutrients are given per 100 g. Density (grams per milliliter) weighs
+// ingredients measured by volume, water being assumed when it is unknown;
+// Unit_weight is the weight of one piece, such as one egg or one clove of garlic,
+// and Unit_weights the weight of kitchen units such as a can, a bunch or a handful
+// of the food. Aisle is the part of a store the food is found in, and Source
+// whether the food is built in or was imported.
+type Food struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	Name         string             `json:"name"`
+	Aliases      []string           `json:"aliases,omitempty" bson:"aliases,omitempty"`
+	Keys         []string           `json:"-"`
+	Per_100g     Nutrients          `json:"per_100g" bson:"per_100g"`
+	Density      *float64           `json:"density,omitempty" bson:"density,omitempty"`
+	Unit_weight  *float64           `json:"unit_weight,omitempty" bson:"unit_weight,omitempty"`
+	Unit_weights map[string]float64 `json:"unit_weights,omitempty" bson:"unit_weights,omitempty"`
+	Aisle        string             `json:"aisle,omitempty" bson:"aisle,omitempty"`
+	Source       string             `json:"-" bson:"source,omitempty"`
+}
+
+// Food sources
+const (
+	FoodBuiltIn  = "builtin"
+	FoodImported = "imported"
+)
+
+// Nutrients is an amount of energy and nutrients: calories in kcal, fat, protein,
+// carbohydrates, fiber and sugar in grams, the minerals and vitamin C in milligrams
+type Nutrients struct {
+	Calories      float64 `json:"calories" validate:"min=0,max=100000"`
+	Fat           float64 `json:"fat" validate:"min=0,max=10000"`
+	Protein       float64 `json:"protein" validate:"min=0,max=10000"`
+	Carbohydrates float64 `json:"carbohydrates" validate:"min=0,max=10000"`
+	Fiber         float64 `json:"fiber" validate:"min=0,max=10000"`
+	Sugar         float64 `json:"sugar" validate:"min=0,max=10000"`
+	Sodium        float64 `json:"sodium" validate:"min=0,max=1000000"`
+	Potassium     float64 `json:"potassium" validate:"min=0,max=1000000"`
+	Calcium       float64 `json:"calcium" validate:"min=0,max=1000000"`
+	Iron          float64 `json:"iron" validate:"min=0,max=100000"`
+	Vitamin_c     float64 `json:"vitamin_c" validate:"min=0,max=100000"`
+}
+
+// Nutrition sources
+const (
+	NutritionCalculated = "calculated"
+	NutritionManual     = "manual"
+)
+
+// Nutrition is what a whole recipe contains. It is calculated from the ingredients,
+// or entered by hand when none of them can be counted. Unmatched lists the
+// ingredients left out of a calculation: those without a food in the database or
+// with an amount that cannot be weighed.
+type Nutrition struct {
+	Nutrients `bson:",inline"`
+	Source    string   `json:"source"`
+	Unmatched []string `json:"unmatched,omitempty" bson:"unmatched,omitempty"`
+}
+
+// Scale multiplies every nutrient by factor
+func (n Nutrients) Scale(factor float64) Nutrients {
+	return n.combine(n, func(a, _ float64) float64 { return a * factor })
+}
+
+// Add sums two amounts of nutrients
+func (n Nutrients) Add(other Nutrients) Nutrients {
+	return n.combine(other, func(a, b float64) float64 { return a + b })
+}
+
+// Round rounds calories, sodium, potassium and calcium to whole units and the
+// rest to a tenth
+func (n Nutrients) Round() Nutrients {
+	return Nutrients{
+		Calories:      math.Round(n.Calories),
+		Fat:           roundTo(n.Fat, 1),
+		Protein:       roundTo(n.Protein, 1),
+		Carbohydrates: roundTo(n.Carbohydrates, 1),
+		Fiber:         roundTo(n.Fiber, 1),
+		Sugar:         roundTo(n.Sugar, 1),
+		Sodium:        math.Round(n.Sodium),
+		Potassium:     math.Round(n.Potassium),
+		Calcium:       math.Round(n.Calcium),
+		Iron:          roundTo(n.Iron, 1),
+		Vitamin_c:     roundTo(n.Vitamin_c, 1),
+	}
+}
+
+func (n Nutrients) combine(other Nutrients, fn func(a, b float64) float64) Nutrients {
+	return Nutrients{
+		Calories:      fn(n.Calories, other.Calories),
+		Fat:           fn(n.Fat, other.Fat),
+		Protein:       fn(n.Protein, other.Protein),
+		Carbohydrates: fn(n.Carbohydrates, other.Carbohydrates),
+		Fiber:         fn(n.Fiber, other.Fiber),
+		Sugar:         fn(n.Sugar, other.Sugar),
+		Sodium:        fn(n.Sodium, other.Sodium),
+		Potassium:     fn(n.Potassium, other.Potassium),
+		Calcium:       fn(n.Calcium, other.Calcium),
+		Iron:          fn(n.Iron, other.Iron),
+		Vitamin_c:     fn(n.Vitamin_c, other.Vitamin_c),
+	}
+}
+
+// Weights and volumes of the canonical units, in grams and milliliters
+var (
+	unitGrams = map[string]float64{
+		UnitGram: 1, UnitKilogram: 1000, UnitMilligram: 0.001, UnitOunce: 28.3495, UnitPound: 453.592,
+	}
+	unitMilliliters = map[string]float64{
+		UnitMilliliter: 1, UnitLiter: 1000, UnitTeaspoon: 4.92892, UnitTablespoon: 14.7868,
+		UnitCup: 236.588, UnitFluidOunce: 29.5735, UnitPint: 473.176, UnitQuart: 946.353,
+		UnitGallon: 3785.41, "pinch": 0.31, "dash": 0.62,
+	}
+	// countedUnits count pieces of a food, each weighing its unit weight
+	countedUnits = map[string]bool{"": true, "piece": true, "clove": true, "slice": true}
+)
+
+// Grams weighs an ingredient of the given food. Pieces, cloves and slices are
+// weighed by the unit weight of the food; other kitchen units such as cans or
+// handfuls only when the food gives a weight for them. It reports false when the
+// ingredient has no amount or the food gives no way to weigh it.
+func (f Food) Grams(ingredient Ingredient) (float64, bool) {
+	if ingredient.Quantity == nil {
+		return 0, false
+	}
+	quantity := *ingredient.Quantity
+	if grams, ok := unitGrams[ingredient.Unit]; ok {
+		return quantity * grams, true
+	}
+	if milliliters, ok := unitMilliliters[ingredient.Unit]; ok {
+		density := 1.0
+		if f.Density != nil {
+			density = *f.Density
+		}
+		return quantity * milliliters * density, true
+	}
+	if weight, ok := f.Unit_weights[ingredient.Unit]; ok {
+		return quantity * weight, true
+	}
+	if countedUnits[ingredient.Unit] && f.Unit_weight != nil {
+		return quantity * *f.Unit_weight, true
+	}
+	return 0, false
+}
+
+// CalculateNutrition adds up the nutrients of the ingredients matched to a food.
+// It returns nil for a recipe without ingredients.
+func CalculateNutrition(ingredients Ingredients, foods map[string]Food) *Nutrition {
+	if len(ingredients) == 0 {
+		return nil
+	}
+	nutrition := &Nutrition{Source: NutritionCalculated}
+	for _, ingredient := range ingredients {
+		food, ok := foods[ingredient.Item]
+		if !ok {
+			nutrition.Unmatched = append(nutrition.Unmatched, ingredient.Item)
+			continue
+		}
+		grams, ok := food.Grams(ingredient)
+		if !ok {
+			nutrition.Unmatched = append(nutrition.Unmatched, ingredient.Item)
+			continue
+		}
+		nutrition.Nutrients = nutrition.Add(food.Per_100g.Scale(grams / 100))
+	}
+	nutrition.Nutrients = nutrition.Round()
+	return nutrition
+}
+
+// Counted reports whether at least one ingredient went into the totals
+func (n *Nutrition) Counted(ingredients Ingredients) bool {
+	return n != nil && len(n.Unmatched) < len(ingredients)
+}
+
+// FoodKey normalizes a food name for matching: lower case, letters and digits
+// only, each word in singular
+func FoodKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = singular(word)
+	}
+	return strings.Join(words, " ")
+}
+
+func singular(word string) string {
+	switch {
+	case len(word) <= 3:
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "oes"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// FoodCandidates returns every run of consecutive words of an ingredient item as a
+// key a food may be known by, so "extra virgin olive oil" can match "olive oil"
+func FoodCandidates(item string) []string {
+	words := strings.Fields(FoodKey(item))
+	var candidates []string
+	for size := len(words); size > 0; size-- {
+		for start := 0; start+size <= len(words); start++ {
+			candidates = append(candidates, strings.Join(words[start:start+size], " "))
+		}
+	}
+	return candidates
+}
+
+// MatchFood picks the food an ingredient item refers to: the one known by the
+// longest run of words of the item, preferring foods named exactly that over
+// foods merely aliased so. It reports false when no food matches.
+func MatchFood(item string, foods []Food) (Food, bool) {
+	rank := map[string]int{}
+	for _, candidate := range FoodCandidates(item) {
+		rank[candidate] = len(strings.Fields(candidate))
+	}
+
+	var best Food
+	bestWords, bestExact, found := 0, false, false
+	for _, food := range foods {
+		for k, key := range food.Keys {
+			words, ok := rank[key]
+			if !ok {
+				continue
+			}
+			// Keys[0] is the food's own name
+			exact := k == 0
+			better := words > bestWords ||
+				(words == bestWords && exact && !bestExact) ||
+				(words == bestWords && exact == bestExact && len(food.Name) < len(best.Name))
+			if !found || better {
+				best, bestWords, bestExact, found = food, words, exact, true
+			}
+		}
+	}
+	return best, found
+}
+
+// Normalize tidies the name and aliases of a food and derives its matching keys,
+// its own name first
+func (f *Food) Normalize() {
+	f.Name = strings.Join(strings.Fields(f.Name), " ")
+	f.Keys = []string{FoodKey(f.Name)}
+	// Descriptions such as "Flour, wheat, all-purpose" are also known by their head
+	if head, _, found := strings.Cut(f.Name, ","); found {
+		f.Keys = append(f.Keys, FoodKey(head))
+	}
+	for _, alias := range f.Aliases {
+		f.Keys = append(f.Keys, FoodKey(alias))
+	}
+	f.Keys = distinct(f.Keys)
+}
+
+// FoodID derives the id of a food from its name, so importing the same food again
+// updates it instead of adding a copy
+func FoodID(name string) primitive.ObjectID {
+	sum := sha1.Sum([]byte("food:" + FoodKey(name)))
+	var id primitive.ObjectID
+	copy(id[:], sum[:])
+	return id
+}
+
+//go:embed foods.csv
+var foodsCSV []byte
+
+// FoodCatalog returns the built-in food composition database
+func FoodCatalog() []Food {
+	foods, err := ParseFoods(bytes.NewReader(foodsCSV))
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in food database: %v", err))
+	}
+	for i := range foods {
+		foods[i].Source = FoodBuiltIn
+	}
+	return foods
+}
+
+// foodColumn is a column heading of a food composition dump and the factor that
+// converts its values into the units of Nutrients
+type foodColumn struct {
+	heading string
+	scale   float64
+}
+
+// foodColumns maps the column headings of known food composition dumps to the
+// fields of Food: the built-in database, USDA FoodData Central exports and Open
+// Food Facts exports. All of them give nutrients per 100 g, Open Food Facts gives
+// minerals and vitamins in grams.
+var foodColumns = map[string][]foodColumn{
+	"name":          {{"name", 1}, {"description", 1}, {"food", 1}, {"food_name", 1}, {"product_name", 1}},
+	"aliases":       {{"aliases", 1}, {"alias", 1}, {"generic_name", 1}},
+	"calories":      {{"calories", 1}, {"energy_kcal", 1}, {"energy-kcal_100g", 1}, {"energy (kcal)", 1}, {"energy kcal", 1}},
+	"fat":           {{"fat", 1}, {"fat_100g", 1}, {"total lipid (fat)", 1}, {"total_fat", 1}},
+	"protein":       {{"protein", 1}, {"proteins_100g", 1}, {"proteins", 1}},
+	"carbohydrates": {{"carbohydrates", 1}, {"carbohydrates_100g", 1}, {"carbohydrate, by difference", 1}, {"carbs", 1}},
+	"fiber":         {{"fiber", 1}, {"fiber_100g", 1}, {"fibre", 1}, {"fiber, total dietary", 1}},
+	"sugar":         {{"sugar", 1}, {"sugars", 1}, {"sugars_100g", 1}, {"sugars, total including nlea", 1}, {"sugars, total", 1}},
+	"sodium":        {{"sodium", 1}, {"sodium, na", 1}, {"sodium_100g", 1000}},
+	"potassium":     {{"potassium", 1}, {"potassium, k", 1}, {"potassium_100g", 1000}},
+	"calcium":       {{"calcium", 1}, {"calcium, ca", 1}, {"calcium_100g", 1000}},
+	"iron":          {{"iron", 1}, {"iron, fe", 1}, {"iron_100g", 1000}},
+	"vitamin_c":     {{"vitamin_c", 1}, {"vitamin c, total ascorbic acid", 1}, {"vitamin-c_100g", 1000}},
+	"density":       {{"density", 1}, {"density_g_ml", 1}},
+	"unit_weight":   {{"unit_weight", 1}, {"piece_weight", 1}},
+	"unit_weights":  {{"unit_weights", 1}},
+	"aisle":         {{"aisle", 1}},
+}
+
+// ParseFoods reads a food composition table. The columns are recognized by their
+// headings (see foodColumns) and may be separated by commas or tabs; aliases are
+// separated by "|", and so are the unit weights, written as "can=400|handful=30". Rows without a name or an energy value are skipped.
+func ParseFoods(r io.Reader) ([]Food, error) {
+	buffered := bufio.NewReader(r)
+	reader := csv.NewReader(buffered)
+	if first, _ := buffered.Peek(4096); foodDelimiter(first) == '\t' {
+		reader.Comma = '\t'
+	}
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := foodHeader(header)
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("no name column")
+	}
+	if _, ok := columns["calories"]; !ok {
+		return nil, errors.New("no energy column")
+	}
+
+	var foods []Food
+	seen := map[primitive.ObjectID]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if column, ok := columns[name]; ok && column.index < len(record) {
+				return strings.TrimSpace(record[column.index])
+			}
+			return ""
+		}
+		number := func(name string) *float64 {
+			value, err := strconv.ParseFloat(field(name), 64)
+			if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+				return nil
+			}
+			value *= columns[name].scale
+			return &value
+		}
+		value := func(name string) float64 {
+			if n := number(name); n != nil {
+				return *n
+			}
+			return 0
+		}
+
+		calories := number("calories")
+		if field("name") == "" || calories == nil {
+			continue
+		}
+		food := Food{
+			Name: field("name"),
+			Per_100g: Nutrients{
+				Calories:      *calories,
+				Fat:           value("fat"),
+				Protein:       value("protein"),
+				Carbohydrates: value("carbohydrates"),
+				Fiber:         value("fiber"),
+				Sugar:         value("sugar"),
+				Sodium:        value("sodium"),
+				Potassium:     value("potassium"),
+				Calcium:       value("calcium"),
+				Iron:          value("iron"),
+				Vitamin_c:     value("vitamin_c"),
+			},
+			Density:      number("density"),
+			Unit_weight:  number("unit_weight"),
+			Unit_weights: unitWeights(field("unit_weights")),
+			Aisle:        strings.ToLower(field("aisle")),
+		}
+		for _, alias := range strings.Split(field("aliases"), "|") {
+			if alias = strings.TrimSpace(alias); alias != "" {
+				food.Aliases = append(food.Aliases, alias)
+			}
+		}
+		food.Normalize()
+		food.ID = FoodID(food.Name)
+
+		// A later row of the same food replaces the earlier one
+		if i, ok := seen[food.ID]; ok {
+			foods[i] = food
+			continue
+		}
+		seen[food.ID] = len(foods)
+		foods = append(foods, food)
+	}
+	sort.SliceStable(foods, func(i, j int) bool { return foods[i].Name < foods[j].Name })
+	return foods, nil
+}
+
+// unitWeights reads weights of kitchen units such as "can=400|handful=30", skipping
+// entries that are not a unit with a positive weight
+func unitWeights(value string) map[string]float64 {
+	var weights map[string]float64
+	for _, entry := range strings.Split(value, "|") {
+		unit, raw, ok := strings.Cut(entry, "=")
+		unit = NormalizeUnit(unit)
+		weight, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if !ok || unit == "" || err != nil || weight <= 0 || math.IsInf(weight, 0) {
+			continue
+		}
+		if weights == nil {
+			weights = map[string]float64{}
+		}
+		weights[unit] = weight
+	}
+	return weights
+}
+
+// foodDelimiter tells tab separated dumps, as Open Food Facts exports are, from
+// comma separated ones by their header line
+func foodDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(line, []byte("\t")) > bytes.Count(line, []byte(",")) {
+		return '\t'
+	}
+	return ','
+}
+
+type headerColumn struct {
+	index int
+	scale float64
+}
+
+func foodHeader(header []string) map[string]headerColumn {
+	columns := map[string]headerColumn{}
+	for i, heading := range header {
+		heading = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(heading, "\ufeff")))
+		for field, known := range foodColumns {
+			for _, column := range known {
+				if _, taken := columns[field]; !taken && heading == column.heading {
+					columns[field] = headerColumn{index: i, scale: column.scale}
+				}
+			}
+		}
+	}
+	return columns
+}
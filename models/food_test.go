@@ -0,0 +1,71 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+)
+
+func TestFoodGrams(t *testing.T) {
+	catalog := models.FoodCatalog()
+	tests := []struct {
+		line  string
+		food  string
+		grams float64
+		ok    bool
+	}{
+		{line: "200 g flour", food: "all-purpose flour", grams: 200, ok: true},
+		{line: "1 cup flour", food: "all-purpose flour", grams: 125.4, ok: true},
+		{line: "1 cup water", food: "water", grams: 236.6, ok: true},
+		{line: "3 eggs", food: "egg", grams: 150, ok: true},
+		{line: "2 pieces chicken breast", food: "chicken breast", grams: 400, ok: true},
+		{line: "3 cloves garlic", food: "garlic", grams: 9, ok: true},
+		{line: "2 slices bread", food: "white bread", grams: 60, ok: true},
+		{line: "1 handful strawberries", food: "strawberry", grams: 80, ok: true},
+		{line: "2 handfuls spinach", food: "spinach", grams: 60, ok: true},
+		{line: "1 can crushed tomatoes", food: "canned tomatoes", grams: 400, ok: true},
+		{line: "1 stick butter", food: "butter", grams: 113, ok: true},
+		// Kitchen units the food gives no weight for cannot be weighed by one piece
+		{line: "1 can tomatoes", food: "tomato"},
+		{line: "2 bunches carrots", food: "carrot"},
+		{line: "1 package broccoli", food: "broccoli"},
+		{line: "1 handful mushrooms", food: "mushroom"},
+		{line: "spinach", food: "spinach"},
+	}
+
+	for _, test := range tests {
+		ingredient := models.ParseIngredient(test.line)
+		food, found := models.MatchFood(ingredient.Item, catalog)
+		if !found || food.Name != test.food {
+			t.Errorf("%q matched %q, want %q", test.line, food.Name, test.food)
+			continue
+		}
+		grams, ok := food.Grams(ingredient)
+		if ok != test.ok || (ok && !near(grams, test.grams)) {
+			t.Errorf("%q weighs %v (%v), want %v (%v)", test.line, grams, ok, test.grams, test.ok)
+		}
+	}
+}
+
+func TestParseFoodsUnitWeights(t *testing.T) {
+	foods, err := models.ParseFoods(strings.NewReader("name,calories,unit_weight,unit_weights\n" +
+		"chickpeas,164,,Cans=240|handful=25|bag=|tin=-3\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"can": 240, "handful": 25}
+	if len(foods) != 1 || len(foods[0].Unit_weights) != len(want) {
+		t.Fatalf("parsed %+v, want unit weights %v", foods, want)
+	}
+	for unit, weight := range want {
+		if foods[0].Unit_weights[unit] != weight {
+			t.Errorf("a %s weighs %v, want %v", unit, foods[0].Unit_weights[unit], weight)
+		}
+	}
+}
+
+// near compares weights to a tenth of a gram
+func near(a float64, b float64) bool {
+	return a-b < 0.05 && b-a < 0.05
+}
@@ -0,0 +1,423 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// MaxIngredients is how many ingredients a recipe may list
+const MaxIngredients = 100
+
+var (
+	ErrIngredientNotFound = errors.New("ingredient not found")
+	ErrIngredientLimit    = errors.New("a recipe cannot list more than 100 ingredients")
+	ErrIngredientOrder    = errors.New("order must list every ingredient of the recipe exactly once")
+)
+
+// Ingredient is one line of a recipe, such as 2 cups of flour, sifted. Quantity is
+// nil for ingredients without an amount ("salt to taste"); Unit is one of the
+// canonical units below, or empty for counted items ("3 eggs").
+type Ingredient struct {
+	ID       primitive.ObjectID `json:"_id" bson:"_id"`
+	Quantity *float64           `json:"quantity,omitempty" bson:"quantity,omitempty" validate:"omitempty,gt=0,max=100000"`
+	Unit     string             `json:"unit,omitempty" bson:"unit,omitempty" validate:"max=30"`
+	Item     string             `json:"item" validate:"required,max=200"`
+	Note     string             `json:"note,omitempty" bson:"note,omitempty" validate:"max=200"`
+}
+
+// Ingredients is the ordered ingredient list of a recipe. Besides a list of
+// ingredients it can be read from free text, one ingredient per line, which is
+// also how recipes stored ingredients before they were structured.
+type Ingredients []Ingredient
+
+// UnmarshalJSON accepts a free-text string, a list of lines or a list of ingredients
+func (i *Ingredients) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*i = nil
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*i = ParseIngredients(text)
+		return nil
+	}
+	return json.Unmarshal(data, (*[]Ingredient)(i))
+}
+
+// UnmarshalBSONValue reads ingredient lists as well as the free-text strings
+// older recipes were saved with
+func (i *Ingredients) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		*i = nil
+		return nil
+	case bsontype.String:
+		text, _, ok := bsoncore.ReadString(data)
+		if !ok {
+			return errors.New("invalid ingredients string")
+		}
+		*i = ParseIngredients(text)
+		return nil
+	}
+	return bson.UnmarshalValue(t, data, (*[]Ingredient)(i))
+}
+
+// UnmarshalJSON accepts either an ingredient object or a line of text to parse
+func (i *Ingredient) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var line string
+		if err := json.Unmarshal(data, &line); err != nil {
+			return err
+		}
+		*i = ParseIngredient(line)
+		return nil
+	}
+	type plain Ingredient
+	return json.Unmarshal(data, (*plain)(i))
+}
+
+// String formats the ingredient back into a line of text
+func (i Ingredient) String() string {
+	var parts []string
+	if i.Quantity != nil {
+		parts = append(parts, strconv.FormatFloat(*i.Quantity, 'f', -1, 64))
+	}
+	if i.Unit != "" {
+		parts = append(parts, i.Unit)
+	}
+	parts = append(parts, i.Item)
+	line := strings.Join(parts, " ")
+	if i.Note != "" {
+		line += ", " + i.Note
+	}
+	return line
+}
+
+// NormalizeIngredients prepares ingredients sent as a whole list: every ingredient
+// gets a fresh id and its text fields are trimmed
+func NormalizeIngredients(ingredients Ingredients) {
+	for k := range ingredients {
+		ingredients[k] = normalizeIngredient(ingredients[k])
+	}
+}
+
+func normalizeIngredient(ingredient Ingredient) Ingredient {
+	ingredient.ID = primitive.NewObjectID()
+	ingredient.Unit = NormalizeUnit(ingredient.Unit)
+	ingredient.Item = strings.Join(strings.Fields(ingredient.Item), " ")
+	ingredient.Note = strings.TrimSpace(ingredient.Note)
+	return ingredient
+}
+
+// AddIngredient inserts an ingredient at position, or appends it when position is
+// nil or past the end, and returns the stored ingredient
+func AddIngredient(ingredients *Ingredients, ingredient Ingredient, position *int) (*Ingredient, error) {
+	if len(*ingredients) >= MaxIngredients {
+		return nil, ErrIngredientLimit
+	}
+	at := len(*ingredients)
+	if position != nil && *position >= 0 && *position < at {
+		at = *position
+	}
+	*ingredients = append(*ingredients, Ingredient{})
+	copy((*ingredients)[at+1:], (*ingredients)[at:])
+	(*ingredients)[at] = normalizeIngredient(ingredient)
+	return &(*ingredients)[at], nil
+}
+
+// RemoveIngredient deletes the ingredient with the given id
+func RemoveIngredient(ingredients *Ingredients, id primitive.ObjectID) bool {
+	for k, ingredient := range *ingredients {
+		if ingredient.ID == id {
+			*ingredients = append((*ingredients)[:k], (*ingredients)[k+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ReorderIngredients puts the ingredients in the order of ids, which must list
+// every one of them exactly once
+func ReorderIngredients(ingredients Ingredients, ids []primitive.ObjectID) error {
+	if len(ids) != len(ingredients) {
+		return ErrIngredientOrder
+	}
+	byID := map[primitive.ObjectID]Ingredient{}
+	for _, ingredient := range ingredients {
+		byID[ingredient.ID] = ingredient
+	}
+	reordered := make(Ingredients, 0, len(ids))
+	for _, id := range ids {
+		ingredient, ok := byID[id]
+		if !ok {
+			return ErrIngredientOrder
+		}
+		delete(byID, id)
+		reordered = append(reordered, ingredient)
+	}
+	copy(ingredients, reordered)
+	return nil
+}
+
+// ParseIngredients reads free text with one ingredient per line. Semicolons also
+// separate ingredients; bullets and blank lines are skipped.
+func ParseIngredients(text string) Ingredients {
+	ingredients := Ingredients{}
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ';' }) {
+		line = strings.TrimLeft(strings.TrimSpace(line), "-*•· \t")
+		if line == "" {
+			continue
+		}
+		ingredients = append(ingredients, ParseIngredient(line))
+	}
+	return ingredients
+}
+
+var (
+	parenthetical = regexp.MustCompile(`\s*\(([^)]*)\)`)
+	// attachedUnit splits amounts written without a space, such as 200g or 1½cups
+	attachedUnit = regexp.MustCompile(`^([0-9./½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅛⅜⅝⅞]+(?:[-–][0-9./½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅛⅜⅝⅞]+)?)([a-zA-Z]+\.?)$`)
+	// amountRange splits a range written as one word, such as 2-3 or 1½–2
+	amountRange = regexp.MustCompile(`^([0-9./½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅛⅜⅝⅞]+)[-–]([0-9./½⅓⅔¼¾⅕⅖⅗⅘⅙⅚⅛⅜⅝⅞]+)$`)
+)
+
+// ParseIngredient reads a line such as "2 cups flour, sifted" or "1 ½ tbsp olive oil
+// (extra virgin)". The amount may be a whole number, a decimal, a fraction or a
+// mixed number; the unit is recognized by its common names and abbreviations. A
+// range such as "2-3 cloves" or "1 to 2 cups" is read as its lower amount, with the
+// upper one kept in the note. Whatever follows the first comma, and any text in
+// parentheses, becomes the note. Lines without an amount are kept whole as the item.
+func ParseIngredient(line string) Ingredient {
+	line = strings.TrimSpace(line)
+	var notes []string
+	for _, match := range parenthetical.FindAllStringSubmatch(line, -1) {
+		if note := strings.TrimSpace(match[1]); note != "" {
+			notes = append(notes, note)
+		}
+	}
+	line = parenthetical.ReplaceAllString(line, "")
+	if before, after, found := strings.Cut(line, ","); found {
+		line = before
+		if note := strings.TrimSpace(after); note != "" {
+			notes = append(notes, note)
+		}
+	}
+
+	words := strings.Fields(line)
+	if len(words) > 0 {
+		if m := attachedUnit.FindStringSubmatch(words[0]); m != nil && unitAliases[unitKey(m[2])] != "" {
+			words = append([]string{m[1], m[2]}, words[1:]...)
+		}
+		if m := amountRange.FindStringSubmatch(words[0]); m != nil {
+			words = append([]string{m[1], "-", m[2]}, words[1:]...)
+		}
+	}
+
+	ingredient := Ingredient{ID: primitive.NewObjectID(), Note: strings.Join(notes, ", ")}
+	quantity, used := parseQuantity(words)
+	if used > 0 {
+		if upper, n := parseUpperAmount(words[used:], quantity); n > 0 {
+			notes = append([]string{"up to " + strconv.FormatFloat(upper, 'f', -1, 64)}, notes...)
+			used += n
+		}
+		ingredient.Quantity = &quantity
+		words = words[used:]
+		if unit, n := parseUnit(words); n > 0 && n < len(words) {
+			ingredient.Unit = unit
+			words = words[n:]
+			if len(words) > 1 && strings.EqualFold(words[0], "of") {
+				words = words[1:]
+			}
+		}
+	}
+	ingredient.Item = strings.Join(words, " ")
+	if ingredient.Item != "" {
+		ingredient.Note = strings.Join(notes, ", ")
+	} else {
+		// Nothing but an amount; keep the line rather than lose it
+		ingredient.Quantity, ingredient.Unit = nil, ""
+		ingredient.Item = strings.TrimSpace(line)
+	}
+	return ingredient
+}
+
+// parseQuantity reads the amount at the start of words, such as 2, 1.5, 3/4,
+// 1 1/2, ½ or 1½, and reports how many words it took
+func parseQuantity(words []string) (float64, int) {
+	if len(words) == 0 {
+		return 0, 0
+	}
+	first, ok := parseNumber(words[0])
+	if !ok || first <= 0 {
+		return 0, 0
+	}
+	// A whole number followed by a fraction is a mixed number
+	if first == float64(int(first)) && len(words) > 1 && isFraction(words[1]) {
+		if fraction, ok := parseNumber(words[1]); ok && fraction < 1 {
+			return first + fraction, 2
+		}
+	}
+	return first, 1
+}
+
+// parseUpperAmount reads the upper end of a range following the amount lower, such
+// as "- 3" or "to 3", and reports how many words it took
+func parseUpperAmount(words []string, lower float64) (float64, int) {
+	if len(words) < 2 {
+		return 0, 0
+	}
+	switch strings.ToLower(words[0]) {
+	case "-", "–", "to", "or":
+	default:
+		return 0, 0
+	}
+	upper, used := parseQuantity(words[1:])
+	if used == 0 || upper <= lower {
+		return 0, 0
+	}
+	return upper, used + 1
+}
+
+var vulgarFractions = map[rune]float64{
+	'½': 1.0 / 2, '⅓': 1.0 / 3, '⅔': 2.0 / 3, '¼': 1.0 / 4, '¾': 3.0 / 4,
+	'⅕': 1.0 / 5, '⅖': 2.0 / 5, '⅗': 3.0 / 5, '⅘': 4.0 / 5, '⅙': 1.0 / 6,
+	'⅚': 5.0 / 6, '⅛': 1.0 / 8, '⅜': 3.0 / 8, '⅝': 5.0 / 8, '⅞': 7.0 / 8,
+}
+
+func parseNumber(word string) (float64, bool) {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return 0, false
+	}
+	// 1½ and ½
+	if fraction, ok := vulgarFractions[runes[len(runes)-1]]; ok {
+		if len(runes) == 1 {
+			return fraction, true
+		}
+		whole, err := strconv.Atoi(string(runes[:len(runes)-1]))
+		if err != nil {
+			return 0, false
+		}
+		return float64(whole) + fraction, true
+	}
+	if numerator, denominator, found := strings.Cut(word, "/"); found {
+		n, err1 := strconv.Atoi(numerator)
+		d, err2 := strconv.Atoi(denominator)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return float64(n) / float64(d), true
+	}
+	if !unicode.IsDigit(runes[0]) && runes[0] != '.' {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(word, 64)
+	return value, err == nil
+}
+
+func isFraction(word string) bool {
+	if strings.Contains(word, "/") {
+		return true
+	}
+	runes := []rune(word)
+	_, ok := vulgarFractions[runes[0]]
+	return len(runes) == 1 && ok
+}
+
+// Canonical units of measure
+const (
+	UnitGram       = "g"
+	UnitKilogram   = "kg"
+	UnitMilligram  = "mg"
+	UnitOunce      = "oz"
+	UnitPound      = "lb"
+	UnitMilliliter = "ml"
+	UnitLiter      = "l"
+	UnitTeaspoon   = "tsp"
+	UnitTablespoon = "tbsp"
+	UnitCup        = "cup"
+	UnitFluidOunce = "fl oz"
+	UnitPint       = "pint"
+	UnitQuart      = "quart"
+	UnitGallon     = "gallon"
+)
+
+// unitAliases maps the spellings of a unit to its canonical name. Besides units of
+// weight and volume it knows the usual kitchen counts.
+var unitAliases = map[string]string{}
+
+func init() {
+	for unit, aliases := range map[string][]string{
+		UnitGram:       {"g", "gr", "gm", "gram", "grams", "gramme", "grammes"},
+		UnitKilogram:   {"kg", "kgs", "kilo", "kilos", "kilogram", "kilograms"},
+		UnitMilligram:  {"mg", "milligram", "milligrams"},
+		UnitOunce:      {"oz", "ounce", "ounces"},
+		UnitPound:      {"lb", "lbs", "pound", "pounds"},
+		UnitMilliliter: {"ml", "milliliter", "milliliters", "millilitre", "millilitres"},
+		UnitLiter:      {"l", "liter", "liters", "litre", "litres"},
+		UnitTeaspoon:   {"tsp", "tsps", "teaspoon", "teaspoons"},
+		UnitTablespoon: {"tbsp", "tbsps", "tbs", "tbl", "tablespoon", "tablespoons"},
+		UnitCup:        {"c", "cup", "cups"},
+		UnitFluidOunce: {"fl oz", "floz", "fluid ounce", "fluid ounces"},
+		UnitPint:       {"pt", "pint", "pints"},
+		UnitQuart:      {"qt", "quart", "quarts"},
+		UnitGallon:     {"gal", "gallon", "gallons"},
+		"pinch":        {"pinch", "pinches"},
+		"dash":         {"dash", "dashes"},
+		"clove":        {"clove", "cloves"},
+		"can":          {"can", "cans", "tin", "tins"},
+		"slice":        {"slice", "slices"},
+		"piece":        {"piece", "pieces", "pc", "pcs"},
+		"stick":        {"stick", "sticks"},
+		"bunch":        {"bunch", "bunches"},
+		"handful":      {"handful", "handfuls"},
+		"package":      {"package", "packages", "pkg", "pack", "packs"},
+	} {
+		for _, alias := range aliases {
+			unitAliases[alias] = unit
+		}
+	}
+}
+
+// NormalizeUnit returns the canonical name of a unit, or the unit as given when it
+// is not a known one
+func NormalizeUnit(unit string) string {
+	if canonical, ok := unitAliases[unitKey(unit)]; ok {
+		return canonical
+	}
+	return strings.TrimSpace(unit)
+}
+
+// parseUnit recognizes a unit of one or two words at the start of words
+func parseUnit(words []string) (string, int) {
+	if len(words) > 1 {
+		if unit, ok := unitAliases[unitKey(words[0]+" "+words[1])]; ok {
+			return unit, 2
+		}
+	}
+	if len(words) > 0 {
+		if unit, ok := unitAliases[unitKey(words[0])]; ok {
+			return unit, 1
+		}
+	}
+	return "", 0
+}
+
+func unitKey(unit string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(unit, ".", ""))), " ")
+}
@@ -0,0 +1,49 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+)
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		line     string
+		quantity float64
+		unit     string
+		item     string
+		note     string
+	}{
+		{line: "2 cups flour, sifted", quantity: 2, unit: "cup", item: "flour", note: "sifted"},
+		{line: "1 1/2 tbsp olive oil", quantity: 1.5, unit: "tbsp", item: "olive oil"},
+		{line: "1 ½ tsp salt", quantity: 1.5, unit: "tsp", item: "salt"},
+		{line: "1½ cups of milk", quantity: 1.5, unit: "cup", item: "milk"},
+		{line: "¾ cup sugar", quantity: 0.75, unit: "cup", item: "sugar"},
+		{line: "3/4 lb ground beef", quantity: 0.75, unit: "lb", item: "ground beef"},
+		{line: "0.5 l stock", quantity: 0.5, unit: "l", item: "stock"},
+		{line: "200g flour", quantity: 200, unit: "g", item: "flour"},
+		{line: "1½cups rice", quantity: 1.5, unit: "cup", item: "rice"},
+		{line: "1 can (400 g) chickpeas (drained), rinsed", quantity: 1, unit: "can", item: "chickpeas", note: "400 g, drained, rinsed"},
+		{line: "2-3 cloves garlic", quantity: 2, unit: "clove", item: "garlic", note: "up to 3"},
+		{line: "1 - 2 tbsp honey", quantity: 1, unit: "tbsp", item: "honey", note: "up to 2"},
+		{line: "1½–2 cups water", quantity: 1.5, unit: "cup", item: "water", note: "up to 2"},
+		{line: "1 to 2 lemons, juiced", quantity: 1, item: "lemons", note: "up to 2, juiced"},
+		{line: "200-250g flour", quantity: 200, unit: "g", item: "flour", note: "up to 250"},
+		{line: "3 eggs", quantity: 3, item: "eggs"},
+		{line: "salt to taste", item: "salt to taste"},
+		{line: "2 cups", quantity: 2, item: "cups"},
+		{line: "2-3", item: "2-3"},
+	}
+
+	for _, test := range tests {
+		got := models.ParseIngredient(test.line)
+		quantity := 0.0
+		if got.Quantity != nil {
+			quantity = *got.Quantity
+		}
+		if !near(quantity, test.quantity) || got.Unit != test.unit || got.Item != test.item || got.Note != test.note {
+			t.Errorf("%q parsed to %v %q %q (%q), want %v %q %q (%q)",
+				test.line, quantity, got.Unit, got.Item, got.Note, test.quantity, test.unit, test.item, test.note)
+		}
+	}
+}
@@ -7,18 +7,60 @@ import (
 )
 
 type ToDoList struct {
-	ID      primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Task    string             `json:"task,omitempty"`
-	Status  bool               `json:"status,omitempty"`
-	User_id string             `json:"user_id"`
+	ID           primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	Task         string              `json:"task,omitempty" validate:"required,max=500"`
+	Status       TodoStatus          `json:"status"`
+	Priority     Priority            `json:"priority"`
+	Due_at       *time.Time          `json:"due_at,omitempty" bson:"due_at,omitempty" validate:"required_with=Reminders"`
+	Reminders    []int               `json:"reminders,omitempty" bson:"reminders,omitempty" validate:"max=10,dive,min=0,max=43200"`
+	Created_at   time.Time           `json:"created_at"`
+	Updated_at   time.Time           `json:"updated_at"`
+	Completed_at *time.Time          `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	Subtasks     []Subtask           `json:"subtasks,omitempty" bson:"subtasks,omitempty" validate:"omitempty,dive"`
+	Progress     *Progress           `json:"progress,omitempty" bson:"-"`
+	Project_id   *primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	Tags         []string            `json:"tags,omitempty" bson:"tags,omitempty" validate:"max=20,dive,max=50"`
+	Recurrence   *Recurrence         `json:"recurrence,omitempty" bson:"recurrence,omitempty"`
+	Next         *ToDoList           `json:"next_occurrence,omitempty" bson:"-"`
+	Revision     int64               `json:"-"`
+	User_id      string              `json:"user_id"`
 }
+
+// TodoPatch lists the todo fields to change; nil fields are left untouched.
+// Reminders are given in minutes before Due_at. The status only changes through
+// TodoRepository.Transition.
+type TodoPatch struct {
+	Task      *string
+	Priority  *Priority
+	Due_at    *time.Time
+	Clear_due bool
+	Reminders *[]int
+	// Project_id files the todo under a project, Clear_project takes it out of its project
+	Project_id    *primitive.ObjectID
+	Clear_project bool
+	Tags          *[]string
+	// Recurrence replaces the repeat rule, Clear_recurrence stops the todo repeating
+	Recurrence       *Recurrence
+	Clear_recurrence bool
+}
+
+// CalorieTracker is a recipe. Nutrition holds what the whole recipe contains;
+// Calories and Fat repeat its calories and fat for clients that predate it.
 type CalorieTracker struct {
-	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Dish        *string            `json:"dish"`
-	Ingredients *string            `json:"ingredients"`
-	Calories    *int64             `json:"calories"`
-	Fat         *int64             `json:"fat"`
-	User_id     string             `json:"user_id"`
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Dish         *string            `json:"dish"`
+	Ingredients  Ingredients        `json:"ingredients" validate:"max=100,dive"`
+	Instructions []string           `json:"instructions,omitempty" bson:"instructions,omitempty" validate:"max=100,dive,max=5000"`
+	Servings     int                `json:"servings" validate:"min=0,max=1000"`
+	Calories     *int64             `json:"calories" validate:"omitempty,min=0,max=100000"`
+	Fat          *int64             `json:"fat" validate:"omitempty,min=0,max=10000"`
+	Nutrition    *Nutrition         `json:"nutrition,omitempty" bson:"nutrition,omitempty"`
+	// Nutrition_basis tells whether nutrition entered by hand is for the whole
+	// recipe (the default) or for one serving
+	Nutrition_basis string     `json:"nutrition_basis,omitempty" bson:"-" validate:"omitempty,oneof=recipe serving"`
+	Per_serving     *Nutrients `json:"per_serving,omitempty" bson:"-"`
+	Revision        int64      `json:"-"`
+	User_id         string     `json:"user_id"`
 }
 
 type User struct {
@@ -30,9 +72,18 @@ type User struct {
 	Phone         *string            `json:"phone" validate:"required"`
 	Token         *string            `json:"token"`
 	Refresh_token *string            `json:"refresh_token"`
-	Created_at    time.Time          `json:"created_at"`
-	Updated_at    time.Time          `json:"updated_at"`
-	User_id       string             `json:"user_id"`
+	Token_version int                `json:"-"`
+	// Token_family is the refresh family of the current login; refresh tokens of
+	// other families are never exchanged
+	Token_family string    `json:"-"`
+	Body_goal    *BodyGoal `json:"body_goal,omitempty" bson:"body_goal,omitempty"`
+	// Nutrition_targets are the daily amounts the food diary is compared against,
+	// overridden for calories and macros by the nutrition goal
+	Nutrition_targets *Nutrients     `json:"nutrition_targets,omitempty" bson:"nutrition_targets,omitempty"`
+	Nutrition_goal    *NutritionGoal `json:"nutrition_goal,omitempty" bson:"nutrition_goal,omitempty"`
+	Created_at        time.Time      `json:"created_at"`
+	Updated_at        time.Time      `json:"updated_at"`
+	User_id           string         `json:"user_id"`
 }
 
 type UserPassword struct {
@@ -40,13 +91,26 @@ type UserPassword struct {
 	NewPassword     string `json:"new_password" validate:"required,min=8"`
 }
 
+// Gym is a named weekly training plan of one user. At most one plan per user is
+// Active; it is the schedule the user is currently following. The days describe the
+// plan in free text, Exercises places catalog exercises on them. A plan following a
+// Program has both generated from the program's current week. Revision counts the
+// saves of the plan, guarding its program against concurrent progressions.
 type Gym struct {
-	ID        primitive.ObjectID `bson:"_id"`
-	Monday    *string            `json:"monday"`
-	Tuesday   *string            `json:"tuesday"`
-	Wednesday *string            `json:"wednesday"`
-	Thursday  *string            `json:"thursday"`
-	Friday    *string            `json:"friday"`
-	Saturday  *string            `json:"saturday"`
-	Sunday    *string            `json:"sunday"`
+	ID         primitive.ObjectID  `json:"_id" bson:"_id"`
+	Name       string              `json:"name" validate:"required,max=100"`
+	Active     bool                `json:"active"`
+	Monday     *string             `json:"monday" validate:"omitempty,max=500"`
+	Tuesday    *string             `json:"tuesday" validate:"omitempty,max=500"`
+	Wednesday  *string             `json:"wednesday" validate:"omitempty,max=500"`
+	Thursday   *string             `json:"thursday" validate:"omitempty,max=500"`
+	Friday     *string             `json:"friday" validate:"omitempty,max=500"`
+	Saturday   *string             `json:"saturday" validate:"omitempty,max=500"`
+	Sunday     *string             `json:"sunday" validate:"omitempty,max=500"`
+	Exercises  []ScheduledExercise `json:"exercises,omitempty" bson:"exercises,omitempty" validate:"max=100,dive"`
+	Program    *Program            `json:"program,omitempty" bson:"program,omitempty"`
+	Created_at time.Time           `json:"created_at"`
+	Updated_at time.Time           `json:"updated_at"`
+	User_id    string              `json:"user_id"`
+	Revision   int64               `json:"-"`
 }
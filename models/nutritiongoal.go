@@ -0,0 +1,193 @@
+package models
+
+import (
+	"errors"
+	"math"
+)
+
+// Sexes the BMR formula distinguishes
+const (
+	SexMale   = "male"
+	SexFemale = "female"
+)
+
+// Plans a nutrition goal can follow
+const (
+	PlanCut      = "cut"
+	PlanMaintain = "maintain"
+	PlanBulk     = "bulk"
+)
+
+// ActivityFactors multiply the BMR into the calories burnt in a day
+var ActivityFactors = map[string]float64{
+	"sedentary":   1.2,
+	"light":       1.375,
+	"moderate":    1.55,
+	"active":      1.725,
+	"very_active": 1.9,
+}
+
+// planAdjustments change the TDEE into the calories to eat on each plan
+var planAdjustments = map[string]float64{
+	PlanCut:      0.8,
+	PlanMaintain: 1,
+	PlanBulk:     1.1,
+}
+
+// minimumCalories is the least a goal of each sex sets as the daily calorie target,
+// whatever a low weight or a steep cut works out to
+var minimumCalories = map[string]float64{
+	SexMale:   1500,
+	SexFemale: 1200,
+}
+
+// DefaultMacros are the macro splits used when a goal gives none
+var DefaultMacros = map[string]MacroSplit{
+	PlanCut:      {Protein: 35, Carbohydrates: 35, Fat: 30},
+	PlanMaintain: {Protein: 30, Carbohydrates: 40, Fat: 30},
+	PlanBulk:     {Protein: 25, Carbohydrates: 50, Fat: 25},
+}
+
+// Calories in a gram of each macronutrient
+const (
+	caloriesPerGramProtein       = 4
+	caloriesPerGramCarbohydrates = 4
+	caloriesPerGramFat           = 9
+)
+
+// AdherenceTolerance is how far off the calorie target, in percent, a day can be
+// and still count as on target
+const AdherenceTolerance = 10
+
+// ErrNoWeight is returned when a goal gives no weight and none was measured
+var ErrNoWeight = errors.New("no weight given or measured")
+
+// MacroSplit divides the calories of a day between the macronutrients, in percent
+type MacroSplit struct {
+	Protein       float64 `json:"protein" validate:"min=0,max=100"`
+	Carbohydrates float64 `json:"carbohydrates" validate:"min=0,max=100"`
+	Fat           float64 `json:"fat" validate:"min=0,max=100"`
+}
+
+// Sum is the total of the split, 100 for a valid one
+func (m MacroSplit) Sum() float64 {
+	return m.Protein + m.Carbohydrates + m.Fat
+}
+
+// NutritionGoal is the profile the daily calorie and macro targets of the user are
+// worked out from, kept on the user. Height is in centimetres and weight in
+// kilograms; without a weight the latest measured one is used.
+type NutritionGoal struct {
+	Age      int         `json:"age" validate:"required,min=13,max=120"`
+	Sex      string      `json:"sex" validate:"required,oneof=male female"`
+	Height   float64     `json:"height" validate:"required,min=100,max=250"`
+	Weight   *float64    `json:"weight,omitempty" bson:"weight,omitempty" validate:"omitempty,min=20,max=500"`
+	Activity string      `json:"activity" validate:"required,oneof=sedentary light moderate active very_active"`
+	Plan     string      `json:"plan" validate:"required,oneof=cut maintain bulk"`
+	Macros   *MacroSplit `json:"macros,omitempty" bson:"macros,omitempty"`
+}
+
+// NutritionPlan is what a nutrition goal works out to for a weight: the BMR, the
+// TDEE and the daily targets
+type NutritionPlan struct {
+	Goal    NutritionGoal `json:"goal"`
+	Weight  float64       `json:"weight"`
+	BMR     float64       `json:"bmr"`
+	TDEE    float64       `json:"tdee"`
+	Macros  MacroSplit    `json:"macros"`
+	Targets Nutrients     `json:"targets"`
+}
+
+// Adherence compares what was eaten on a day with the targets. Percent is the share
+// of every target eaten, zero for nutrients without one.
+type Adherence struct {
+	Percent   Nutrients `json:"percent"`
+	On_target bool      `json:"on_target"`
+}
+
+// Split returns the macro split of the goal, the default of its plan when it gives
+// none
+func (g NutritionGoal) Split() MacroSplit {
+	if g.Macros != nil {
+		return *g.Macros
+	}
+	return DefaultMacros[g.Plan]
+}
+
+// Calculate works out the goal for the measured weight, or the goal's own one when
+// it has one. The BMR follows Mifflin-St Jeor; the calorie target never drops below
+// the minimum for the sex.
+func (g NutritionGoal) Calculate(measured *float64) (NutritionPlan, error) {
+	weight := measured
+	if g.Weight != nil {
+		weight = g.Weight
+	}
+	if weight == nil {
+		return NutritionPlan{}, ErrNoWeight
+	}
+
+	bmr := 10**weight + 6.25*g.Height - 5*float64(g.Age)
+	if g.Sex == SexMale {
+		bmr += 5
+	} else {
+		bmr -= 161
+	}
+	tdee := bmr * ActivityFactors[g.Activity]
+	calories := math.Max(tdee*planAdjustments[g.Plan], minimumCalories[g.Sex])
+	split := g.Split()
+
+	plan := NutritionPlan{
+		Goal:   g,
+		Weight: *weight,
+		BMR:    math.Round(bmr),
+		TDEE:   math.Round(tdee),
+		Macros: split,
+		Targets: Nutrients{
+			Calories:      calories,
+			Protein:       calories * split.Protein / 100 / caloriesPerGramProtein,
+			Carbohydrates: calories * split.Carbohydrates / 100 / caloriesPerGramCarbohydrates,
+			Fat:           calories * split.Fat / 100 / caloriesPerGramFat,
+		}.Round(),
+	}
+	return plan, nil
+}
+
+// WithTargets puts the calorie and macro targets of the plan over other targets,
+// keeping those set for the other nutrients
+func (p NutritionPlan) WithTargets(targets *Nutrients) Nutrients {
+	merged := p.Targets
+	if targets != nil {
+		merged = *targets
+		merged.Calories = p.Targets.Calories
+		merged.Protein = p.Targets.Protein
+		merged.Carbohydrates = p.Targets.Carbohydrates
+		merged.Fat = p.Targets.Fat
+	}
+	return merged
+}
+
+// Remaining is what is left of every target after eating total, negative when a
+// target was exceeded and zero for nutrients without a target
+func Remaining(targets Nutrients, total Nutrients) Nutrients {
+	return targets.combine(total, func(target, eaten float64) float64 {
+		if target == 0 {
+			return 0
+		}
+		return target - eaten
+	}).Round()
+}
+
+// Adhere measures what was eaten against the targets. A day is on target when its
+// calories are within AdherenceTolerance percent of the calorie target.
+func Adhere(targets Nutrients, total Nutrients) Adherence {
+	percent := targets.combine(total, func(target, eaten float64) float64 {
+		if target == 0 {
+			return 0
+		}
+		return math.Round(eaten / target * 100)
+	})
+	return Adherence{
+		Percent:   percent,
+		On_target: targets.Calories > 0 && math.Abs(percent.Calories-100) <= AdherenceTolerance,
+	}
+}
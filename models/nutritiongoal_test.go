@@ -0,0 +1,103 @@
+package models_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+)
+
+func TestNutritionGoalCalculate(t *testing.T) {
+	weight := func(kg float64) *float64 { return &kg }
+	tests := []struct {
+		name     string
+		goal     models.NutritionGoal
+		measured *float64
+		bmr      float64
+		tdee     float64
+		targets  models.Nutrients
+	}{
+		{
+			name: "man maintaining on moderate activity",
+			goal: models.NutritionGoal{Age: 30, Sex: models.SexMale, Height: 180, Weight: weight(80), Activity: "moderate", Plan: models.PlanMaintain},
+			bmr:  1780, tdee: 2759,
+			targets: models.Nutrients{Calories: 2759, Protein: 206.9, Carbohydrates: 275.9, Fat: 92},
+		},
+		{
+			name: "woman cutting while sedentary",
+			goal: models.NutritionGoal{Age: 30, Sex: models.SexFemale, Height: 165, Weight: weight(60), Activity: "sedentary", Plan: models.PlanCut},
+			bmr:  1320, tdee: 1584,
+			targets: models.Nutrients{Calories: 1267, Protein: 110.9, Carbohydrates: 110.9, Fat: 42.2},
+		},
+		{
+			name:     "bulking on the measured weight",
+			goal:     models.NutritionGoal{Age: 25, Sex: models.SexMale, Height: 175, Activity: "very_active", Plan: models.PlanBulk},
+			measured: weight(70),
+			bmr:      1674, tdee: 3180,
+			targets: models.Nutrients{Calories: 3498, Protein: 218.6, Carbohydrates: 437.3, Fat: 97.2},
+		},
+		{
+			name:     "the weight of the goal wins over the measured one",
+			goal:     models.NutritionGoal{Age: 40, Sex: models.SexFemale, Height: 160, Weight: weight(55), Activity: "light", Plan: models.PlanMaintain},
+			measured: weight(90),
+			bmr:      1189, tdee: 1635,
+			targets: models.Nutrients{Calories: 1635, Protein: 122.6, Carbohydrates: 163.5, Fat: 54.5},
+		},
+		{
+			name: "own macro split",
+			goal: models.NutritionGoal{Age: 30, Sex: models.SexMale, Height: 180, Weight: weight(80), Activity: "active", Plan: models.PlanMaintain,
+				Macros: &models.MacroSplit{Protein: 40, Carbohydrates: 30, Fat: 30}},
+			bmr: 1780, tdee: 3071,
+			targets: models.Nutrients{Calories: 3071, Protein: 307.1, Carbohydrates: 230.3, Fat: 102.4},
+		},
+		{
+			name: "a woman's calories never drop below 1200",
+			goal: models.NutritionGoal{Age: 70, Sex: models.SexFemale, Height: 150, Weight: weight(40), Activity: "sedentary", Plan: models.PlanCut},
+			bmr:  827, tdee: 992,
+			targets: models.Nutrients{Calories: 1200, Protein: 105, Carbohydrates: 105, Fat: 40},
+		},
+		{
+			name: "a man's calories never drop below 1500",
+			goal: models.NutritionGoal{Age: 80, Sex: models.SexMale, Height: 150, Weight: weight(45), Activity: "sedentary", Plan: models.PlanCut},
+			bmr:  993, tdee: 1191,
+			targets: models.Nutrients{Calories: 1500, Protein: 131.3, Carbohydrates: 131.3, Fat: 50},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, err := test.goal.Calculate(test.measured)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.BMR != test.bmr || plan.TDEE != test.tdee {
+				t.Errorf("BMR %v and TDEE %v, want %v and %v", plan.BMR, plan.TDEE, test.bmr, test.tdee)
+			}
+			if plan.Targets != test.targets {
+				t.Errorf("targets = %+v, want %+v", plan.Targets, test.targets)
+			}
+		})
+	}
+
+	goal := models.NutritionGoal{Age: 30, Sex: models.SexMale, Height: 180, Activity: "moderate", Plan: models.PlanMaintain}
+	if _, err := goal.Calculate(nil); !errors.Is(err, models.ErrNoWeight) {
+		t.Errorf("a goal without any weight calculated with %v, want ErrNoWeight", err)
+	}
+}
+
+func TestNutritionPlanWithTargets(t *testing.T) {
+	plan := models.NutritionPlan{Targets: models.Nutrients{Calories: 2000, Protein: 150, Carbohydrates: 200, Fat: 67}}
+
+	if got := plan.WithTargets(nil); got != plan.Targets {
+		t.Errorf("without other targets got %+v, want the plan's %+v", got, plan.Targets)
+	}
+
+	own := models.Nutrients{Calories: 1000, Protein: 10, Fiber: 30, Sodium: 2300}
+	want := models.Nutrients{Calories: 2000, Protein: 150, Carbohydrates: 200, Fat: 67, Fiber: 30, Sodium: 2300}
+	if got := plan.WithTargets(&own); got != want {
+		t.Errorf("merged targets = %+v, want %+v", got, want)
+	}
+	if own.Calories != 1000 {
+		t.Errorf("merging changed the targets set by hand to %+v", own)
+	}
+}
@@ -0,0 +1,387 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoadStep is the smallest weight change a barbell allows, in kilograms
+const LoadStep = 2.5
+
+// Outcomes of a lift in a logged program session
+const (
+	LiftProgressed = "progressed"
+	LiftHeld       = "held"
+	LiftFailed     = "failed"
+	LiftDeloaded   = "deloaded"
+)
+
+// ProgramTemplate describes a training program. Sessions rotate over the training
+// Days, so a two session program on three days alternates A/B/A then B/A/B. Weeks
+// optionally cycle the intensity of every session week by week.
+type ProgramTemplate struct {
+	Key         string           `json:"key"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Days        []string         `json:"days"`
+	Sessions    []ProgramSession `json:"sessions"`
+	Weeks       []ProgramWeek    `json:"weeks,omitempty"`
+}
+
+// ProgramSession is one workout of a template
+type ProgramSession struct {
+	Name      string        `json:"name"`
+	Exercises []ProgramSlot `json:"exercises"`
+}
+
+// ProgramSlot prescribes an exercise of the catalog. After a session in which every
+// set was completed the load goes up by Increment; after Deload_after failed
+// sessions in a row it drops by Deload_percent.
+type ProgramSlot struct {
+	Exercise_id    primitive.ObjectID `json:"exercise_id"`
+	Exercise       string             `json:"exercise"`
+	Sets           int                `json:"sets"`
+	Reps           int                `json:"reps"`
+	Increment      float64            `json:"increment"`
+	Deload_after   int                `json:"deload_after"`
+	Deload_percent int                `json:"deload_percent"`
+}
+
+// ProgramWeek adjusts the prescriptions of one week of the cycle. Loads are
+// Percent of the working weight; weeks below 100 percent are recovery weeks in
+// which loads neither progress nor count as failed.
+type ProgramWeek struct {
+	Name       string `json:"name"`
+	Percent    int    `json:"percent"`
+	Sets_delta int    `json:"sets_delta,omitempty"`
+	Reps_delta int    `json:"reps_delta,omitempty"`
+}
+
+// Program is the state of a template a gym plan follows
+type Program struct {
+	Template      string        `json:"template"`
+	Started_at    time.Time     `json:"started_at"`
+	Sessions_done int           `json:"sessions_done"`
+	Lifts         []ProgramLift `json:"lifts"`
+}
+
+// ProgramLift is the current working weight of an exercise of the program
+type ProgramLift struct {
+	Exercise_id    primitive.ObjectID `json:"exercise_id"`
+	Exercise       string             `json:"exercise"`
+	Weight         float64            `json:"weight"`
+	Increment      float64            `json:"increment"`
+	Deload_after   int                `json:"deload_after"`
+	Deload_percent int                `json:"deload_percent"`
+	Failures       int                `json:"failures"`
+}
+
+// PrescribedSession is a session of a program with concrete loads
+type PrescribedSession struct {
+	Number    int            `json:"number"`
+	Week      int            `json:"week"`
+	Week_name string         `json:"week_name,omitempty"`
+	Day       string         `json:"day"`
+	Name      string         `json:"name"`
+	Exercises []Prescription `json:"exercises"`
+}
+
+// Prescription is what to lift for one exercise of a session
+type Prescription struct {
+	Exercise_id primitive.ObjectID `json:"exercise_id"`
+	Exercise    string             `json:"exercise"`
+	Sets        int                `json:"sets"`
+	Reps        int                `json:"reps"`
+	Weight      float64            `json:"weight"`
+}
+
+// LiftAdjustment reports how a logged session changed the weight of a lift
+type LiftAdjustment struct {
+	Exercise_id primitive.ObjectID `json:"exercise_id"`
+	Exercise    string             `json:"exercise"`
+	Outcome     string             `json:"outcome"`
+	From        float64            `json:"from"`
+	To          float64            `json:"to"`
+}
+
+// RoundLoad rounds a weight to the nearest LoadStep
+func RoundLoad(weight float64) float64 {
+	return math.Round(weight/LoadStep) * LoadStep
+}
+
+// ProgramTemplates returns the built-in program templates
+func ProgramTemplates() []ProgramTemplate {
+	return programTemplates
+}
+
+// FindProgramTemplate returns the built-in template with the given key
+func FindProgramTemplate(key string) (*ProgramTemplate, bool) {
+	for i := range programTemplates {
+		if programTemplates[i].Key == key {
+			return &programTemplates[i], true
+		}
+	}
+	return nil, false
+}
+
+// Slots returns the distinct exercises of the template, each with its first slot
+func (t *ProgramTemplate) Slots() []ProgramSlot {
+	slots := []ProgramSlot{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, session := range t.Sessions {
+		for _, slot := range session.Exercises {
+			if !seen[slot.Exercise_id] {
+				seen[slot.Exercise_id] = true
+				slots = append(slots, slot)
+			}
+		}
+	}
+	return slots
+}
+
+// Start begins the template with the given working weight for every exercise
+func (t *ProgramTemplate) Start(weights map[primitive.ObjectID]float64, now time.Time) *Program {
+	program := &Program{Template: t.Key, Started_at: now, Lifts: []ProgramLift{}}
+	for _, slot := range t.Slots() {
+		program.Lifts = append(program.Lifts, ProgramLift{
+			Exercise_id:    slot.Exercise_id,
+			Exercise:       slot.Exercise,
+			Weight:         RoundLoad(weights[slot.Exercise_id]),
+			Increment:      slot.Increment,
+			Deload_after:   slot.Deload_after,
+			Deload_percent: slot.Deload_percent,
+		})
+	}
+	return program
+}
+
+func (p *Program) lift(id primitive.ObjectID) *ProgramLift {
+	for i := range p.Lifts {
+		if p.Lifts[i].Exercise_id == id {
+			return &p.Lifts[i]
+		}
+	}
+	return nil
+}
+
+// week returns the cycle week session n falls into, counting from 1, and its rule
+func (t *ProgramTemplate) week(n int) (int, ProgramWeek) {
+	week := n / len(t.Days)
+	if len(t.Weeks) == 0 {
+		return week + 1, ProgramWeek{Percent: 100}
+	}
+	return week + 1, t.Weeks[week%len(t.Weeks)]
+}
+
+// Session prescribes session n of the program, counting from 0, at the current
+// working weights
+func (p *Program) Session(t *ProgramTemplate, n int) PrescribedSession {
+	week, rule := t.week(n)
+	session := t.Sessions[n%len(t.Sessions)]
+	prescribed := PrescribedSession{
+		Number:    n + 1,
+		Week:      week,
+		Week_name: rule.Name,
+		Day:       t.Days[n%len(t.Days)],
+		Name:      session.Name,
+		Exercises: []Prescription{},
+	}
+	for _, slot := range session.Exercises {
+		weight := 0.0
+		if lift := p.lift(slot.Exercise_id); lift != nil {
+			weight = RoundLoad(lift.Weight * float64(rule.Percent) / 100)
+		}
+		prescribed.Exercises = append(prescribed.Exercises, Prescription{
+			Exercise_id: slot.Exercise_id,
+			Exercise:    slot.Exercise,
+			Sets:        max(slot.Sets+rule.Sets_delta, 1),
+			Reps:        max(slot.Reps+rule.Reps_delta, 1),
+			Weight:      weight,
+		})
+	}
+	return prescribed
+}
+
+// Next prescribes the session the user is due to perform
+func (p *Program) Next(t *ProgramTemplate) PrescribedSession {
+	return p.Session(t, p.Sessions_done)
+}
+
+// Schedule writes the sessions of the current week into the days and exercises
+// of the plan
+func (p *Program) Schedule(t *ProgramTemplate, plan *Gym) {
+	days := map[string]**string{
+		"monday": &plan.Monday, "tuesday": &plan.Tuesday, "wednesday": &plan.Wednesday,
+		"thursday": &plan.Thursday, "friday": &plan.Friday, "saturday": &plan.Saturday, "sunday": &plan.Sunday,
+	}
+	for _, day := range days {
+		*day = nil
+	}
+	plan.Exercises = []ScheduledExercise{}
+
+	first := p.Sessions_done / len(t.Days) * len(t.Days)
+	for n := first; n < first+len(t.Days); n++ {
+		session := p.Session(t, n)
+		lines := []string{}
+		for _, e := range session.Exercises {
+			plan.Exercises = append(plan.Exercises, ScheduledExercise{
+				Day:         session.Day,
+				Exercise_id: e.Exercise_id,
+				Sets:        e.Sets,
+				Reps:        e.Reps,
+				Weight:      e.Weight,
+			})
+			lines = append(lines, fmt.Sprintf("%s %dx%d @ %gkg", e.Exercise, e.Sets, e.Reps, e.Weight))
+		}
+		summary := session.Name + ": " + strings.Join(lines, ", ")
+		*days[session.Day] = &summary
+	}
+}
+
+// Record evaluates a logged workout as the next session of the program. A lift
+// progresses when every prescribed set was done for the prescribed reps at the
+// prescribed weight or more. Exercises of the session missing from the workout are
+// left alone; a workout without any of them does not count as the session.
+func (p *Program) Record(t *ProgramTemplate, workout *Workout) []LiftAdjustment {
+	session := p.Next(t)
+	_, rule := t.week(p.Sessions_done)
+
+	adjustments := []LiftAdjustment{}
+	for _, prescription := range session.Exercises {
+		lift := p.lift(prescription.Exercise_id)
+		if lift == nil {
+			continue
+		}
+		completed, attempted := 0, false
+		for _, e := range workout.Exercises {
+			if e.Exercise_id == nil || *e.Exercise_id != prescription.Exercise_id {
+				continue
+			}
+			attempted = true
+			for _, set := range e.Sets {
+				if !set.Warmup && set.Reps >= prescription.Reps && set.Weight >= prescription.Weight {
+					completed++
+				}
+			}
+		}
+		if !attempted {
+			continue
+		}
+
+		adjustment := LiftAdjustment{Exercise_id: lift.Exercise_id, Exercise: lift.Exercise, From: lift.Weight}
+		switch {
+		case rule.Percent < 100:
+			adjustment.Outcome = LiftHeld
+		case completed >= prescription.Sets:
+			lift.Weight += lift.Increment
+			lift.Failures = 0
+			adjustment.Outcome = LiftProgressed
+		default:
+			lift.Failures++
+			adjustment.Outcome = LiftFailed
+			if lift.Deload_after > 0 && lift.Failures >= lift.Deload_after {
+				lift.Weight = math.Floor(lift.Weight*float64(100-lift.Deload_percent)/100/LoadStep) * LoadStep
+				lift.Failures = 0
+				adjustment.Outcome = LiftDeloaded
+			}
+		}
+		adjustment.To = lift.Weight
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if len(adjustments) > 0 {
+		p.Sessions_done++
+	}
+	return adjustments
+}
+
+// slot is shorthand for a catalog exercise in a built-in template
+func slot(exercise string, sets int, reps int, increment float64) ProgramSlot {
+	return ProgramSlot{
+		Exercise_id:    CatalogID(exercise),
+		Exercise:       exercise,
+		Sets:           sets,
+		Reps:           reps,
+		Increment:      increment,
+		Deload_after:   3,
+		Deload_percent: 10,
+	}
+}
+
+var programTemplates = []ProgramTemplate{
+	{
+		Key:         "5x5",
+		Name:        "5x5",
+		Description: "Three full body sessions a week alternating two workouts of five sets of five, adding weight every successful session.",
+		Days:        []string{"monday", "wednesday", "friday"},
+		Sessions: []ProgramSession{
+			{Name: "Workout A", Exercises: []ProgramSlot{
+				slot("Squat", 5, 5, 2.5),
+				slot("Bench Press", 5, 5, 2.5),
+				slot("Barbell Row", 5, 5, 2.5),
+			}},
+			{Name: "Workout B", Exercises: []ProgramSlot{
+				slot("Squat", 5, 5, 2.5),
+				slot("Overhead Press", 5, 5, 2.5),
+				slot("Deadlift", 1, 5, 5),
+			}},
+		},
+	},
+	{
+		Key:         "linear-progression",
+		Name:        "Linear progression",
+		Description: "A beginner program of three sets of five on the main lifts, three days a week, adding weight every session until it stalls.",
+		Days:        []string{"monday", "wednesday", "friday"},
+		Sessions: []ProgramSession{
+			{Name: "Workout A", Exercises: []ProgramSlot{
+				slot("Squat", 3, 5, 2.5),
+				slot("Bench Press", 3, 5, 2.5),
+				slot("Deadlift", 1, 5, 5),
+			}},
+			{Name: "Workout B", Exercises: []ProgramSlot{
+				slot("Squat", 3, 5, 2.5),
+				slot("Overhead Press", 3, 5, 2.5),
+				slot("Power Clean", 5, 3, 2.5),
+			}},
+		},
+	},
+	{
+		Key:         "push-pull-legs",
+		Name:        "Push/pull/legs",
+		Description: "Six sessions a week split by movement, on a four week cycle of building intensity followed by a recovery week.",
+		Days:        []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday"},
+		Sessions: []ProgramSession{
+			{Name: "Push", Exercises: []ProgramSlot{
+				slot("Bench Press", 4, 8, 2.5),
+				slot("Overhead Press", 3, 8, 2.5),
+				slot("Incline Dumbbell Press", 3, 10, 2.5),
+				slot("Lateral Raise", 3, 12, 2.5),
+				slot("Triceps Pushdown", 3, 12, 2.5),
+			}},
+			{Name: "Pull", Exercises: []ProgramSlot{
+				slot("Barbell Row", 4, 8, 2.5),
+				slot("Lat Pulldown", 3, 10, 2.5),
+				slot("Seated Cable Row", 3, 10, 2.5),
+				slot("Face Pull", 3, 15, 2.5),
+				slot("Barbell Curl", 3, 10, 2.5),
+			}},
+			{Name: "Legs", Exercises: []ProgramSlot{
+				slot("Squat", 4, 8, 2.5),
+				slot("Romanian Deadlift", 3, 10, 2.5),
+				slot("Leg Press", 3, 12, 5),
+				slot("Leg Curl", 3, 12, 2.5),
+				slot("Standing Calf Raise", 4, 12, 2.5),
+			}},
+		},
+		Weeks: []ProgramWeek{
+			{Name: "Volume", Percent: 100},
+			{Name: "Build", Percent: 105, Reps_delta: -2},
+			{Name: "Intensity", Percent: 110, Reps_delta: -4},
+			{Name: "Recovery", Percent: 80, Sets_delta: -1},
+		},
+	},
+}
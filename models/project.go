@@ -0,0 +1,54 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Project is a user-owned list todos can be filed under. Projects are shown in
+// ascending Order.
+type Project struct {
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name       string             `json:"name" validate:"required,max=100"`
+	Color      string             `json:"color,omitempty" bson:"color,omitempty" validate:"omitempty,hexcolor"`
+	Order      int                `json:"order"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	User_id    string             `json:"user_id"`
+}
+
+// ProjectPatch lists the project fields to change; nil fields are left untouched
+type ProjectPatch struct {
+	Name  *string
+	Color *string
+}
+
+// TagCount is a tag together with the number of todos carrying it
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int    `json:"count"`
+}
+
+// NormalizeTags trims and lowercases tags and drops empty and duplicate ones, so
+// "Work" and " work" end up as the same tag
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
+// NormalizeTag brings a single tag into the form it is stored in
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
@@ -0,0 +1,86 @@
+package models
+
+import "math"
+
+// Bases nutrition can be entered on
+const (
+	BasisRecipe  = "recipe"
+	BasisServing = "serving"
+)
+
+// ServingCount is the number of servings of the recipe, one when it was never set
+func (r *CalorieTracker) ServingCount() int {
+	if r.Servings < 1 {
+		return 1
+	}
+	return r.Servings
+}
+
+// EnteredNutrition returns the nutrition of the recipe that was entered by hand,
+// for the whole recipe: its nutrition unless that was calculated, multiplied by
+// the servings when given per serving, or else the calories and fat of recipes
+// saved before anything else was tracked. It returns nil when there is none.
+func (r *CalorieTracker) EnteredNutrition() *Nutrients {
+	if r.Nutrition != nil {
+		if r.Nutrition.Source == NutritionCalculated {
+			return nil
+		}
+		entered := r.Nutrition.Nutrients
+		if r.Nutrition_basis == BasisServing {
+			entered = entered.Scale(float64(r.ServingCount()))
+		}
+		return &entered
+	}
+	if r.Calories == nil && r.Fat == nil {
+		return nil
+	}
+	entered := Nutrients{}
+	if r.Calories != nil {
+		entered.Calories = float64(*r.Calories)
+	}
+	if r.Fat != nil {
+		entered.Fat = float64(*r.Fat)
+	}
+	return &entered
+}
+
+// ApplyNutrition settles the nutrition of the recipe. The calculation wins as soon
+// as one ingredient could be counted; until then values entered by hand are kept.
+// Calories and fat follow whichever is used.
+func (r *CalorieTracker) ApplyNutrition(calculated *Nutrition, entered *Nutrients) {
+	switch {
+	case calculated.Counted(r.Ingredients):
+		r.Nutrition = calculated
+	case entered != nil:
+		r.Nutrition = &Nutrition{Nutrients: entered.Round(), Source: NutritionManual}
+		if calculated != nil {
+			r.Nutrition.Unmatched = calculated.Unmatched
+		}
+	default:
+		r.Nutrition = calculated
+	}
+	r.Nutrition_basis = ""
+
+	r.Calories, r.Fat = nil, nil
+	if r.Nutrition != nil {
+		calories := int64(math.Round(r.Nutrition.Calories))
+		fat := int64(math.Round(r.Nutrition.Fat))
+		r.Calories, r.Fat = &calories, &fat
+	}
+}
+
+// Derive fills in what is computed on read: the servings and nutrition of recipes
+// saved before they were tracked, and the nutrition of one serving
+func (r *CalorieTracker) Derive() {
+	r.Servings = r.ServingCount()
+	if r.Nutrition == nil {
+		if entered := r.EnteredNutrition(); entered != nil {
+			r.Nutrition = &Nutrition{Nutrients: *entered, Source: NutritionManual}
+		}
+	}
+	r.Per_serving = nil
+	if r.Nutrition != nil {
+		perServing := r.Nutrition.Scale(1 / float64(r.Servings)).Round()
+		r.Per_serving = &perServing
+	}
+}
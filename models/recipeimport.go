@@ -0,0 +1,377 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoRecipe is returned when a page or document holds no schema.org Recipe
+var ErrNoRecipe = errors.New("no schema.org Recipe found")
+
+// RecipeImport is a recipe read from a schema.org Recipe. Unmapped lists the
+// properties of the Recipe that have no place in a recipe, or whose value could
+// not be understood; ingredient lines that could not be read, or were left out
+// past MaxIngredients, are listed by their position, such as recipeIngredient[3].
+// Steps cut short or left out past the limits of a recipe are listed by their
+// position among the steps, such as recipeInstructions[0].
+type RecipeImport struct {
+	Recipe   CalorieTracker `json:"recipe"`
+	Unmapped []string       `json:"unmapped"`
+}
+
+// Properties of a Recipe that are read, besides the JSON-LD keywords
+var importedProperties = map[string]bool{
+	"name": true, "recipeIngredient": true, "ingredients": true, "recipeInstructions": true,
+	"recipeYield": true, "nutrition": true,
+}
+
+// importedNutrients maps the properties of a schema.org NutritionInformation to the
+// nutrients they fill and the unit the nutrient is kept in
+var importedNutrients = map[string]struct {
+	field func(*Nutrients) *float64
+	unit  string
+}{
+	"calories":            {func(n *Nutrients) *float64 { return &n.Calories }, "kcal"},
+	"fatContent":          {func(n *Nutrients) *float64 { return &n.Fat }, UnitGram},
+	"proteinContent":      {func(n *Nutrients) *float64 { return &n.Protein }, UnitGram},
+	"carbohydrateContent": {func(n *Nutrients) *float64 { return &n.Carbohydrates }, UnitGram},
+	"fiberContent":        {func(n *Nutrients) *float64 { return &n.Fiber }, UnitGram},
+	"sugarContent":        {func(n *Nutrients) *float64 { return &n.Sugar }, UnitGram},
+	"sodiumContent":       {func(n *Nutrients) *float64 { return &n.Sodium }, UnitMilligram},
+}
+
+// Limits of the instructions of a recipe, as CalorieTracker validates them
+const (
+	maxSteps      = 100
+	maxStepLength = 5000
+)
+
+// kilojoulesPerCalorie converts energy given in kJ to calories
+const kilojoulesPerCalorie = 4.184
+
+// amountPattern reads the number and unit at the start of an amount such as "9 g"
+var amountPattern = regexp.MustCompile(`^\s*(\d+(?:[.,]\d+)?)\s*([a-zA-Zµ]*)`)
+
+// ImportRecipe reads the first schema.org Recipe out of an HTML page, from its
+// JSON-LD scripts, or out of a JSON-LD document. Nutrition is taken to be per
+// serving, as schema.org gives it.
+func ImportRecipe(data []byte) (*RecipeImport, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	var documents [][]byte
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		documents = [][]byte{trimmed}
+	} else {
+		scripts, err := jsonLDScripts(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		documents = scripts
+	}
+
+	var invalid error
+	for _, document := range documents {
+		var node any
+		if err := json.Unmarshal(document, &node); err != nil {
+			invalid = err
+			continue
+		}
+		if recipe := findRecipe(node); recipe != nil {
+			return mapRecipe(recipe), nil
+		}
+	}
+	if invalid != nil && len(documents) == 1 {
+		return nil, fmt.Errorf("invalid JSON-LD: %w", invalid)
+	}
+	return nil, ErrNoRecipe
+}
+
+// jsonLDScripts returns the contents of the JSON-LD scripts of an HTML page
+func jsonLDScripts(page []byte) ([][]byte, error) {
+	root, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return nil, err
+	}
+	var scripts [][]byte
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Script {
+			for _, attr := range n.Attr {
+				if attr.Key == "type" && strings.EqualFold(strings.TrimSpace(attr.Val), "application/ld+json") {
+					var text bytes.Buffer
+					for child := n.FirstChild; child != nil; child = child.NextSibling {
+						text.WriteString(child.Data)
+					}
+					scripts = append(scripts, text.Bytes())
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(root)
+	return scripts, nil
+}
+
+// findRecipe looks for a node typed Recipe in a JSON-LD value, through lists and
+// @graph
+func findRecipe(node any) map[string]any {
+	switch value := node.(type) {
+	case []any:
+		for _, item := range value {
+			if recipe := findRecipe(item); recipe != nil {
+				return recipe
+			}
+		}
+	case map[string]any:
+		for _, kind := range texts(value["@type"]) {
+			if kind == "Recipe" || strings.HasSuffix(kind, "/Recipe") {
+				return value
+			}
+		}
+		if graph, ok := value["@graph"]; ok {
+			return findRecipe(graph)
+		}
+	}
+	return nil
+}
+
+// mapRecipe maps the properties of a Recipe node onto a recipe
+func mapRecipe(node map[string]any) *RecipeImport {
+	imported := &RecipeImport{Unmapped: []string{}}
+	recipe := &imported.Recipe
+	for key := range node {
+		if !strings.HasPrefix(key, "@") && !importedProperties[key] {
+			imported.Unmapped = append(imported.Unmapped, key)
+		}
+	}
+
+	if names := texts(node["name"]); len(names) > 0 {
+		dish := html.UnescapeString(names[0])
+		recipe.Dish = &dish
+	}
+
+	property := "recipeIngredient"
+	if len(texts(node[property])) == 0 {
+		property = "ingredients"
+	}
+	ingredients, unmapped := ingredientsOf(property, node[property])
+	recipe.Ingredients = ingredients
+	imported.Unmapped = append(imported.Unmapped, unmapped...)
+	NormalizeIngredients(recipe.Ingredients)
+
+	steps, unmapped := limitSteps(instructions(node["recipeInstructions"]))
+	recipe.Instructions = steps
+	imported.Unmapped = append(imported.Unmapped, unmapped...)
+
+	if yield, ok := node["recipeYield"]; ok {
+		if servings, ok := servingsOf(yield); ok {
+			recipe.Servings = servings
+		} else {
+			imported.Unmapped = append(imported.Unmapped, "recipeYield")
+		}
+	}
+
+	if information, ok := node["nutrition"].(map[string]any); ok {
+		nutrients, unmapped := nutritionOf(information)
+		if nutrients != nil {
+			recipe.Nutrition = &Nutrition{Nutrients: *nutrients, Source: NutritionManual}
+			recipe.Nutrition_basis = BasisServing
+		}
+		imported.Unmapped = append(imported.Unmapped, unmapped...)
+	}
+
+	sort.Strings(imported.Unmapped)
+	return imported
+}
+
+// ingredientsOf parses the ingredient lines of a Recipe property, which may be a
+// single text or a list of them. Lines that are not text, or hold no item, are
+// returned as unmapped, as are the lines past MaxIngredients.
+func ingredientsOf(property string, value any) (Ingredients, []string) {
+	lines, isList := value.([]any)
+	if !isList && value != nil {
+		lines = []any{value}
+	}
+	var ingredients Ingredients
+	var unmapped []string
+	for i, line := range lines {
+		text, isText := line.(string)
+		if isText && strings.TrimSpace(text) == "" {
+			continue
+		}
+		ingredient := ParseIngredient(html.UnescapeString(text))
+		if ingredient.Item == "" || len(ingredients) == MaxIngredients {
+			if isList {
+				unmapped = append(unmapped, fmt.Sprintf("%s[%d]", property, i))
+			} else {
+				unmapped = append(unmapped, property)
+			}
+			continue
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	return ingredients, unmapped
+}
+
+// instructions flattens recipeInstructions, which may be text, a list of texts,
+// HowToSteps or HowToSections of steps, into one step per line
+func instructions(value any) []string {
+	var steps []string
+	switch value := value.(type) {
+	case string:
+		for _, line := range strings.Split(html.UnescapeString(value), "\n") {
+			if line = strings.TrimSpace(stripTags(line)); line != "" {
+				steps = append(steps, line)
+			}
+		}
+	case []any:
+		for _, item := range value {
+			steps = append(steps, instructions(item)...)
+		}
+	case map[string]any:
+		if items, ok := value["itemListElement"]; ok {
+			return instructions(items)
+		}
+		if text, ok := value["text"].(string); ok {
+			return instructions(text)
+		}
+		if name, ok := value["name"].(string); ok {
+			return instructions(name)
+		}
+	}
+	return steps
+}
+
+// limitSteps cuts steps down to the number and length a recipe allows, returning
+// the positions of the steps it shortened or left out
+func limitSteps(steps []string) ([]string, []string) {
+	var unmapped []string
+	for i := maxSteps; i < len(steps); i++ {
+		unmapped = append(unmapped, fmt.Sprintf("recipeInstructions[%d]", i))
+	}
+	if len(steps) > maxSteps {
+		steps = steps[:maxSteps]
+	}
+	for i, step := range steps {
+		if runes := []rune(step); len(runes) > maxStepLength {
+			steps[i] = strings.TrimSpace(string(runes[:maxStepLength]))
+			unmapped = append(unmapped, fmt.Sprintf("recipeInstructions[%d]", i))
+		}
+	}
+	return steps, unmapped
+}
+
+// servingsOf reads the number of servings from a recipeYield such as 4, "4" or
+// "Serves 4-6", taking the first number given
+func servingsOf(yield any) (int, bool) {
+	switch value := yield.(type) {
+	case float64:
+		if value >= 1 {
+			return int(math.Round(value)), true
+		}
+	case string:
+		for _, word := range strings.FieldsFunc(value, func(r rune) bool { return r < '0' || r > '9' }) {
+			if n, err := strconv.Atoi(word); err == nil && n >= 1 {
+				return n, true
+			}
+		}
+	case []any:
+		for _, item := range value {
+			if servings, ok := servingsOf(item); ok {
+				return servings, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// nutritionOf reads a NutritionInformation. Amounts such as "9 g" or "300 mg" are
+// converted to the unit the nutrient is kept in. It returns nil when no nutrient
+// could be read, and the properties left unmapped as nutrition.<property>.
+func nutritionOf(information map[string]any) (*Nutrients, []string) {
+	var nutrients Nutrients
+	var unmapped []string
+	read := false
+	for key, value := range information {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		nutrient, known := importedNutrients[key]
+		amount, ok := 0.0, false
+		if known {
+			amount, ok = amountOf(value, nutrient.unit)
+		}
+		if !ok {
+			unmapped = append(unmapped, "nutrition."+key)
+			continue
+		}
+		*nutrient.field(&nutrients) = amount
+		read = true
+	}
+	if !read {
+		return nil, unmapped
+	}
+	return &nutrients, unmapped
+}
+
+// amountOf reads an amount given as a number or as text with a unit, converting
+// weights to unit
+func amountOf(value any, unit string) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, value >= 0
+	case string:
+		match := amountPattern.FindStringSubmatch(value)
+		if match == nil {
+			return 0, false
+		}
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(match[1], ",", "."), 64)
+		if err != nil {
+			return 0, false
+		}
+		given := NormalizeUnit(match[2])
+		if unit == "kcal" && strings.EqualFold(given, "kj") {
+			return amount / kilojoulesPerCalorie, true
+		}
+		if grams, ok := unitGrams[given]; ok && unit != "kcal" {
+			return amount * grams / unitGrams[unit], true
+		}
+		return amount, true
+	}
+	return 0, false
+}
+
+// texts reads a value that may be a single text or a list of them
+func texts(value any) []string {
+	switch value := value.(type) {
+	case string:
+		if value = strings.TrimSpace(value); value != "" {
+			return []string{value}
+		}
+	case []any:
+		var all []string
+		for _, item := range value {
+			all = append(all, texts(item)...)
+		}
+		return all
+	}
+	return nil
+}
+
+var tagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// stripTags removes the HTML tags sites leave in instruction text
+func stripTags(text string) string {
+	return tagPattern.ReplaceAllString(text, "")
+}
@@ -0,0 +1,144 @@
+package models_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+)
+
+func TestImportRecipe(t *testing.T) {
+	page := `<html><head>
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "WebSite", "name": "Cooking"}</script>
+<script type="application/ld+json">
+{
+  "@context": "https://schema.org",
+  "@graph": [
+    {"@type": "Organization", "name": "Cooking"},
+    {
+      "@type": ["Recipe", "NewsArticle"],
+      "name": "Pasta &amp; Peas",
+      "author": {"@type": "Person", "name": "Ada"},
+      "recipeYield": ["Serves 4-6", "4 to 6 plates"],
+      "recipeIngredient": ["200 g spaghetti", {"@type": "Thing"}, "", "1 cup peas", "2-3 cloves garlic", 7],
+      "recipeInstructions": [
+        {"@type": "HowToSection", "name": "Sauce", "itemListElement": [
+          {"@type": "HowToStep", "text": "Crush the <b>garlic</b>."},
+          {"@type": "HowToStep", "text": "Warm the peas."}
+        ]},
+        {"@type": "HowToStep", "text": "Boil the spaghetti.\nToss everything together."}
+      ],
+      "nutrition": {
+        "@type": "NutritionInformation",
+        "calories": "2092 kJ",
+        "sodiumContent": "1.2 g",
+        "proteinContent": "20,5 g",
+        "fatContent": 9,
+        "servingSize": "1 plate"
+      }
+    }
+  ]
+}
+</script></head><body></body></html>`
+
+	imported, err := models.ImportRecipe([]byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipe := imported.Recipe
+	if recipe.Dish == nil || *recipe.Dish != "Pasta & Peas" {
+		t.Errorf("dish = %v, want Pasta & Peas", recipe.Dish)
+	}
+	if recipe.Servings != 4 {
+		t.Errorf("servings = %d, want the first number of Serves 4-6", recipe.Servings)
+	}
+
+	var items []string
+	for _, ingredient := range recipe.Ingredients {
+		items = append(items, ingredient.Item)
+	}
+	if want := []string{"spaghetti", "peas", "garlic"}; !slices.Equal(items, want) {
+		t.Errorf("ingredients = %v, want %v", items, want)
+	}
+	if garlic := recipe.Ingredients[2]; garlic.Quantity == nil || *garlic.Quantity != 2 || garlic.Unit != "clove" {
+		t.Errorf("garlic = %+v, want 2 cloves", garlic)
+	}
+
+	want := []string{"Crush the garlic.", "Warm the peas.", "Boil the spaghetti.", "Toss everything together."}
+	if !slices.Equal(recipe.Instructions, want) {
+		t.Errorf("instructions = %q, want %q", recipe.Instructions, want)
+	}
+
+	if recipe.Nutrition == nil || recipe.Nutrition_basis != models.BasisServing {
+		t.Fatalf("nutrition = %+v per %q, want it per serving", recipe.Nutrition, recipe.Nutrition_basis)
+	}
+	nutrients := recipe.Nutrition.Nutrients
+	if !near(nutrients.Calories, 500) || !near(nutrients.Sodium, 1200) || !near(nutrients.Protein, 20.5) || nutrients.Fat != 9 {
+		t.Errorf("nutrients = %+v, want 500 kcal, 1200 mg sodium, 20.5 g protein and 9 g fat", nutrients)
+	}
+
+	wantUnmapped := []string{"author", "nutrition.servingSize", "recipeIngredient[1]", "recipeIngredient[5]"}
+	if !slices.Equal(imported.Unmapped, wantUnmapped) {
+		t.Errorf("unmapped = %v, want %v", imported.Unmapped, wantUnmapped)
+	}
+}
+
+func TestImportRecipeLimits(t *testing.T) {
+	ingredients := make([]string, models.MaxIngredients+2)
+	for i := range ingredients {
+		ingredients[i] = fmt.Sprintf("%q", fmt.Sprintf("%d g flour", i+1))
+	}
+	steps := make([]string, 102)
+	for i := range steps {
+		steps[i] = fmt.Sprintf("%q", fmt.Sprintf("Step %d.", i+1))
+	}
+	steps[3] = fmt.Sprintf("%q", strings.Repeat("é", 5001))
+	document := fmt.Sprintf(`{"@type": "Recipe", "name": "Bread", "recipeIngredient": [%s], "recipeInstructions": [%s]}`,
+		strings.Join(ingredients, ","), strings.Join(steps, ","))
+
+	imported, err := models.ImportRecipe([]byte(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipe := imported.Recipe
+	if len(recipe.Ingredients) != models.MaxIngredients || recipe.Ingredients[models.MaxIngredients-1].Item != "flour" {
+		t.Errorf("kept %d ingredients, want the first %d", len(recipe.Ingredients), models.MaxIngredients)
+	}
+	if len(recipe.Instructions) != 100 || recipe.Instructions[99] != "Step 100." {
+		t.Errorf("kept %d steps, want the first 100", len(recipe.Instructions))
+	}
+	if got := len([]rune(recipe.Instructions[3])); got != 5000 {
+		t.Errorf("a long step was cut to %d characters, want 5000", got)
+	}
+
+	want := []string{"recipeIngredient[100]", "recipeIngredient[101]", "recipeInstructions[100]", "recipeInstructions[101]", "recipeInstructions[3]"}
+	if !slices.Equal(imported.Unmapped, want) {
+		t.Errorf("unmapped = %v, want %v", imported.Unmapped, want)
+	}
+}
+
+func TestImportRecipeWithoutRecipe(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		invalid bool
+	}{
+		{name: "page without JSON-LD", data: "<html><body><h1>Pasta</h1></body></html>"},
+		{name: "JSON-LD of another type", data: `{"@type": "Person", "name": "Ada"}`},
+		{name: "graph without a recipe", data: `{"@graph": [{"@type": "WebPage"}]}`},
+		{name: "broken JSON-LD", data: `{"@type": "Recipe",`, invalid: true},
+	}
+
+	for _, test := range tests {
+		_, err := models.ImportRecipe([]byte(test.data))
+		switch {
+		case test.invalid && (err == nil || errors.Is(err, models.ErrNoRecipe)):
+			t.Errorf("%s: got %v, want invalid JSON-LD", test.name, err)
+		case !test.invalid && !errors.Is(err, models.ErrNoRecipe):
+			t.Errorf("%s: got %v, want ErrNoRecipe", test.name, err)
+		}
+	}
+}
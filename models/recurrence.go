@@ -0,0 +1,407 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Frequencies of a recurrence rule this server can expand
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// MaxOccurrences bounds how many occurrences a single expansion returns
+const MaxOccurrences = 100
+
+// maxIdlePeriods stops an expansion that keeps finding no occurrence, such as the
+// 31st of every other month starting in an even month
+const maxIdlePeriods = 1000
+
+// ErrInvalidRecurrence is wrapped by every error about a malformed recurrence rule
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// Recurrence makes a todo repeat. Start is the due date of the first todo of the
+// series and Rule an RFC 5545 RRULE expanded from it in Time_zone. Every todo of a
+// series carries the same Series_id and its own 1-based Index.
+type Recurrence struct {
+	Rule      string             `json:"rule" validate:"required,max=500"`
+	Time_zone string             `json:"time_zone,omitempty" bson:"time_zone,omitempty"`
+	Start     time.Time          `json:"start"`
+	Series_id primitive.ObjectID `json:"series_id"`
+	Index     int                `json:"index"`
+}
+
+// Occurrence is one expanded date of a recurrence
+type Occurrence struct {
+	Index  int       `json:"index"`
+	Due_at time.Time `json:"due_at"`
+}
+
+// Location returns the time zone the rule is expanded in, UTC by default
+func (r Recurrence) Location() (*time.Location, error) {
+	if r.Time_zone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(r.Time_zone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidRecurrence, r.Time_zone)
+	}
+	return loc, nil
+}
+
+// Normalize checks the rule and time zone and rewrites the rule in canonical form
+func (r *Recurrence) Normalize() error {
+	loc, err := r.Location()
+	if err != nil {
+		return err
+	}
+	rule, err := ParseRecurrenceRule(r.Rule, loc)
+	if err != nil {
+		return err
+	}
+	r.Rule = rule.String()
+	return nil
+}
+
+// After returns up to limit occurrences of the series strictly after the given time
+func (r Recurrence) After(after time.Time, limit int) ([]Occurrence, error) {
+	loc, err := r.Location()
+	if err != nil {
+		return nil, err
+	}
+	rule, err := ParseRecurrenceRule(r.Rule, loc)
+	if err != nil {
+		return nil, err
+	}
+	return rule.After(r.Start.In(loc), after, limit), nil
+}
+
+// WeekdayNum is a BYDAY entry: a weekday, optionally the Nth one of the month
+// (negative N counts from the end of the month)
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// RecurrenceRule is a parsed RRULE. FREQ may be DAILY, WEEKLY or MONTHLY, together
+// with INTERVAL, BYDAY, BYMONTHDAY, WKST and either UNTIL or COUNT.
+type RecurrenceRule struct {
+	Freq        string
+	Interval    int
+	By_day      []WeekdayNum
+	By_monthday []int
+	Week_start  time.Weekday
+	Until       *time.Time
+	Count       int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func weekdayCode(day time.Weekday) string {
+	return strings.ToUpper(day.String()[:2])
+}
+
+// ParseRecurrenceRule parses an RRULE such as "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10".
+// An UNTIL without a trailing Z is taken in loc, a plain date means the end of that day.
+func ParseRecurrenceRule(s string, loc *time.Location) (*RecurrenceRule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	rule := &RecurrenceRule{Interval: 1, Week_start: time.Monday}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(s, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRecurrence, part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRecurrence, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			if value != FreqDaily && value != FreqWeekly && value != FreqMonthly {
+				return nil, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err != nil || rule.Interval < 1 || rule.Interval > 999 {
+				return nil, fmt.Errorf("%w: INTERVAL must be between 1 and 999", ErrInvalidRecurrence)
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err != nil || rule.Count < 1 {
+				return nil, fmt.Errorf("%w: COUNT must be a positive number", ErrInvalidRecurrence)
+			}
+		case "UNTIL":
+			until, err := parseUntil(value, loc)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.By_day = append(rule.By_day, day)
+			}
+		case "BYMONTHDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, err := strconv.Atoi(code)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("%w: BYMONTHDAY must be between 1 and 31 or -31 and -1", ErrInvalidRecurrence)
+				}
+				rule.By_monthday = append(rule.By_monthday, day)
+			}
+		case "WKST":
+			day, ok := weekdayCodes[value]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown WKST %q", ErrInvalidRecurrence, value)
+			}
+			rule.Week_start = day
+		default:
+			return nil, fmt.Errorf("%w: %s is not supported", ErrInvalidRecurrence, name)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	case rule.Count > 0 && rule.Until != nil:
+		return nil, fmt.Errorf("%w: COUNT and UNTIL cannot both be given", ErrInvalidRecurrence)
+	case len(rule.By_monthday) > 0 && rule.Freq != FreqMonthly:
+		return nil, fmt.Errorf("%w: BYMONTHDAY needs FREQ=MONTHLY", ErrInvalidRecurrence)
+	}
+	for _, day := range rule.By_day {
+		if day.N != 0 && rule.Freq != FreqMonthly {
+			return nil, fmt.Errorf("%w: numbered BYDAY needs FREQ=MONTHLY", ErrInvalidRecurrence)
+		}
+	}
+	return rule, nil
+}
+
+func parseWeekdayNum(code string) (WeekdayNum, error) {
+	if len(code) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: unknown BYDAY %q", ErrInvalidRecurrence, code)
+	}
+	day, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("%w: unknown BYDAY %q", ErrInvalidRecurrence, code)
+	}
+	num := WeekdayNum{Weekday: day}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("%w: unknown BYDAY %q", ErrInvalidRecurrence, code)
+		}
+		num.N = n
+	}
+	return num, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	if until, err := time.ParseInLocation("20060102T150405", value, loc); err == nil {
+		return until, nil
+	}
+	if day, err := time.ParseInLocation("20060102", value, loc); err == nil {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: UNTIL must look like 20250131 or 20250131T090000Z", ErrInvalidRecurrence)
+}
+
+// String formats the rule as a canonical RRULE
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.By_day) > 0 {
+		days := make([]string, len(r.By_day))
+		for i, day := range r.By_day {
+			days[i] = weekdayCode(day.Weekday)
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.By_monthday) > 0 {
+		days := make([]string, len(r.By_monthday))
+		for i, day := range r.By_monthday {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Week_start != time.Monday {
+		parts = append(parts, "WKST="+weekdayCode(r.Week_start))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// After returns up to limit occurrences strictly after the given time, numbered by
+// their position in the series that starts at dtstart
+func (r *RecurrenceRule) After(dtstart time.Time, after time.Time, limit int) []Occurrence {
+	occurrences := []Occurrence{}
+	if limit <= 0 {
+		return occurrences
+	}
+	r.each(dtstart, func(n int, at time.Time) bool {
+		if at.After(after) {
+			occurrences = append(occurrences, Occurrence{Index: n, Due_at: at})
+		}
+		return len(occurrences) < limit
+	})
+	return occurrences
+}
+
+// each calls fn with every occurrence in order until fn returns false or the rule
+// ends. As in RFC 5545, dtstart itself is always the first occurrence.
+func (r *RecurrenceRule) each(dtstart time.Time, fn func(n int, at time.Time) bool) {
+	n := 0
+	emit := func(at time.Time) bool {
+		if r.Until != nil && at.After(*r.Until) {
+			return false
+		}
+		n++
+		return fn(n, at) && (r.Count == 0 || n < r.Count)
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+	for period, idle := 0, 0; idle < maxIdlePeriods; period++ {
+		idle++
+		for _, at := range r.period(dtstart, period) {
+			if !at.After(dtstart) {
+				continue
+			}
+			idle = 0
+			if !emit(at) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the candidate dates of the k-th day, week or month of the rule,
+// in order and at the wall clock time of dtstart
+func (r *RecurrenceRule) period(dtstart time.Time, k int) []time.Time {
+	switch r.Freq {
+	case FreqDaily:
+		day := dtstart.AddDate(0, 0, k*r.Interval)
+		if len(r.By_day) > 0 && !r.onWeekday(day.Weekday()) {
+			return nil
+		}
+		return []time.Time{day}
+
+	case FreqWeekly:
+		sinceWeekStart := (int(dtstart.Weekday()) - int(r.Week_start) + 7) % 7
+		weekStart := dtstart.AddDate(0, 0, 7*k*r.Interval-sinceWeekStart)
+		var days []time.Time
+		for offset := 0; offset < 7; offset++ {
+			day := weekStart.AddDate(0, 0, offset)
+			if (len(r.By_day) == 0 && day.Weekday() == dtstart.Weekday()) || r.onWeekday(day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+		return days
+
+	default:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(k*r.Interval), 1,
+			dtstart.Hour(), dtstart.Minute(), dtstart.Second(), dtstart.Nanosecond(), dtstart.Location())
+		var days []time.Time
+		for _, day := range r.monthDays(first, dtstart.Day()) {
+			days = append(days, first.AddDate(0, 0, day-1))
+		}
+		return days
+	}
+}
+
+func (r *RecurrenceRule) onWeekday(day time.Weekday) bool {
+	return slices.ContainsFunc(r.By_day, func(d WeekdayNum) bool { return d.Weekday == day })
+}
+
+// monthDays returns the sorted days of the month starting at first that the rule
+// selects. BYMONTHDAY and BYDAY narrow each other down when both are given.
+func (r *RecurrenceRule) monthDays(first time.Time, startDay int) []int {
+	length := first.AddDate(0, 1, -1).Day()
+
+	var byMonthday []int
+	for _, day := range r.By_monthday {
+		if day < 0 {
+			day = length + 1 + day
+		}
+		if day >= 1 && day <= length {
+			byMonthday = append(byMonthday, day)
+		}
+	}
+
+	var byDay []int
+	for _, weekday := range r.By_day {
+		firstMatch := 1 + (int(weekday.Weekday)-int(first.Weekday())+7)%7
+		switch {
+		case weekday.N == 0:
+			for day := firstMatch; day <= length; day += 7 {
+				byDay = append(byDay, day)
+			}
+		case weekday.N > 0:
+			if day := firstMatch + 7*(weekday.N-1); day <= length {
+				byDay = append(byDay, day)
+			}
+		default:
+			last := firstMatch + 7*((length-firstMatch)/7)
+			if day := last + 7*(weekday.N+1); day >= 1 {
+				byDay = append(byDay, day)
+			}
+		}
+	}
+
+	var days []int
+	switch {
+	case len(r.By_monthday) > 0 && len(r.By_day) > 0:
+		for _, day := range byMonthday {
+			if slices.Contains(byDay, day) {
+				days = append(days, day)
+			}
+		}
+	case len(r.By_monthday) > 0:
+		days = byMonthday
+	case len(r.By_day) > 0:
+		days = byDay
+	case startDay <= length:
+		days = []int{startDay}
+	}
+
+	sort.Ints(days)
+	return slices.Compact(days)
+}
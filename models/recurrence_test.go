@@ -0,0 +1,200 @@
+package models_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+)
+
+func TestRecurrenceAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		zone     string
+		start    string
+		after    string
+		limit    int
+		want     []string
+		wantFrom int
+	}{
+		{
+			name:  "every other week on Tuesday and Sunday, weeks starting Monday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			start: "1997-08-05 09:00",
+			limit: 10,
+			want:  []string{"1997-08-10 09:00", "1997-08-19 09:00", "1997-08-24 09:00"},
+		},
+		{
+			name:  "every other week on Tuesday and Sunday, weeks starting Sunday",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			start: "1997-08-05 09:00",
+			limit: 10,
+			want:  []string{"1997-08-17 09:00", "1997-08-19 09:00", "1997-08-31 09:00"},
+		},
+		{
+			name:  "last Friday of the month",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: "2026-01-30 18:00",
+			limit: 4,
+			want:  []string{"2026-02-27 18:00", "2026-03-27 18:00", "2026-04-24 18:00", "2026-05-29 18:00"},
+		},
+		{
+			name:  "the 31st skips shorter months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: "2026-01-31 08:00",
+			limit: 4,
+			want:  []string{"2026-03-31 08:00", "2026-05-31 08:00", "2026-07-31 08:00", "2026-08-31 08:00"},
+		},
+		{
+			name:  "the last day of every month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2026-01-31 08:00",
+			limit: 3,
+			want:  []string{"2026-02-28 08:00", "2026-03-31 08:00", "2026-04-30 08:00"},
+		},
+		{
+			name:  "count includes the first occurrence",
+			rule:  "FREQ=DAILY;COUNT=3",
+			start: "2026-03-01 09:00",
+			limit: 10,
+			want:  []string{"2026-03-02 09:00", "2026-03-03 09:00"},
+		},
+		{
+			name:  "a plain until date includes that whole day",
+			rule:  "FREQ=DAILY;UNTIL=20260303",
+			start: "2026-03-01 09:00",
+			limit: 10,
+			want:  []string{"2026-03-02 09:00", "2026-03-03 09:00"},
+		},
+		{
+			name:  "until at a time stops before a later occurrence that day",
+			rule:  "FREQ=DAILY;UNTIL=20260303T080000Z",
+			start: "2026-03-01 09:00",
+			limit: 10,
+			want:  []string{"2026-03-02 09:00"},
+		},
+		{
+			name:     "numbering continues after a later point in the series",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,TH",
+			start:    "2026-03-02 07:30",
+			after:    "2026-03-12 07:30",
+			limit:    2,
+			want:     []string{"2026-03-16 07:30", "2026-03-19 07:30"},
+			wantFrom: 5,
+		},
+		{
+			name:  "the wall clock time is kept when summer time starts",
+			rule:  "FREQ=DAILY",
+			zone:  "Europe/Berlin",
+			start: "2026-03-28 09:00",
+			limit: 2,
+			want:  []string{"2026-03-29 09:00", "2026-03-30 09:00"},
+		},
+		{
+			name:  "the wall clock time is kept when summer time ends",
+			rule:  "FREQ=WEEKLY",
+			zone:  "America/New_York",
+			start: "2026-10-26 09:00",
+			limit: 2,
+			want:  []string{"2026-11-02 09:00", "2026-11-09 09:00"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recurrence := models.Recurrence{Rule: test.rule, Time_zone: test.zone}
+			loc, err := recurrence.Location()
+			if err != nil {
+				t.Fatal(err)
+			}
+			recurrence.Start = localTime(t, test.start, loc)
+			after := recurrence.Start
+			if test.after != "" {
+				after = localTime(t, test.after, loc)
+			}
+
+			occurrences, err := recurrence.After(after, test.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for i, occurrence := range occurrences {
+				got = append(got, occurrence.Due_at.In(loc).Format("2006-01-02 15:04"))
+				wantIndex := i + 2
+				if test.wantFrom != 0 {
+					wantIndex = i + test.wantFrom
+				}
+				if occurrence.Index != wantIndex {
+					t.Errorf("occurrence %d has index %d, want %d", i, occurrence.Index, wantIndex)
+				}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceDSTOffset(t *testing.T) {
+	recurrence := models.Recurrence{Rule: "FREQ=DAILY", Time_zone: "Europe/Berlin"}
+	loc, err := recurrence.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recurrence.Start = localTime(t, "2026-03-28 09:00", loc)
+	occurrences, err := recurrence.After(recurrence.Start, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 09:00 in Berlin is 08:00 UTC in winter and 07:00 UTC in summer
+	if got := occurrences[0].Due_at.Sub(recurrence.Start); got != 23*time.Hour {
+		t.Errorf("the day summer time starts lasts %v, want 23h", got)
+	}
+}
+
+func TestRecurrenceNormalize(t *testing.T) {
+	tests := []struct {
+		rule    string
+		zone    string
+		want    string
+		invalid bool
+	}{
+		{rule: "rrule:freq=weekly;byday=mo,we;wkst=su;count=5", want: "FREQ=WEEKLY;BYDAY=MO,WE;WKST=SU;COUNT=5"},
+		{rule: "FREQ=MONTHLY;BYDAY=-1FR;INTERVAL=1", want: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{rule: "FREQ=DAILY;UNTIL=20260303T080000Z", want: "FREQ=DAILY;UNTIL=20260303T080000Z"},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20260303", invalid: true},
+		{rule: "FREQ=YEARLY", invalid: true},
+		{rule: "FREQ=WEEKLY;BYMONTHDAY=31", invalid: true},
+		{rule: "FREQ=WEEKLY;BYDAY=-1FR", invalid: true},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=32", invalid: true},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", invalid: true},
+		{rule: "BYDAY=MO", invalid: true},
+		{rule: "FREQ=DAILY", zone: "Mars/Olympus", invalid: true},
+	}
+
+	for _, test := range tests {
+		recurrence := models.Recurrence{Rule: test.rule, Time_zone: test.zone}
+		err := recurrence.Normalize()
+		switch {
+		case test.invalid && !errors.Is(err, models.ErrInvalidRecurrence):
+			t.Errorf("%q: got %v, want an invalid recurrence", test.rule, err)
+		case !test.invalid && err != nil:
+			t.Errorf("%q: %v", test.rule, err)
+		case !test.invalid && recurrence.Rule != test.want:
+			t.Errorf("%q normalized to %q, want %q", test.rule, recurrence.Rule, test.want)
+		}
+	}
+}
+
+// localTime parses a wall clock time in loc
+func localTime(t *testing.T, value string, loc *time.Location) time.Time {
+	t.Helper()
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
@@ -0,0 +1,123 @@
+package models
+
+import "math"
+
+// Systems of units ingredients can be converted to
+const (
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// Teaspoons and tablespoons are used the world over and are kept in either system
+// unless weighed
+var spoonUnits = map[string]bool{UnitTeaspoon: true, UnitTablespoon: true, "pinch": true, "dash": true}
+
+// ScaledRecipe is a recipe scaled from its own number of servings to another one,
+// with Factor the ratio between the two
+type ScaledRecipe struct {
+	CalorieTracker
+	Original_servings int      `json:"original_servings"`
+	Factor            float64  `json:"factor"`
+	Unconverted       []string `json:"unconverted,omitempty"`
+}
+
+// Scale multiplies the ingredients and nutrition of the recipe for the given
+// number of servings. The ingredients are copied, so the slice the recipe was
+// loaded with is left alone.
+func (r *CalorieTracker) Scale(servings int) float64 {
+	factor := float64(servings) / float64(r.ServingCount())
+	scaled := make(Ingredients, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		if ingredient.Quantity != nil {
+			quantity := roundTo(*ingredient.Quantity*factor, 3)
+			ingredient.Quantity = &quantity
+		}
+		scaled[i] = ingredient
+	}
+	r.Ingredients = scaled
+	r.Servings = servings
+	if r.Nutrition != nil {
+		nutrition := *r.Nutrition
+		nutrition.Nutrients = nutrition.Scale(factor).Round()
+		r.Nutrition = &nutrition
+	}
+	return factor
+}
+
+// ConvertUnits writes the amount of an ingredient in the units of system. Weights
+// and volumes are converted within their kind; with weigh, volumes of a food with
+// a known density become weights. Counted ingredients and kitchen units such as
+// cloves are left as they are. It reports false when the ingredient is measured
+// in units it cannot convert.
+func ConvertUnits(ingredient Ingredient, system string, food *Food, weigh bool) (Ingredient, bool) {
+	if ingredient.Quantity == nil {
+		return ingredient, true
+	}
+	grams, isWeight := unitGrams[ingredient.Unit]
+	milliliters, isVolume := unitMilliliters[ingredient.Unit]
+
+	switch {
+	case isVolume && weigh:
+		if food == nil || food.Density == nil {
+			return ingredient, false
+		}
+		quantity, unit := weightIn(system, *ingredient.Quantity*milliliters**food.Density)
+		ingredient.Quantity, ingredient.Unit = &quantity, unit
+	case isVolume && !spoonUnits[ingredient.Unit]:
+		quantity, unit := volumeIn(system, *ingredient.Quantity*milliliters)
+		ingredient.Quantity, ingredient.Unit = &quantity, unit
+	case isWeight:
+		quantity, unit := weightIn(system, *ingredient.Quantity*grams)
+		ingredient.Quantity, ingredient.Unit = &quantity, unit
+	}
+	return ingredient, true
+}
+
+// weightIn writes a weight in grams as the handiest unit of system
+func weightIn(system string, grams float64) (float64, string) {
+	if system == UnitsImperial {
+		ounces := grams / unitGrams[UnitOunce]
+		if ounces >= 16 {
+			return roundTo(ounces/16, 2), UnitPound
+		}
+		return roundFraction(ounces, 4), UnitOunce
+	}
+	if grams >= 1000 {
+		return roundTo(grams/1000, 2), UnitKilogram
+	}
+	return roundMetric(grams), UnitGram
+}
+
+// volumeIn writes a volume in milliliters as the handiest unit of system
+func volumeIn(system string, milliliters float64) (float64, string) {
+	if system == UnitsImperial {
+		switch {
+		case milliliters >= unitMilliliters[UnitCup]/4:
+			return roundFraction(milliliters/unitMilliliters[UnitCup], 8), UnitCup
+		case milliliters >= unitMilliliters[UnitTablespoon]:
+			return roundFraction(milliliters/unitMilliliters[UnitTablespoon], 4), UnitTablespoon
+		}
+		return roundFraction(milliliters/unitMilliliters[UnitTeaspoon], 8), UnitTeaspoon
+	}
+	if milliliters >= 1000 {
+		return roundTo(milliliters/1000, 2), UnitLiter
+	}
+	return roundMetric(milliliters), UnitMilliliter
+}
+
+// roundMetric rounds to whole units, or to a tenth below ten
+func roundMetric(value float64) float64 {
+	if value < 10 {
+		return roundTo(value, 1)
+	}
+	return math.Round(value)
+}
+
+// roundFraction rounds to the nearest 1/parts, the way imperial amounts are
+// measured, and to two decimals when that would round it away
+func roundFraction(value float64, parts float64) float64 {
+	if rounded := math.Round(value*parts) / parts; rounded > 0 {
+		return rounded
+	}
+	return roundTo(value, 2)
+}
@@ -0,0 +1,115 @@
+package models_test
+
+import (
+	"testing"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+)
+
+func TestRecipeScale(t *testing.T) {
+	amount := func(quantity float64) *float64 { return &quantity }
+	tests := []struct {
+		name     string
+		servings int
+		scaled   int
+		factor   float64
+		flour    float64
+		calories float64
+	}{
+		{name: "up", servings: 4, scaled: 6, factor: 1.5, flour: 300, calories: 1200},
+		{name: "down", servings: 4, scaled: 1, factor: 0.25, flour: 50, calories: 200},
+		{name: "to a third", servings: 3, scaled: 1, factor: 1.0 / 3, flour: 66.667, calories: 267},
+		{name: "a recipe without servings serves one", servings: 0, scaled: 2, factor: 2, flour: 400, calories: 1600},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ingredients := models.Ingredients{
+				{Quantity: amount(200), Unit: models.UnitGram, Item: "flour"},
+				{Item: "salt"},
+			}
+			recipe := models.CalorieTracker{
+				Servings:    test.servings,
+				Ingredients: ingredients,
+				Nutrition:   &models.Nutrition{Nutrients: models.Nutrients{Calories: 800}, Source: models.NutritionManual},
+			}
+			nutrition := recipe.Nutrition
+
+			factor := recipe.Scale(test.scaled)
+			if factor != test.factor || recipe.Servings != test.scaled {
+				t.Errorf("scaled by %v to %d servings, want %v and %d", factor, recipe.Servings, test.factor, test.scaled)
+			}
+			if got := *recipe.Ingredients[0].Quantity; got != test.flour {
+				t.Errorf("flour = %v g, want %v", got, test.flour)
+			}
+			if recipe.Ingredients[1].Quantity != nil {
+				t.Errorf("salt without an amount was given %v", *recipe.Ingredients[1].Quantity)
+			}
+			if recipe.Nutrition.Calories != test.calories {
+				t.Errorf("calories = %v, want %v", recipe.Nutrition.Calories, test.calories)
+			}
+
+			// The recipe is scaled on copies, leaving what was loaded alone
+			if *ingredients[0].Quantity != 200 || nutrition.Calories != 800 {
+				t.Errorf("scaling changed the loaded recipe to %v g flour and %v calories", *ingredients[0].Quantity, nutrition.Calories)
+			}
+		})
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	water, flour := 1.0, 0.5
+	tests := []struct {
+		name     string
+		quantity float64
+		unit     string
+		system   string
+		density  *float64
+		weigh    bool
+		want     float64
+		wantUnit string
+		failed   bool
+	}{
+		{name: "grams to pounds", quantity: 500, unit: "g", system: models.UnitsImperial, want: 1.1, wantUnit: "lb"},
+		{name: "grams to quarter ounces", quantity: 100, unit: "g", system: models.UnitsImperial, want: 3.5, wantUnit: "oz"},
+		{name: "a pinch of ounces keeps two decimals", quantity: 0.3, unit: "g", system: models.UnitsImperial, want: 0.01, wantUnit: "oz"},
+		{name: "pounds to grams", quantity: 1, unit: "lb", system: models.UnitsMetric, want: 454, wantUnit: "g"},
+		{name: "grams to kilograms", quantity: 1500, unit: "g", system: models.UnitsMetric, want: 1.5, wantUnit: "kg"},
+		{name: "ounces to tenths of a gram", quantity: 0.25, unit: "oz", system: models.UnitsMetric, want: 7.1, wantUnit: "g"},
+		{name: "milliliters to eighth cups", quantity: 250, unit: "ml", system: models.UnitsImperial, want: 1, wantUnit: "cup"},
+		{name: "milliliters to tablespoons", quantity: 30, unit: "ml", system: models.UnitsImperial, want: 2, wantUnit: "tbsp"},
+		{name: "milliliters to teaspoons", quantity: 2, unit: "ml", system: models.UnitsImperial, want: 0.375, wantUnit: "tsp"},
+		{name: "cups to milliliters", quantity: 1, unit: "cup", system: models.UnitsMetric, want: 237, wantUnit: "ml"},
+		{name: "cups to liters", quantity: 5, unit: "cup", system: models.UnitsMetric, want: 1.18, wantUnit: "l"},
+		{name: "spoons stay spoons", quantity: 1, unit: "tbsp", system: models.UnitsMetric, want: 1, wantUnit: "tbsp"},
+		{name: "cloves are counted", quantity: 3, unit: "clove", system: models.UnitsImperial, want: 3, wantUnit: "clove"},
+		{name: "weighing water", quantity: 1, unit: "cup", system: models.UnitsMetric, density: &water, weigh: true, want: 237, wantUnit: "g"},
+		{name: "weighing flour in ounces", quantity: 1, unit: "cup", system: models.UnitsImperial, density: &flour, weigh: true, want: 4.25, wantUnit: "oz"},
+		{name: "weighing spoons", quantity: 2, unit: "tbsp", system: models.UnitsMetric, density: &flour, weigh: true, want: 15, wantUnit: "g"},
+		{name: "weighing without a density", quantity: 1, unit: "cup", system: models.UnitsMetric, weigh: true, want: 1, wantUnit: "cup", failed: true},
+		{name: "weighing a weight converts it", quantity: 2, unit: "kg", system: models.UnitsImperial, weigh: true, want: 4.41, wantUnit: "lb"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quantity := test.quantity
+			ingredient := models.Ingredient{Quantity: &quantity, Unit: test.unit, Item: "flour"}
+			food := &models.Food{Name: "flour", Density: test.density}
+			converted, ok := models.ConvertUnits(ingredient, test.system, food, test.weigh)
+			if ok == test.failed {
+				t.Errorf("converted = %v, want %v", ok, !test.failed)
+			}
+			if *converted.Quantity != test.want || converted.Unit != test.wantUnit {
+				t.Errorf("%v %s became %v %s, want %v %s", test.quantity, test.unit, *converted.Quantity, converted.Unit, test.want, test.wantUnit)
+			}
+			if quantity != test.quantity || ingredient.Unit != test.unit {
+				t.Errorf("converting changed the ingredient to %v %s", quantity, ingredient.Unit)
+			}
+		})
+	}
+
+	salt := models.Ingredient{Item: "salt"}
+	if converted, ok := models.ConvertUnits(salt, models.UnitsImperial, nil, true); !ok || converted.Quantity != nil {
+		t.Errorf("an ingredient without an amount converted to %+v (%v)", converted, ok)
+	}
+}
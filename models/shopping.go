@@ -0,0 +1,238 @@
+package models
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// AisleOther holds the shopping items of foods without a known aisle
+const AisleOther = "other"
+
+// Aisles are the parts of a store in the order a shopping list walks them
+var Aisles = []string{
+	"produce", "bakery", "meat", "seafood", "dairy", "baking", "pantry", "canned", "condiments", "spices", "beverages",
+	AisleOther,
+}
+
+// ShoppingItem is one thing to buy, the ingredients of every recipe needing it
+// added up. Quantity is nil for ingredients without an amount.
+type ShoppingItem struct {
+	Item     string   `json:"item"`
+	Quantity *float64 `json:"quantity,omitempty"`
+	Unit     string   `json:"unit,omitempty"`
+	Aisle    string   `json:"aisle"`
+	Recipes  []string `json:"recipes"`
+}
+
+// ShoppingAisle is the part of a shopping list found in one aisle
+type ShoppingAisle struct {
+	Aisle string         `json:"aisle"`
+	Items []ShoppingItem `json:"items"`
+}
+
+// String formats the item as a line such as "500 g flour"
+func (i ShoppingItem) String() string {
+	return Ingredient{Quantity: i.Quantity, Unit: i.Unit, Item: i.Item}.String()
+}
+
+// Kinds of amount ingredients are added up by. Ingredients measured in other
+// units, such as cans or cloves, are added up per unit.
+const (
+	amountWeight = "weight"
+	amountVolume = "volume"
+	amountCount  = "count"
+	amountNone   = "none"
+)
+
+// shoppingGroup adds up the amounts of one food of one kind
+type shoppingGroup struct {
+	kind    string
+	unit    string
+	value   float64
+	recipes []string
+}
+
+// shoppingFood collects everything a list needs of one food. It is listed under
+// the first ingredient naming it with an amount, since ingredients without one
+// tend to read like "salt to taste".
+type shoppingFood struct {
+	item     string
+	measured bool
+	food     *Food
+	groups   []*shoppingGroup
+}
+
+// ShoppingList merges the ingredients of recipes into the items to buy, grouped by
+// aisle. Ingredients of the same food are added up: weights with weights, volumes
+// with volumes and counted pieces with pieces. Volumes and pieces are weighed when
+// the food is also needed by weight and its density or unit weight is known.
+// Amounts are written in the units of system; pieces are rounded up to whole ones.
+func ShoppingList(recipes []CalorieTracker, foods map[string]Food, system string) []ShoppingAisle {
+	var order []string
+	needed := map[string]*shoppingFood{}
+	for _, recipe := range recipes {
+		dish := ""
+		if recipe.Dish != nil {
+			dish = *recipe.Dish
+		}
+		for _, ingredient := range recipe.Ingredients {
+			key := FoodKey(ingredient.Item)
+			var food *Food
+			if match, ok := foods[ingredient.Item]; ok {
+				food = &match
+				key = FoodKey(match.Name)
+			}
+			entry, ok := needed[key]
+			if !ok {
+				entry = &shoppingFood{food: food}
+				needed[key] = entry
+				order = append(order, key)
+			}
+			if entry.item == "" || !entry.measured && ingredient.Quantity != nil {
+				entry.item = ingredient.Item
+			}
+			entry.add(ingredient, dish)
+		}
+	}
+
+	byAisle := map[string][]ShoppingItem{}
+	for _, key := range order {
+		entry := needed[key]
+		entry.fold()
+		aisle := AisleOther
+		if entry.food != nil && entry.food.Aisle != "" {
+			aisle = entry.food.Aisle
+		}
+		for _, group := range entry.groups {
+			item := ShoppingItem{Item: entry.item, Aisle: aisle, Recipes: group.recipes}
+			if group.kind != amountNone {
+				quantity, unit := group.amount(system)
+				item.Quantity, item.Unit = &quantity, unit
+			}
+			byAisle[aisle] = append(byAisle[aisle], item)
+		}
+	}
+
+	list := []ShoppingAisle{}
+	for _, aisle := range shoppingAisles(byAisle) {
+		items := byAisle[aisle]
+		sort.SliceStable(items, func(i, j int) bool { return strings.ToLower(items[i].Item) < strings.ToLower(items[j].Item) })
+		list = append(list, ShoppingAisle{Aisle: aisle, Items: items})
+	}
+	return list
+}
+
+// add counts an ingredient of a recipe towards the food
+func (f *shoppingFood) add(ingredient Ingredient, dish string) {
+	kind, unit, value := amountNone, "", 0.0
+	if ingredient.Quantity != nil {
+		f.measured = true
+		quantity := *ingredient.Quantity
+		if grams, ok := unitGrams[ingredient.Unit]; ok {
+			kind, value = amountWeight, quantity*grams
+		} else if milliliters, ok := unitMilliliters[ingredient.Unit]; ok {
+			kind, value = amountVolume, quantity*milliliters
+		} else if ingredient.Unit == "" {
+			kind, value = amountCount, quantity
+		} else {
+			kind, unit, value = ingredient.Unit, ingredient.Unit, quantity
+		}
+	}
+
+	group := f.group(kind, unit)
+	group.value += value
+	if dish != "" && !slices.Contains(group.recipes, dish) {
+		group.recipes = append(group.recipes, dish)
+	}
+}
+
+func (f *shoppingFood) group(kind string, unit string) *shoppingGroup {
+	if group := f.find(kind); group != nil {
+		return group
+	}
+	group := &shoppingGroup{kind: kind, unit: unit, recipes: []string{}}
+	f.groups = append(f.groups, group)
+	return group
+}
+
+// fold weighs volumes and pieces into the weight needed when the food tells how,
+// and drops ingredients without an amount once an amount is needed anyway
+func (f *shoppingFood) fold() {
+	weight := f.find(amountWeight)
+	if weight != nil && f.food != nil {
+		if volume := f.find(amountVolume); volume != nil && f.food.Density != nil {
+			weight.merge(volume, volume.value**f.food.Density)
+			f.remove(volume)
+		}
+		if count := f.find(amountCount); count != nil && f.food.Unit_weight != nil {
+			weight.merge(count, count.value**f.food.Unit_weight)
+			f.remove(count)
+		}
+	}
+	if none := f.find(amountNone); none != nil && len(f.groups) > 1 {
+		f.remove(none)
+		f.groups[0].merge(none, 0)
+	}
+}
+
+func (f *shoppingFood) find(kind string) *shoppingGroup {
+	for _, group := range f.groups {
+		if group.kind == kind {
+			return group
+		}
+	}
+	return nil
+}
+
+func (f *shoppingFood) remove(target *shoppingGroup) {
+	for i, group := range f.groups {
+		if group == target {
+			f.groups = append(f.groups[:i], f.groups[i+1:]...)
+			return
+		}
+	}
+}
+
+// merge adds another group, its amount already converted, to the group
+func (g *shoppingGroup) merge(other *shoppingGroup, value float64) {
+	g.value += value
+	for _, dish := range other.recipes {
+		if !slices.Contains(g.recipes, dish) {
+			g.recipes = append(g.recipes, dish)
+		}
+	}
+}
+
+// amount writes the added up amount in the units of system
+func (g *shoppingGroup) amount(system string) (float64, string) {
+	switch g.kind {
+	case amountWeight:
+		return weightIn(system, g.value)
+	case amountVolume:
+		return volumeIn(system, g.value)
+	}
+	return math.Ceil(roundTo(g.value, 3)), g.unit
+}
+
+// shoppingAisles returns the aisles in store order, unknown ones before other
+func shoppingAisles(byAisle map[string][]ShoppingItem) []string {
+	var known, unknown []string
+	for _, aisle := range Aisles {
+		if _, ok := byAisle[aisle]; ok && aisle != AisleOther {
+			known = append(known, aisle)
+		}
+	}
+	for aisle := range byAisle {
+		if !slices.Contains(Aisles, aisle) {
+			unknown = append(unknown, aisle)
+		}
+	}
+	sort.Strings(unknown)
+	aisles := append(known, unknown...)
+	if _, ok := byAisle[AisleOther]; ok {
+		aisles = append(aisles, AisleOther)
+	}
+	return aisles
+}
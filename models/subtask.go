@@ -0,0 +1,269 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxSubtaskDepth is how deep subtasks may nest below their todo
+const MaxSubtaskDepth = 3
+
+var (
+	ErrSubtaskNotFound = errors.New("subtask not found")
+	ErrSubtaskTooDeep  = errors.New("subtasks cannot nest deeper than 3 levels")
+	ErrSubtaskOrder    = errors.New("order must list every sibling subtask exactly once")
+)
+
+// Subtask is a checklist item inside a todo. It has its own status and position
+// among its siblings, and may carry subtasks of its own.
+type Subtask struct {
+	ID           primitive.ObjectID `json:"_id" bson:"_id"`
+	Title        string             `json:"title" validate:"required,max=500"`
+	Status       TodoStatus         `json:"status"`
+	Order        int                `json:"order"`
+	Completed_at *time.Time         `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+	Subtasks     []Subtask          `json:"subtasks,omitempty" bson:"subtasks,omitempty" validate:"omitempty,dive"`
+	Progress     *Progress          `json:"progress,omitempty" bson:"-"`
+}
+
+// Progress counts finished subtasks at every level below a todo or subtask
+type Progress struct {
+	Done    int `json:"done"`
+	Total   int `json:"total"`
+	Percent int `json:"percent"`
+}
+
+// NormalizeSubtasks prepares subtasks sent along with a new todo: every subtask gets
+// a fresh id, an open status unless it is done, and its position as order
+func NormalizeSubtasks(subtasks []Subtask, depth int, now time.Time) error {
+	if len(subtasks) > 0 && depth > MaxSubtaskDepth {
+		return ErrSubtaskTooDeep
+	}
+	for i := range subtasks {
+		s := &subtasks[i]
+		s.ID = primitive.NewObjectID()
+		s.Order = i
+		s.Progress = nil
+		s.Completed_at = nil
+		switch s.Status {
+		case StatusDone:
+			completed := now
+			s.Completed_at = &completed
+		case StatusInProgress:
+		default:
+			s.Status = StatusOpen
+		}
+		if err := NormalizeSubtasks(s.Subtasks, depth+1, now); err != nil {
+			return err
+		}
+		if s.Status == StatusDone {
+			CompleteSubtasks(s.Subtasks, now)
+		}
+	}
+	return nil
+}
+
+// FindSubtask returns the subtask with the given id anywhere in the tree and the
+// depth it sits at, 1 being a direct child of the todo
+func FindSubtask(subtasks []Subtask, id primitive.ObjectID) (*Subtask, int) {
+	for i := range subtasks {
+		if subtasks[i].ID == id {
+			return &subtasks[i], 1
+		}
+		if found, depth := FindSubtask(subtasks[i].Subtasks, id); found != nil {
+			return found, depth + 1
+		}
+	}
+	return nil, 0
+}
+
+// AddSubtask appends a subtask below parent, or at the top level when parent is nil
+func AddSubtask(subtasks *[]Subtask, parent *primitive.ObjectID, title string) (*Subtask, error) {
+	siblings := subtasks
+	if parent != nil {
+		found, depth := FindSubtask(*subtasks, *parent)
+		if found == nil {
+			return nil, ErrSubtaskNotFound
+		}
+		if depth >= MaxSubtaskDepth {
+			return nil, ErrSubtaskTooDeep
+		}
+		siblings = &found.Subtasks
+	}
+
+	order := 0
+	for _, s := range *siblings {
+		if s.Order >= order {
+			order = s.Order + 1
+		}
+	}
+
+	*siblings = append(*siblings, Subtask{
+		ID:     primitive.NewObjectID(),
+		Title:  title,
+		Status: StatusOpen,
+		Order:  order,
+	})
+	return &(*siblings)[len(*siblings)-1], nil
+}
+
+// RemoveSubtask deletes a subtask together with everything nested below it
+func RemoveSubtask(subtasks *[]Subtask, id primitive.ObjectID) bool {
+	for i, s := range *subtasks {
+		if s.ID == id {
+			*subtasks = append((*subtasks)[:i], (*subtasks)[i+1:]...)
+			return true
+		}
+		if RemoveSubtask(&(*subtasks)[i].Subtasks, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReorderSubtasks gives the children of parent (or the top level when parent is nil)
+// the order of ids, which must list every one of them exactly once
+func ReorderSubtasks(subtasks []Subtask, parent *primitive.ObjectID, ids []primitive.ObjectID) error {
+	siblings := subtasks
+	if parent != nil {
+		found, _ := FindSubtask(subtasks, *parent)
+		if found == nil {
+			return ErrSubtaskNotFound
+		}
+		siblings = found.Subtasks
+	}
+
+	if len(ids) != len(siblings) {
+		return ErrSubtaskOrder
+	}
+	position := map[primitive.ObjectID]int{}
+	for i, id := range ids {
+		position[id] = i
+	}
+	for i := range siblings {
+		order, ok := position[siblings[i].ID]
+		if !ok {
+			return ErrSubtaskOrder
+		}
+		siblings[i].Order = order
+	}
+	sortSubtasks(siblings)
+	return nil
+}
+
+// SetSubtaskStatus changes the status of a subtask. Finishing a subtask finishes
+// everything below it; reopening one reopens the finished subtasks above it so a
+// done subtask never hides open work.
+func SetSubtaskStatus(subtasks []Subtask, id primitive.ObjectID, status TodoStatus, now time.Time) error {
+	path := subtaskPath(subtasks, id)
+	if path == nil {
+		return ErrSubtaskNotFound
+	}
+
+	target := path[len(path)-1]
+	if status == StatusDone {
+		completeSubtask(target, now)
+		return nil
+	}
+
+	target.Status = status
+	target.Completed_at = nil
+	for _, ancestor := range path[:len(path)-1] {
+		if ancestor.Status == StatusDone {
+			ancestor.Status = StatusOpen
+			ancestor.Completed_at = nil
+		}
+	}
+	return nil
+}
+
+// CompleteSubtasks marks every subtask in the tree done
+func CompleteSubtasks(subtasks []Subtask, now time.Time) {
+	for i := range subtasks {
+		completeSubtask(&subtasks[i], now)
+	}
+}
+
+func completeSubtask(subtask *Subtask, now time.Time) {
+	if subtask.Status != StatusDone {
+		subtask.Status = StatusDone
+		completed := now
+		subtask.Completed_at = &completed
+	}
+	CompleteSubtasks(subtask.Subtasks, now)
+}
+
+// subtaskPath returns the subtasks from the top level down to the one with id
+func subtaskPath(subtasks []Subtask, id primitive.ObjectID) []*Subtask {
+	for i := range subtasks {
+		if subtasks[i].ID == id {
+			return []*Subtask{&subtasks[i]}
+		}
+		if path := subtaskPath(subtasks[i].Subtasks, id); path != nil {
+			return append([]*Subtask{&subtasks[i]}, path...)
+		}
+	}
+	return nil
+}
+
+// RollUp sorts the subtask tree by order and fills in the progress of the todo and
+// of every subtask that has children
+func (t *ToDoList) RollUp() {
+	t.Progress = rollUp(t.Subtasks)
+}
+
+func rollUp(subtasks []Subtask) *Progress {
+	if len(subtasks) == 0 {
+		return nil
+	}
+	sortSubtasks(subtasks)
+
+	progress := &Progress{}
+	for i := range subtasks {
+		progress.Total++
+		if subtasks[i].Status == StatusDone {
+			progress.Done++
+		}
+		if child := rollUp(subtasks[i].Subtasks); child != nil {
+			subtasks[i].Progress = child
+			progress.Done += child.Done
+			progress.Total += child.Total
+		}
+	}
+	progress.Percent = progress.Done * 100 / progress.Total
+	return progress
+}
+
+func sortSubtasks(subtasks []Subtask) {
+	sort.SliceStable(subtasks, func(i, j int) bool { return subtasks[i].Order < subtasks[j].Order })
+}
+
+// ResetSubtasks copies a subtask tree with fresh ids and every subtask open, as
+// the checklist of the next occurrence of a repeating todo
+func ResetSubtasks(subtasks []Subtask) []Subtask {
+	reset := CloneSubtasks(subtasks)
+	for i := range reset {
+		reset[i].ID = primitive.NewObjectID()
+		reset[i].Status = StatusOpen
+		reset[i].Completed_at = nil
+		reset[i].Progress = nil
+		reset[i].Subtasks = ResetSubtasks(reset[i].Subtasks)
+	}
+	return reset
+}
+
+// CloneSubtasks deep copies a subtask tree
+func CloneSubtasks(subtasks []Subtask) []Subtask {
+	if subtasks == nil {
+		return nil
+	}
+	clone := make([]Subtask, len(subtasks))
+	for i, s := range subtasks {
+		clone[i] = s
+		clone[i].Subtasks = CloneSubtasks(s.Subtasks)
+	}
+	return clone
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Priority ranks todos. It is stored as a number so MongoDB can sort on it,
+// and exchanged with clients by name.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+func (p Priority) String() string {
+	if p < PriorityNone || p > PriorityUrgent {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// ParsePriority accepts a priority name, case insensitive
+func ParsePriority(name string) (Priority, error) {
+	for i, n := range priorityNames {
+		if strings.EqualFold(n, name) {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNone, fmt.Errorf("unknown priority %q, expected one of %s", name, strings.Join(priorityNames, ", "))
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+// UnmarshalJSON accepts either a priority name or its level
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var level int
+	if err := json.Unmarshal(data, &level); err == nil {
+		if level < int(PriorityNone) || level > int(PriorityUrgent) {
+			return fmt.Errorf("priority level %d out of range", level)
+		}
+		*p = Priority(level)
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("priority must be a name or a level")
+	}
+	parsed, err := ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// TodoStatus is the lifecycle state of a todo
+type TodoStatus string
+
+const (
+	StatusOpen       TodoStatus = "open"
+	StatusInProgress TodoStatus = "in_progress"
+	StatusDone       TodoStatus = "done"
+	StatusArchived   TodoStatus = "archived"
+)
+
+// todoTransitions lists, for every state, the states a todo may move to next
+var todoTransitions = map[TodoStatus][]TodoStatus{
+	StatusOpen:       {StatusInProgress, StatusDone, StatusArchived},
+	StatusInProgress: {StatusOpen, StatusDone, StatusArchived},
+	StatusDone:       {StatusOpen, StatusArchived},
+	StatusArchived:   {StatusOpen},
+}
+
+// ParseTodoStatus accepts a status name. The legacy boolean spellings "true" and
+// "false" map to done and open.
+func ParseTodoStatus(name string) (TodoStatus, error) {
+	switch strings.ToLower(name) {
+	case "true":
+		return StatusDone, nil
+	case "false":
+		return StatusOpen, nil
+	}
+	status := TodoStatus(strings.ToLower(name))
+	if !status.Valid() {
+		return "", fmt.Errorf("unknown status %q, expected one of open, in_progress, done, archived", name)
+	}
+	return status, nil
+}
+
+// Valid reports whether s is one of the known states
+func (s TodoStatus) Valid() bool {
+	_, ok := todoTransitions[s]
+	return ok
+}
+
+// Closed reports whether the todo needs no more work
+func (s TodoStatus) Closed() bool {
+	return s == StatusDone || s == StatusArchived
+}
+
+// CanTransitionTo reports whether a todo in state s may move to next
+func (s TodoStatus) CanTransitionTo(next TodoStatus) bool {
+	for _, allowed := range todoTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionSources returns every state from which a todo may move to next
+func TransitionSources(next TodoStatus) []TodoStatus {
+	var sources []TodoStatus
+	for _, from := range []TodoStatus{StatusOpen, StatusInProgress, StatusDone, StatusArchived} {
+		if from.CanTransitionTo(next) {
+			sources = append(sources, from)
+		}
+	}
+	return sources
+}
+
+// UnmarshalJSON accepts a status name, or the boolean older clients send
+func (s *TodoStatus) UnmarshalJSON(data []byte) error {
+	var done bool
+	if err := json.Unmarshal(data, &done); err == nil {
+		*s = StatusOpen
+		if done {
+			*s = StatusDone
+		}
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("status must be a string")
+	}
+	parsed, err := ParseTodoStatus(name)
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// UnmarshalBSONValue reads the status of documents written before the lifecycle
+// existed, when it was a boolean, as well as the current string form
+func (s *TodoStatus) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Boolean:
+		*s = StatusOpen
+		if value.Boolean() {
+			*s = StatusDone
+		}
+	case bsontype.String:
+		*s = TodoStatus(value.StringValue())
+	case bsontype.Null, bsontype.Undefined:
+		*s = StatusOpen
+	default:
+		return fmt.Errorf("cannot decode status from %v", t)
+	}
+	return nil
+}
+
+// TransitionError reports a status change the lifecycle does not allow
+type TransitionError struct {
+	From TodoStatus
+	To   TodoStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move a task from %s to %s", e.From, e.To)
+}
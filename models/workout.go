@@ -0,0 +1,195 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Record metrics tracked per exercise
+const (
+	RecordWeight = "weight"
+	RecordE1RM   = "e1rm"
+	RecordVolume = "volume"
+)
+
+// Workout is one logged training session. Weights are in kilograms.
+type Workout struct {
+	ID           primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name         string              `json:"name,omitempty" bson:"name,omitempty" validate:"max=100"`
+	Performed_at time.Time           `json:"performed_at" validate:"required"`
+	Duration     int                 `json:"duration_minutes,omitempty" bson:"duration_minutes,omitempty" validate:"min=0,max=1440"`
+	Notes        string              `json:"notes,omitempty" bson:"notes,omitempty" validate:"max=2000"`
+	Plan_id      *primitive.ObjectID `json:"plan_id,omitempty" bson:"plan_id,omitempty"`
+	Exercises    []WorkoutExercise   `json:"exercises" validate:"required,min=1,max=50,dive"`
+	Records      []PersonalRecord    `json:"records,omitempty" bson:"-"`
+	Progression  []LiftAdjustment    `json:"progression,omitempty" bson:"-"`
+	Created_at   time.Time           `json:"created_at"`
+	Updated_at   time.Time           `json:"updated_at"`
+	User_id      string              `json:"user_id"`
+}
+
+// WorkoutExercise is an exercise performed during a workout, preferably one of the
+// catalog referenced by Exercise_id. Key identifies the exercise across workouts:
+// the catalog id, or the name for exercises logged by name only. The totals are
+// derived from the working sets on save.
+type WorkoutExercise struct {
+	Key         string              `json:"key"`
+	Exercise_id *primitive.ObjectID `json:"exercise_id,omitempty" bson:"exercise_id,omitempty"`
+	Name        string              `json:"name" validate:"required_without=Exercise_id,max=100"`
+	Notes       string              `json:"notes,omitempty" bson:"notes,omitempty" validate:"max=500"`
+	Sets        []WorkoutSet        `json:"sets" validate:"required,min=1,max=50,dive"`
+	Top_weight  float64             `json:"top_weight"`
+	Best_e1rm   float64             `json:"best_e1rm"`
+	Volume      float64             `json:"volume"`
+}
+
+// WorkoutSet is a single set. Warm-up sets are logged but never count towards
+// totals or records.
+type WorkoutSet struct {
+	Reps         int      `json:"reps" validate:"min=0,max=1000"`
+	Weight       float64  `json:"weight" validate:"min=0,max=2000"`
+	Rpe          *float64 `json:"rpe,omitempty" bson:"rpe,omitempty" validate:"omitempty,min=1,max=10"`
+	Rest_seconds int      `json:"rest_seconds,omitempty" bson:"rest_seconds,omitempty" validate:"min=0,max=3600"`
+	Warmup       bool     `json:"warmup,omitempty" bson:"warmup,omitempty"`
+}
+
+// PersonalRecord reports a metric of an exercise that beat every earlier workout.
+// Previous is nil the first time an exercise is logged.
+type PersonalRecord struct {
+	Key      string   `json:"key"`
+	Exercise string   `json:"exercise"`
+	Metric   string   `json:"metric"`
+	Value    float64  `json:"value"`
+	Previous *float64 `json:"previous"`
+}
+
+// ExerciseRecords holds the all-time bests of one exercise
+type ExerciseRecords struct {
+	Key         string      `json:"key" bson:"_id"`
+	Exercise    string      `json:"exercise"`
+	Best_weight RecordValue `json:"best_weight"`
+	Best_e1rm   RecordValue `json:"best_e1rm"`
+	Best_volume RecordValue `json:"best_volume"`
+}
+
+// RecordValue is a best value and the workout it was set in
+type RecordValue struct {
+	Value       float64            `json:"value"`
+	Workout_id  primitive.ObjectID `json:"workout_id"`
+	Achieved_at time.Time          `json:"achieved_at"`
+}
+
+// ExerciseKey identifies an exercise by its name, ignoring case and spacing
+func ExerciseKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// EstimatedOneRepMax applies the Epley formula. A single is its own one rep max.
+func EstimatedOneRepMax(weight float64, reps int) float64 {
+	if reps <= 0 || weight <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+	return roundTo(weight*(1+float64(reps)/30), 2)
+}
+
+// Summarize fills in the key and totals of every exercise of the workout
+func (w *Workout) Summarize() {
+	for i := range w.Exercises {
+		e := &w.Exercises[i]
+		e.Name = strings.TrimSpace(e.Name)
+		if e.Exercise_id != nil {
+			e.Key = e.Exercise_id.Hex()
+		} else {
+			e.Key = ExerciseKey(e.Name)
+		}
+		e.Top_weight, e.Best_e1rm, e.Volume = 0, 0, 0
+		for _, set := range e.Sets {
+			if set.Warmup || set.Reps == 0 {
+				continue
+			}
+			e.Top_weight = math.Max(e.Top_weight, set.Weight)
+			e.Best_e1rm = math.Max(e.Best_e1rm, EstimatedOneRepMax(set.Weight, set.Reps))
+			e.Volume += set.Weight * float64(set.Reps)
+		}
+		e.Volume = roundTo(e.Volume, 2)
+	}
+}
+
+// Keys returns the distinct exercise keys of a summarized workout
+func (w *Workout) Keys() []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, e := range w.Exercises {
+		if !seen[e.Key] {
+			seen[e.Key] = true
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+// DetectRecords compares a summarized workout with the bests of earlier workouts.
+// An exercise logged twice in one workout counts with its best entry.
+func (w *Workout) DetectRecords(earlier []ExerciseRecords) []PersonalRecord {
+	bests := map[string]ExerciseRecords{}
+	for _, r := range earlier {
+		bests[r.Key] = r
+	}
+
+	records := []PersonalRecord{}
+	seen := map[string]bool{}
+	for _, e := range w.Exercises {
+		if seen[e.Key] {
+			continue
+		}
+		seen[e.Key] = true
+		current := w.exerciseBests(e.Key)
+
+		previous, ok := bests[e.Key]
+		for _, metric := range []struct {
+			name     string
+			value    float64
+			previous float64
+		}{
+			{RecordWeight, current.Top_weight, previous.Best_weight.Value},
+			{RecordE1RM, current.Best_e1rm, previous.Best_e1rm.Value},
+			{RecordVolume, current.Volume, previous.Best_volume.Value},
+		} {
+			if metric.value <= 0 || (ok && metric.value <= metric.previous) {
+				continue
+			}
+			record := PersonalRecord{Key: e.Key, Exercise: e.Name, Metric: metric.name, Value: metric.value}
+			if ok {
+				prev := metric.previous
+				record.Previous = &prev
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// exerciseBests merges every entry of an exercise within the workout
+func (w *Workout) exerciseBests(key string) WorkoutExercise {
+	var best WorkoutExercise
+	for _, e := range w.Exercises {
+		if e.Key != key {
+			continue
+		}
+		best.Top_weight = math.Max(best.Top_weight, e.Top_weight)
+		best.Best_e1rm = math.Max(best.Best_e1rm, e.Best_e1rm)
+		best.Volume += e.Volume
+	}
+	return best
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type bodyRepository struct {
+	mu      sync.RWMutex
+	metrics collection[models.BodyMetric]
+}
+
+// NewBodyRepository returns an empty in-memory BodyRepository
+func NewBodyRepository() repository.BodyRepository {
+	return &bodyRepository{metrics: newCollection[models.BodyMetric]()}
+}
+
+func (r *bodyRepository) List(ctx context.Context, uid string, from *time.Time, to *time.Time) ([]models.BodyMetric, error) {
+	r.mu.RLock()
+	metrics := r.metrics.filter(func(m models.BodyMetric) bool {
+		return m.User_id == uid && inRange(m.Measured_at, from, to)
+	})
+	r.mu.RUnlock()
+
+	models.SortBodyMetrics(metrics)
+	return metrics, nil
+}
+
+// owned returns the measurement with the given id if uid owns it
+func (r *bodyRepository) owned(uid string, id string) (models.BodyMetric, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.BodyMetric{}, err
+	}
+	metric, ok := r.metrics.docs[objID]
+	if !ok || metric.User_id != uid {
+		return models.BodyMetric{}, repository.ErrNotFound
+	}
+	return metric, nil
+}
+
+func (r *bodyRepository) Get(ctx context.Context, uid string, id string) (*models.BodyMetric, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	metric, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return &metric, nil
+}
+
+func (r *bodyRepository) Create(ctx context.Context, metric *models.BodyMetric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if metric.ID.IsZero() {
+		metric.ID = primitive.NewObjectID()
+	}
+	stored := *metric
+	stored.Averages = nil
+	r.metrics.insert(stored.ID, stored)
+	return nil
+}
+
+func (r *bodyRepository) Update(ctx context.Context, uid string, id string, body models.BodyMetric) (*models.BodyMetric, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metric, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	metric.Measured_at = body.Measured_at
+	metric.Weight = body.Weight
+	metric.Body_fat = body.Body_fat
+	metric.Waist = body.Waist
+	metric.Chest = body.Chest
+	metric.Arm = body.Arm
+	metric.Notes = body.Notes
+	metric.Updated_at = body.Updated_at
+	r.metrics.insert(metric.ID, metric)
+	return &metric, nil
+}
+
+func (r *bodyRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metric, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.metrics.remove(metric.ID)
+	return nil
+}
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type diaryRepository struct {
+	mu      sync.RWMutex
+	entries collection[models.FoodEntry]
+}
+
+// NewDiaryRepository returns an empty in-memory DiaryRepository
+func NewDiaryRepository() repository.DiaryRepository {
+	return &diaryRepository{entries: newCollection[models.FoodEntry]()}
+}
+
+func (r *diaryRepository) List(ctx context.Context, uid string, from string, to string) ([]models.FoodEntry, error) {
+	r.mu.RLock()
+	entries := r.entries.filter(func(e models.FoodEntry) bool {
+		return e.User_id == uid && e.Date >= from && e.Date <= to
+	})
+	r.mu.RUnlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		if !entries[i].Created_at.Equal(entries[j].Created_at) {
+			return entries[i].Created_at.Before(entries[j].Created_at)
+		}
+		return entries[i].ID.Hex() < entries[j].ID.Hex()
+	})
+	return entries, nil
+}
+
+// owned returns the entry with the given id if uid owns it
+func (r *diaryRepository) owned(uid string, id string) (models.FoodEntry, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.FoodEntry{}, err
+	}
+	entry, ok := r.entries.docs[objID]
+	if !ok || entry.User_id != uid {
+		return models.FoodEntry{}, repository.ErrNotFound
+	}
+	return entry, nil
+}
+
+func (r *diaryRepository) Get(ctx context.Context, uid string, id string) (*models.FoodEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *diaryRepository) Create(ctx context.Context, entry *models.FoodEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	stored := *entry
+	stored.Total = models.Nutrients{}
+	r.entries.insert(stored.ID, stored)
+	return nil
+}
+
+func (r *diaryRepository) Update(ctx context.Context, uid string, id string, body models.FoodEntry) (*models.FoodEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	entry.Date = body.Date
+	entry.Meal = body.Meal
+	entry.Recipe_id = body.Recipe_id
+	entry.Name = body.Name
+	entry.Servings = body.Servings
+	entry.Per_serving = body.Per_serving
+	entry.Updated_at = body.Updated_at
+	r.entries.insert(entry.ID, entry)
+	return &entry, nil
+}
+
+func (r *diaryRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.entries.remove(entry.ID)
+	return nil
+}
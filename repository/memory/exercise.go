@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exerciseRepository struct {
+	mu        sync.RWMutex
+	exercises collection[models.Exercise]
+}
+
+// NewExerciseRepository returns an in-memory ExerciseRepository holding the
+// built-in catalog
+func NewExerciseRepository() repository.ExerciseRepository {
+	r := &exerciseRepository{exercises: newCollection[models.Exercise]()}
+	now := time.Now()
+	for _, exercise := range models.ExerciseCatalog() {
+		exercise.Created_at = now
+		exercise.Updated_at = now
+		r.exercises.insert(exercise.ID, exercise)
+	}
+	return r
+}
+
+func visible(e models.Exercise, uid string) bool {
+	return e.User_id == "" || e.User_id == uid
+}
+
+func (r *exerciseRepository) List(ctx context.Context, uid string, query repository.ExerciseQuery) ([]models.Exercise, error) {
+	text := models.ExerciseKey(query.Text)
+
+	r.mu.RLock()
+	exercises := r.exercises.filter(func(e models.Exercise) bool {
+		return visible(e, uid) &&
+			strings.Contains(e.Key, text) &&
+			(query.Muscle == "" || e.Trains(query.Muscle)) &&
+			(query.Equipment == "" || e.Equipment == query.Equipment) &&
+			(query.Pattern == "" || e.Pattern == query.Pattern)
+	})
+	r.mu.RUnlock()
+
+	sort.SliceStable(exercises, func(i, j int) bool {
+		if exercises[i].Key != exercises[j].Key {
+			return exercises[i].Key < exercises[j].Key
+		}
+		return exercises[i].User_id < exercises[j].User_id
+	})
+	return exercises, nil
+}
+
+func (r *exerciseRepository) Get(ctx context.Context, uid string, id string) (*models.Exercise, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exercise, ok := r.exercises.docs[objID]
+	if !ok || !visible(exercise, uid) {
+		return nil, repository.ErrNotFound
+	}
+	return &exercise, nil
+}
+
+func (r *exerciseRepository) Find(ctx context.Context, uid string, ids []primitive.ObjectID) ([]models.Exercise, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	exercises := []models.Exercise{}
+	for _, id := range ids {
+		if exercise, ok := r.exercises.docs[id]; ok && visible(exercise, uid) {
+			exercises = append(exercises, exercise)
+		}
+	}
+	return exercises, nil
+}
+
+// taken reports whether uid can already see an exercise with key other than id
+func (r *exerciseRepository) taken(uid string, key string, id primitive.ObjectID) bool {
+	return len(r.exercises.filter(func(e models.Exercise) bool {
+		return visible(e, uid) && e.Key == key && e.ID != id
+	})) > 0
+}
+
+func (r *exerciseRepository) Create(ctx context.Context, exercise *models.Exercise) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if exercise.ID.IsZero() {
+		exercise.ID = primitive.NewObjectID()
+	}
+	if r.taken(exercise.User_id, exercise.Key, exercise.ID) {
+		return repository.ErrDuplicate
+	}
+	r.exercises.insert(exercise.ID, *exercise)
+	return nil
+}
+
+func (r *exerciseRepository) Update(ctx context.Context, uid string, id string, body models.Exercise) (*models.Exercise, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exercise, ok := r.exercises.docs[objID]
+	if !ok || exercise.User_id != uid {
+		return nil, repository.ErrNotFound
+	}
+	if r.taken(uid, body.Key, exercise.ID) {
+		return nil, repository.ErrDuplicate
+	}
+	exercise.Name = body.Name
+	exercise.Key = body.Key
+	exercise.Primary_muscles = body.Primary_muscles
+	exercise.Secondary_muscles = body.Secondary_muscles
+	exercise.Equipment = body.Equipment
+	exercise.Pattern = body.Pattern
+	exercise.Updated_at = body.Updated_at
+	r.exercises.insert(exercise.ID, exercise)
+	return &exercise, nil
+}
+
+func (r *exerciseRepository) Delete(ctx context.Context, uid string, id string) error {
+	objID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exercise, ok := r.exercises.docs[objID]
+	if !ok || exercise.User_id != uid {
+		return repository.ErrNotFound
+	}
+	r.exercises.remove(exercise.ID)
+	return nil
+}
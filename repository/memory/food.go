@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+type foodRepository struct {
+	foods []models.Food
+}
+
+// NewFoodRepository returns an in-memory FoodRepository holding the built-in
+// food database
+func NewFoodRepository() repository.FoodRepository {
+	return &foodRepository{foods: models.FoodCatalog()}
+}
+
+func (r *foodRepository) Search(ctx context.Context, text string, limit int) ([]models.Food, error) {
+	key := models.FoodKey(text)
+	foods := []models.Food{}
+	for _, food := range r.foods {
+		for _, k := range food.Keys {
+			if strings.Contains(k, key) {
+				foods = append(foods, food)
+				break
+			}
+		}
+	}
+	sort.SliceStable(foods, func(i, j int) bool { return foods[i].Name < foods[j].Name })
+	if len(foods) > limit {
+		foods = foods[:limit]
+	}
+	return foods, nil
+}
+
+func (r *foodRepository) Match(ctx context.Context, items []string) (map[string]models.Food, error) {
+	matches := map[string]models.Food{}
+	for _, item := range items {
+		if food, ok := models.MatchFood(item, r.foods); ok {
+			matches[item] = food
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type gymRepository struct {
+	mu    sync.RWMutex
+	plans collection[models.Gym]
+}
+
+// NewGymRepository returns an empty in-memory GymRepository
+func NewGymRepository() repository.GymRepository {
+	return &gymRepository{plans: newCollection[models.Gym]()}
+}
+
+func (r *gymRepository) List(ctx context.Context, uid string) ([]models.Gym, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	plans := r.plans.filter(func(p models.Gym) bool { return p.User_id == uid })
+	for i := range plans {
+		plans[i] = cloneGym(plans[i])
+	}
+	return plans, nil
+}
+
+// cloneGym copies a plan deep enough that changing its exercises or program
+// leaves the stored plan alone
+func cloneGym(plan models.Gym) models.Gym {
+	plan.Exercises = slices.Clone(plan.Exercises)
+	if plan.Program != nil {
+		program := *plan.Program
+		program.Lifts = slices.Clone(program.Lifts)
+		plan.Program = &program
+	}
+	return plan
+}
+
+// owned returns the plan with the given id if uid owns it
+func (r *gymRepository) owned(uid string, id string) (models.Gym, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.Gym{}, err
+	}
+	plan, ok := r.plans.docs[objID]
+	if !ok || plan.User_id != uid {
+		return models.Gym{}, repository.ErrNotFound
+	}
+	return cloneGym(plan), nil
+}
+
+func (r *gymRepository) Get(ctx context.Context, uid string, id string) (*models.Gym, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	plan, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *gymRepository) Active(ctx context.Context, uid string) (*models.Gym, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	active := r.plans.filter(func(p models.Gym) bool { return p.User_id == uid && p.Active })
+	if len(active) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	plan := cloneGym(active[0])
+	return &plan, nil
+}
+
+func (r *gymRepository) Create(ctx context.Context, plan *models.Gym) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !plan.Active {
+		plan.Active = len(r.plans.filter(func(p models.Gym) bool { return p.User_id == plan.User_id })) == 0
+	}
+	if plan.Active {
+		r.deactivateAll(plan.User_id, time.Now())
+	}
+	if plan.ID.IsZero() {
+		plan.ID = primitive.NewObjectID()
+	}
+	r.plans.insert(plan.ID, *plan)
+	return nil
+}
+
+func (r *gymRepository) Update(ctx context.Context, uid string, id string, body models.Gym) (*models.Gym, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plan, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	plan.Name = body.Name
+	plan.Monday = body.Monday
+	plan.Tuesday = body.Tuesday
+	plan.Wednesday = body.Wednesday
+	plan.Thursday = body.Thursday
+	plan.Friday = body.Friday
+	plan.Saturday = body.Saturday
+	plan.Sunday = body.Sunday
+	plan.Exercises = body.Exercises
+	plan.Program = body.Program
+	plan.Revision++
+	plan.Updated_at = time.Now()
+	r.plans.insert(plan.ID, plan)
+	return &plan, nil
+}
+
+func (r *gymRepository) UpdateProgram(ctx context.Context, uid string, id string, fn func(plan *models.Gym) bool) (*models.Gym, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plan, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if !fn(&plan) {
+		return &plan, nil
+	}
+	plan.Revision++
+	plan.Updated_at = time.Now()
+	r.plans.insert(plan.ID, plan)
+	return &plan, nil
+}
+
+func (r *gymRepository) Activate(ctx context.Context, uid string, id string) (*models.Gym, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plan, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	r.deactivateAll(uid, now)
+	plan.Active = true
+	plan.Updated_at = now
+	r.plans.insert(plan.ID, plan)
+	return &plan, nil
+}
+
+func (r *gymRepository) deactivateAll(uid string, now time.Time) {
+	for _, plan := range r.plans.filter(func(p models.Gym) bool { return p.User_id == uid && p.Active }) {
+		plan.Active = false
+		plan.Updated_at = now
+		r.plans.insert(plan.ID, plan)
+	}
+}
+
+func (r *gymRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	plan, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.plans.remove(plan.ID)
+	return nil
+}
@@ -0,0 +1,76 @@
+// Package memory provides thread-safe in-memory repositories so the HTTP API
+// can be exercised without a MongoDB instance.
+package memory
+
+import (
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// New returns empty in-memory repositories
+func New() repository.Repositories {
+	exercises := NewExerciseRepository()
+	return repository.Repositories{
+		Todos:     NewTodoRepository(),
+		Projects:  NewProjectRepository(),
+		Recipes:   NewRecipeRepository(),
+		Users:     NewUserRepository(),
+		Gym:       NewGymRepository(),
+		Workouts:  NewWorkoutRepository(exercises),
+		Exercises: exercises,
+		Body:      NewBodyRepository(),
+		Foods:     NewFoodRepository(),
+		Diary:     NewDiaryRepository(),
+	}
+}
+
+// parseID turns a hex id into an ObjectID, treating malformed ids as missing documents
+func parseID(id string) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, repository.ErrNotFound
+	}
+	return objID, nil
+}
+
+// collection keeps documents in insertion order, which is what MongoDB returns
+// for an unsorted find on a collection that is only appended to
+type collection[T any] struct {
+	order []primitive.ObjectID
+	docs  map[primitive.ObjectID]T
+}
+
+func newCollection[T any]() collection[T] {
+	return collection[T]{docs: map[primitive.ObjectID]T{}}
+}
+
+func (c *collection[T]) insert(id primitive.ObjectID, doc T) {
+	if _, ok := c.docs[id]; !ok {
+		c.order = append(c.order, id)
+	}
+	c.docs[id] = doc
+}
+
+func (c *collection[T]) remove(id primitive.ObjectID) {
+	if _, ok := c.docs[id]; !ok {
+		return
+	}
+	delete(c.docs, id)
+	for i, o := range c.order {
+		if o == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// filter returns every document for which keep is true, in insertion order
+func (c *collection[T]) filter(keep func(T) bool) []T {
+	results := []T{}
+	for _, id := range c.order {
+		if doc := c.docs[id]; keep(doc) {
+			results = append(results, doc)
+		}
+	}
+	return results
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type projectRepository struct {
+	mu       sync.RWMutex
+	projects collection[models.Project]
+}
+
+// NewProjectRepository returns an empty in-memory ProjectRepository
+func NewProjectRepository() repository.ProjectRepository {
+	return &projectRepository{projects: newCollection[models.Project]()}
+}
+
+func (r *projectRepository) List(ctx context.Context, uid string) ([]models.Project, error) {
+	r.mu.RLock()
+	projects := r.projects.filter(func(p models.Project) bool { return p.User_id == uid })
+	r.mu.RUnlock()
+
+	sort.SliceStable(projects, func(i, j int) bool { return projects[i].Order < projects[j].Order })
+	return projects, nil
+}
+
+// owned returns the project with the given id if uid owns it
+func (r *projectRepository) owned(uid string, id string) (models.Project, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.Project{}, err
+	}
+	project, ok := r.projects.docs[objID]
+	if !ok || project.User_id != uid {
+		return models.Project{}, repository.ErrNotFound
+	}
+	return project, nil
+}
+
+func (r *projectRepository) Get(ctx context.Context, uid string, id string) (*models.Project, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	project, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *projectRepository) Create(ctx context.Context, project *models.Project) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project.Order = 0
+	for _, p := range r.projects.filter(func(p models.Project) bool { return p.User_id == project.User_id }) {
+		if p.Order >= project.Order {
+			project.Order = p.Order + 1
+		}
+	}
+	if project.ID.IsZero() {
+		project.ID = primitive.NewObjectID()
+	}
+	r.projects.insert(project.ID, *project)
+	return nil
+}
+
+func (r *projectRepository) Update(ctx context.Context, uid string, id string, patch models.ProjectPatch) (*models.Project, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Name != nil {
+		project.Name = *patch.Name
+	}
+	if patch.Color != nil {
+		project.Color = *patch.Color
+	}
+	project.Updated_at = time.Now()
+	r.projects.insert(project.ID, project)
+	return &project, nil
+}
+
+func (r *projectRepository) Reorder(ctx context.Context, uid string, ids []primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for i, id := range ids {
+		project, ok := r.projects.docs[id]
+		if !ok || project.User_id != uid {
+			continue
+		}
+		project.Order = i
+		project.Updated_at = now
+		r.projects.insert(id, project)
+	}
+	return nil
+}
+
+func (r *projectRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	project, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.projects.remove(project.ID)
+	return nil
+}
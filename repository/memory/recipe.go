@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type recipeRepository struct {
+	mu      sync.RWMutex
+	recipes collection[models.CalorieTracker]
+}
+
+// NewRecipeRepository returns an empty in-memory RecipeRepository
+func NewRecipeRepository() repository.RecipeRepository {
+	return &recipeRepository{recipes: newCollection[models.CalorieTracker]()}
+}
+
+func (r *recipeRepository) List(ctx context.Context, uid string) ([]models.CalorieTracker, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.recipes.filter(func(rc models.CalorieTracker) bool { return rc.User_id == uid }), nil
+}
+
+func (r *recipeRepository) Create(ctx context.Context, recipe *models.CalorieTracker) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if recipe.ID.IsZero() {
+		recipe.ID = primitive.NewObjectID()
+	}
+	r.recipes.insert(recipe.ID, *recipe)
+	return nil
+}
+
+// owned returns the recipe with the given id if uid owns it
+func (r *recipeRepository) owned(uid string, id string) (models.CalorieTracker, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.CalorieTracker{}, err
+	}
+	recipe, ok := r.recipes.docs[objID]
+	if !ok || recipe.User_id != uid {
+		return models.CalorieTracker{}, repository.ErrNotFound
+	}
+	return recipe, nil
+}
+
+func (r *recipeRepository) Get(ctx context.Context, uid string, id string) (*models.CalorieTracker, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recipe, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	recipe.Ingredients = slices.Clone(recipe.Ingredients)
+	return &recipe, nil
+}
+
+func (r *recipeRepository) Update(ctx context.Context, uid string, id string, body models.CalorieTracker) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recipe, err := r.owned(uid, id)
+	if err != nil {
+		return 0, err
+	}
+	recipe.Dish = body.Dish
+	recipe.Ingredients = body.Ingredients
+	recipe.Instructions = body.Instructions
+	recipe.Servings = body.Servings
+	recipe.Calories = body.Calories
+	recipe.Fat = body.Fat
+	recipe.Nutrition = body.Nutrition
+	recipe.Revision++
+	r.recipes.insert(recipe.ID, recipe)
+	return 1, nil
+}
+
+func (r *recipeRepository) UpdateIngredients(ctx context.Context, uid string, id string, fn func(recipe *models.CalorieTracker) error) (*models.CalorieTracker, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recipe, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on a copy so a failing fn leaves the stored list untouched
+	recipe.Ingredients = slices.Clone(recipe.Ingredients)
+	if err := fn(&recipe); err != nil {
+		return nil, err
+	}
+	recipe.Revision++
+	r.recipes.insert(recipe.ID, recipe)
+
+	saved := recipe
+	saved.Ingredients = slices.Clone(recipe.Ingredients)
+	return &saved, nil
+}
+
+func (r *recipeRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	recipe, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.recipes.remove(recipe.ID)
+	return nil
+}
+
+func (r *recipeRepository) DeleteAll(ctx context.Context, uid string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, recipe := range r.recipes.filter(func(rc models.CalorieTracker) bool { return rc.User_id == uid }) {
+		r.recipes.remove(recipe.ID)
+		count++
+	}
+	return count, nil
+}
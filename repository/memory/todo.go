@@ -0,0 +1,425 @@
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type todoRepository struct {
+	mu    sync.RWMutex
+	todos collection[models.ToDoList]
+}
+
+// NewTodoRepository returns an empty in-memory TodoRepository
+func NewTodoRepository() repository.TodoRepository {
+	return &todoRepository{todos: newCollection[models.ToDoList]()}
+}
+
+func (r *todoRepository) List(ctx context.Context, uid string, query repository.TodoQuery) (repository.TodoPage, error) {
+	r.mu.RLock()
+	items := r.todos.filter(func(t models.ToDoList) bool {
+		return t.User_id == uid && matchesTodoQuery(t, query)
+	})
+	for i := range items {
+		items[i] = cloneTodo(items[i])
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return compareTodos(items[i], items[j], query.Sort) < 0
+	})
+
+	if query.Cursor != nil {
+		start := len(items)
+		for i, todo := range items {
+			if compareToCursor(todo, *query.Cursor) > 0 {
+				start = i
+				break
+			}
+		}
+		items = items[start:]
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = repository.DefaultTodoLimit
+	}
+	page := repository.TodoPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.Next_cursor = repository.CursorAfter(page.Items[limit-1], query.Sort).Encode()
+	}
+	return page, nil
+}
+
+func matchesTodoQuery(todo models.ToDoList, query repository.TodoQuery) bool {
+	if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, todo.Status) {
+		return false
+	}
+	if len(query.Priorities) > 0 && !slices.Contains(query.Priorities, todo.Priority) {
+		return false
+	}
+	if query.Due_after != nil && (todo.Due_at == nil || todo.Due_at.Before(*query.Due_after)) {
+		return false
+	}
+	if query.Due_before != nil && (todo.Due_at == nil || !todo.Due_at.Before(*query.Due_before)) {
+		return false
+	}
+	if query.Text != "" {
+		words := repository.SearchWords(todo.Task)
+		for _, word := range repository.SearchWords(query.Text) {
+			if !slices.Contains(words, word) {
+				return false
+			}
+		}
+	}
+	if query.Project != nil && (todo.Project_id == nil || *todo.Project_id != *query.Project) {
+		return false
+	}
+	if query.Project == nil && query.No_project && todo.Project_id != nil {
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !slices.Contains(todo.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareTodos orders todos the way the MongoDB repository sorts them
+func compareTodos(a models.ToDoList, b models.ToDoList, by repository.TodoSort) int {
+	return compareToCursor(a, repository.CursorAfter(b, by))
+}
+
+// compareToCursor reports whether todo sorts before (-1), at (0) or after (1) the cursor.
+// Missing values sort first, ties are broken by id.
+func compareToCursor(todo models.ToDoList, cursor repository.TodoCursor) int {
+	key := repository.CursorAfter(todo, repository.TodoSort{Field: cursor.Sort, Desc: cursor.Desc})
+
+	result := 0
+	switch {
+	case key.Null && !cursor.Null:
+		result = -1
+	case !key.Null && cursor.Null:
+		result = 1
+	case key.Level != nil && cursor.Level != nil:
+		result = cmp.Compare(*key.Level, *cursor.Level)
+	case key.Time != nil && cursor.Time != nil:
+		result = key.Time.Compare(*cursor.Time)
+	}
+	if result == 0 {
+		result = bytes.Compare(key.ID[:], cursor.ID[:])
+	}
+
+	if cursor.Desc {
+		return -result
+	}
+	return result
+}
+
+func (r *todoRepository) Create(ctx context.Context, todo *models.ToDoList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	r.todos.insert(todo.ID, cloneTodo(*todo))
+	return nil
+}
+
+// cloneTodo copies the subtask tree so callers never share it with the store
+func cloneTodo(todo models.ToDoList) models.ToDoList {
+	todo.Subtasks = models.CloneSubtasks(todo.Subtasks)
+	return todo
+}
+
+// owned returns the todo with the given id if uid owns it
+func (r *todoRepository) owned(uid string, id string) (models.ToDoList, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.ToDoList{}, err
+	}
+	todo, ok := r.todos.docs[objID]
+	if !ok || todo.User_id != uid {
+		return models.ToDoList{}, repository.ErrNotFound
+	}
+	return todo, nil
+}
+
+func (r *todoRepository) Get(ctx context.Context, uid string, id string) (*models.ToDoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	todo = cloneTodo(todo)
+	return &todo, nil
+}
+
+func (r *todoRepository) CreateOccurrence(ctx context.Context, todo *models.ToDoList) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := todo.Recurrence
+	taken := r.todos.filter(func(t models.ToDoList) bool {
+		return t.User_id == todo.User_id && t.Recurrence != nil &&
+			t.Recurrence.Series_id == next.Series_id && t.Recurrence.Index == next.Index
+	})
+	if len(taken) > 0 {
+		return false, nil
+	}
+
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	r.todos.insert(todo.ID, cloneTodo(*todo))
+	return true, nil
+}
+
+func (r *todoRepository) Update(ctx context.Context, uid string, id string, patch models.TodoPatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	applyTodoPatch(&todo, patch, time.Now())
+	r.todos.insert(todo.ID, todo)
+	return nil
+}
+
+func (r *todoRepository) Transition(ctx context.Context, uid string, id string, to models.TodoStatus) (*models.ToDoList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	if !todo.Status.CanTransitionTo(to) {
+		return nil, &models.TransitionError{From: todo.Status, To: to}
+	}
+
+	now := time.Now()
+	todo.Status = to
+	todo.Updated_at = now
+	switch to {
+	case models.StatusDone:
+		todo.Completed_at = &now
+	case models.StatusOpen, models.StatusInProgress:
+		todo.Completed_at = nil
+	}
+	r.todos.insert(todo.ID, todo)
+	saved := cloneTodo(todo)
+	return &saved, nil
+}
+
+func (r *todoRepository) UpdateSubtasks(ctx context.Context, uid string, id string, fn func(todo *models.ToDoList) error) (*models.ToDoList, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on a copy so a failing fn leaves the stored tree untouched
+	todo.Subtasks = models.CloneSubtasks(todo.Subtasks)
+	if err := fn(&todo); err != nil {
+		return nil, err
+	}
+	todo.Revision++
+	todo.Updated_at = time.Now()
+	r.todos.insert(todo.ID, todo)
+
+	saved := cloneTodo(todo)
+	return &saved, nil
+}
+
+func (r *todoRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.todos.remove(todo.ID)
+	return nil
+}
+
+func (r *todoRepository) DeleteAll(ctx context.Context, uid string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, todo := range r.todos.filter(func(t models.ToDoList) bool { return t.User_id == uid }) {
+		r.todos.remove(todo.ID)
+		count++
+	}
+	return count, nil
+}
+
+func (r *todoRepository) Move(ctx context.Context, uid string, ids []primitive.ObjectID, project *primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var count int64
+	for _, id := range ids {
+		todo, ok := r.todos.docs[id]
+		if !ok || todo.User_id != uid {
+			continue
+		}
+		todo.Project_id = nil
+		if project != nil {
+			moved := *project
+			todo.Project_id = &moved
+		}
+		todo.Updated_at = now
+		r.todos.insert(id, todo)
+		count++
+	}
+	return count, nil
+}
+
+func (r *todoRepository) DetachProject(ctx context.Context, uid string, project primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var count int64
+	for _, todo := range r.todos.filter(func(t models.ToDoList) bool {
+		return t.User_id == uid && t.Project_id != nil && *t.Project_id == project
+	}) {
+		todo.Project_id = nil
+		todo.Updated_at = now
+		r.todos.insert(todo.ID, todo)
+		count++
+	}
+	return count, nil
+}
+
+func (r *todoRepository) Tags(ctx context.Context, uid string) ([]models.TagCount, error) {
+	r.mu.RLock()
+	counts := map[string]int{}
+	for _, todo := range r.todos.filter(func(t models.ToDoList) bool { return t.User_id == uid }) {
+		for _, tag := range todo.Tags {
+			counts[tag]++
+		}
+	}
+	r.mu.RUnlock()
+
+	tags := []models.TagCount{}
+	for tag, count := range counts {
+		tags = append(tags, models.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags, nil
+}
+
+func (r *todoRepository) RenameTag(ctx context.Context, uid string, from string, to string) (int64, error) {
+	return r.retag(uid, from, func(tags []string) []string {
+		kept := slices.DeleteFunc(tags, func(t string) bool { return t == from || t == to })
+		return append(kept, to)
+	})
+}
+
+func (r *todoRepository) RemoveTag(ctx context.Context, uid string, tag string) (int64, error) {
+	return r.retag(uid, tag, func(tags []string) []string {
+		kept := slices.DeleteFunc(tags, func(t string) bool { return t == tag })
+		if len(kept) == 0 {
+			return nil
+		}
+		return kept
+	})
+}
+
+// retag rewrites the tags of every todo of uid that carries tag
+func (r *todoRepository) retag(uid string, tag string, rewrite func(tags []string) []string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var count int64
+	for _, todo := range r.todos.filter(func(t models.ToDoList) bool {
+		return t.User_id == uid && slices.Contains(t.Tags, tag)
+	}) {
+		todo.Tags = rewrite(slices.Clone(todo.Tags))
+		todo.Updated_at = now
+		r.todos.insert(todo.ID, todo)
+		count++
+	}
+	return count, nil
+}
+
+// applyTodoPatch mirrors the update document the MongoDB repository builds
+func applyTodoPatch(todo *models.ToDoList, patch models.TodoPatch, now time.Time) {
+	changed := false
+	if patch.Task != nil {
+		todo.Task = *patch.Task
+		changed = true
+	}
+	if patch.Priority != nil {
+		todo.Priority = *patch.Priority
+		changed = true
+	}
+	if patch.Clear_due {
+		todo.Due_at = nil
+		todo.Reminders = nil
+		changed = true
+	} else if patch.Due_at != nil {
+		due := *patch.Due_at
+		todo.Due_at = &due
+		changed = true
+	}
+	if patch.Reminders != nil && !patch.Clear_due {
+		todo.Reminders = append([]int(nil), (*patch.Reminders)...)
+		if len(todo.Reminders) == 0 {
+			todo.Reminders = nil
+		}
+		changed = true
+	}
+	if patch.Clear_project {
+		todo.Project_id = nil
+		changed = true
+	} else if patch.Project_id != nil {
+		project := *patch.Project_id
+		todo.Project_id = &project
+		changed = true
+	}
+	if patch.Tags != nil {
+		todo.Tags = models.NormalizeTags(*patch.Tags)
+		changed = true
+	}
+	if patch.Clear_recurrence {
+		todo.Recurrence = nil
+		changed = true
+	} else if patch.Recurrence != nil {
+		recurrence := *patch.Recurrence
+		todo.Recurrence = &recurrence
+		changed = true
+	}
+	if changed {
+		todo.Updated_at = now
+	}
+}
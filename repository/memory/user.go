@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+)
+
+type userRepository struct {
+	mu      sync.RWMutex
+	users   map[string]models.User
+	revoked map[string]time.Time
+}
+
+// NewUserRepository returns an empty in-memory UserRepository
+func NewUserRepository() repository.UserRepository {
+	return &userRepository{
+		users:   map[string]models.User{},
+		revoked: map[string]time.Time{},
+	}
+}
+
+func (r *userRepository) find(match func(models.User) bool) (*models.User, error) {
+	for _, user := range r.users {
+		if match(user) {
+			found := user
+			return &found, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.find(func(u models.User) bool { return u.Email != nil && *u.Email == email })
+}
+
+func (r *userRepository) FindByUserID(ctx context.Context, uid string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[uid]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	user, err := r.FindByEmail(ctx, email)
+	return user != nil, ignoreNotFound(err)
+}
+
+func (r *userRepository) PhoneExists(ctx context.Context, phone string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, err := r.find(func(u models.User) bool { return u.Phone != nil && *u.Phone == phone })
+	return user != nil, ignoreNotFound(err)
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.User_id] = *user
+	return nil
+}
+
+// modify applies fn to the stored user and saves the result
+func (r *userRepository) modify(uid string, fn func(*models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[uid]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	fn(&user)
+	user.Updated_at = time.Now()
+	r.users[uid] = user
+	return nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, uid string, hashedPassword string) error {
+	return r.modify(uid, func(u *models.User) {
+		u.Password = &hashedPassword
+	})
+}
+
+func (r *userRepository) SetBodyGoal(ctx context.Context, uid string, goal *models.BodyGoal) error {
+	return r.modify(uid, func(u *models.User) {
+		u.Body_goal = goal
+	})
+}
+
+func (r *userRepository) SetNutritionTargets(ctx context.Context, uid string, targets *models.Nutrients) error {
+	return r.modify(uid, func(u *models.User) {
+		u.Nutrition_targets = targets
+	})
+}
+
+func (r *userRepository) SetNutritionGoal(ctx context.Context, uid string, goal *models.NutritionGoal) error {
+	return r.modify(uid, func(u *models.User) {
+		u.Nutrition_goal = goal
+	})
+}
+
+func (r *userRepository) UpdateTokens(ctx context.Context, uid string, token string, refreshToken string, family string) error {
+	return r.modify(uid, func(u *models.User) {
+		u.Token = &token
+		u.Refresh_token = &refreshToken
+		u.Token_family = family
+	})
+}
+
+func (r *userRepository) RotateTokens(ctx context.Context, uid string, presentedRefreshToken string, token string, refreshToken string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[uid]
+	if !ok || user.Refresh_token == nil || *user.Refresh_token != presentedRefreshToken {
+		return false, nil
+	}
+	user.Token = &token
+	user.Refresh_token = &refreshToken
+	user.Updated_at = time.Now()
+	r.users[uid] = user
+	return true, nil
+}
+
+func (r *userRepository) RevokeTokens(ctx context.Context, uid string) error {
+	return ignoreNotFound(r.modify(uid, func(u *models.User) {
+		u.Token = nil
+		u.Refresh_token = nil
+		u.Token_family = ""
+	}))
+}
+
+func (r *userRepository) RevokeAllSessions(ctx context.Context, uid string) error {
+	return r.modify(uid, func(u *models.User) {
+		u.Token_version++
+		u.Token = nil
+		u.Refresh_token = nil
+		u.Token_family = ""
+	})
+}
+
+func (r *userRepository) RevokeToken(ctx context.Context, jti string, uid string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Like the TTL index of the Mongo denylist, drop entries once the token they
+	// revoke has expired and could not be used anyway
+	now := time.Now()
+	for revoked, expiry := range r.revoked {
+		if expiry.Before(now) {
+			delete(r.revoked, revoked)
+		}
+	}
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *userRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+func ignoreNotFound(err error) error {
+	if err == repository.ErrNotFound {
+		return nil
+	}
+	return err
+}
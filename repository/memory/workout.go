@@ -0,0 +1,320 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type workoutRepository struct {
+	mu        sync.RWMutex
+	workouts  collection[models.Workout]
+	exercises repository.ExerciseRepository
+}
+
+// NewWorkoutRepository returns an empty in-memory WorkoutRepository. Analytics look
+// up the muscles of logged exercises in exercises.
+func NewWorkoutRepository(exercises repository.ExerciseRepository) repository.WorkoutRepository {
+	return &workoutRepository{workouts: newCollection[models.Workout](), exercises: exercises}
+}
+
+func (r *workoutRepository) List(ctx context.Context, uid string, query repository.WorkoutQuery) ([]models.Workout, error) {
+	r.mu.RLock()
+	workouts := r.workouts.filter(func(w models.Workout) bool {
+		return w.User_id == uid && inRange(w.Performed_at, query.From, query.To)
+	})
+	r.mu.RUnlock()
+
+	sort.SliceStable(workouts, func(i, j int) bool {
+		if !workouts[i].Performed_at.Equal(workouts[j].Performed_at) {
+			return workouts[i].Performed_at.After(workouts[j].Performed_at)
+		}
+		return workouts[i].ID.Hex() > workouts[j].ID.Hex()
+	})
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = repository.DefaultWorkoutLimit
+	}
+	if len(workouts) > limit {
+		workouts = workouts[:limit]
+	}
+	return workouts, nil
+}
+
+// inRange reports whether at lies in [from, to); nil bounds are open
+func inRange(at time.Time, from *time.Time, to *time.Time) bool {
+	return (from == nil || !at.Before(*from)) && (to == nil || at.Before(*to))
+}
+
+// owned returns the workout with the given id if uid owns it
+func (r *workoutRepository) owned(uid string, id string) (models.Workout, error) {
+	objID, err := parseID(id)
+	if err != nil {
+		return models.Workout{}, err
+	}
+	workout, ok := r.workouts.docs[objID]
+	if !ok || workout.User_id != uid {
+		return models.Workout{}, repository.ErrNotFound
+	}
+	return workout, nil
+}
+
+func (r *workoutRepository) Get(ctx context.Context, uid string, id string) (*models.Workout, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workout, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	return &workout, nil
+}
+
+func (r *workoutRepository) Create(ctx context.Context, workout *models.Workout) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if workout.ID.IsZero() {
+		workout.ID = primitive.NewObjectID()
+	}
+	stored := *workout
+	stored.Records = nil
+	r.workouts.insert(stored.ID, stored)
+	return nil
+}
+
+func (r *workoutRepository) Update(ctx context.Context, uid string, id string, body models.Workout) (*models.Workout, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workout, err := r.owned(uid, id)
+	if err != nil {
+		return nil, err
+	}
+	workout.Name = body.Name
+	workout.Performed_at = body.Performed_at
+	workout.Duration = body.Duration
+	workout.Notes = body.Notes
+	workout.Plan_id = body.Plan_id
+	workout.Exercises = body.Exercises
+	workout.Updated_at = body.Updated_at
+	r.workouts.insert(workout.ID, workout)
+	return &workout, nil
+}
+
+func (r *workoutRepository) Delete(ctx context.Context, uid string, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	workout, err := r.owned(uid, id)
+	if err != nil {
+		return err
+	}
+	r.workouts.remove(workout.ID)
+	return nil
+}
+
+// Records mirrors the aggregation of the MongoDB repository
+func (r *workoutRepository) Records(ctx context.Context, uid string, before *time.Time, keys []string) ([]models.ExerciseRecords, error) {
+	r.mu.RLock()
+	workouts := r.workouts.filter(func(w models.Workout) bool {
+		return w.User_id == uid && (before == nil || w.Performed_at.Before(*before))
+	})
+	r.mu.RUnlock()
+
+	sort.SliceStable(workouts, func(i, j int) bool { return workouts[i].Performed_at.Before(workouts[j].Performed_at) })
+
+	byKey := map[string]*models.ExerciseRecords{}
+	for _, workout := range workouts {
+		for _, key := range workout.Keys() {
+			if len(keys) > 0 && !slices.Contains(keys, key) {
+				continue
+			}
+			records, ok := byKey[key]
+			if !ok {
+				records = &models.ExerciseRecords{Key: key}
+				byKey[key] = records
+			}
+
+			var session models.WorkoutExercise
+			for _, e := range workout.Exercises {
+				if e.Key == key {
+					session.Top_weight = max(session.Top_weight, e.Top_weight)
+					session.Best_e1rm = max(session.Best_e1rm, e.Best_e1rm)
+					session.Volume += e.Volume
+					records.Exercise = e.Name
+				}
+			}
+			improve(&records.Best_weight, session.Top_weight, workout)
+			improve(&records.Best_e1rm, session.Best_e1rm, workout)
+			improve(&records.Best_volume, session.Volume, workout)
+		}
+	}
+
+	records := []models.ExerciseRecords{}
+	for _, r := range byKey {
+		records = append(records, *r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	return records, nil
+}
+
+// improve replaces best when value beats it; workouts arrive oldest first so the
+// earliest workout reaching a value keeps the record
+func improve(best *models.RecordValue, value float64, workout models.Workout) {
+	if best.Workout_id.IsZero() || value > best.Value {
+		*best = models.RecordValue{Value: value, Workout_id: workout.ID, Achieved_at: workout.Performed_at}
+	}
+}
+
+// performed returns the workouts of the query, oldest first
+func (r *workoutRepository) performed(uid string, query repository.AnalyticsQuery) []models.Workout {
+	r.mu.RLock()
+	workouts := r.workouts.filter(func(w models.Workout) bool {
+		return w.User_id == uid && inRange(w.Performed_at, &query.From, &query.To)
+	})
+	r.mu.RUnlock()
+
+	sort.SliceStable(workouts, func(i, j int) bool { return workouts[i].Performed_at.Before(workouts[j].Performed_at) })
+	return workouts
+}
+
+// workingSets counts the sets of an exercise that are neither warm-ups nor empty
+func workingSets(e models.WorkoutExercise) int {
+	count := 0
+	for _, set := range e.Sets {
+		if !set.Warmup && set.Reps > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// MuscleVolume mirrors the aggregation of the MongoDB repository
+func (r *workoutRepository) MuscleVolume(ctx context.Context, uid string, query repository.AnalyticsQuery) ([]models.MuscleVolume, error) {
+	workouts := r.performed(uid, query)
+
+	var ids []primitive.ObjectID
+	for _, w := range workouts {
+		for _, e := range w.Exercises {
+			if e.Exercise_id != nil {
+				ids = append(ids, *e.Exercise_id)
+			}
+		}
+	}
+	found, err := r.exercises.Find(ctx, uid, ids)
+	if err != nil {
+		return nil, err
+	}
+	catalog := map[primitive.ObjectID]models.Exercise{}
+	for _, exercise := range found {
+		catalog[exercise.ID] = exercise
+	}
+
+	type bucket struct {
+		week   time.Time
+		muscle string
+	}
+	byBucket := map[bucket]*models.MuscleVolume{}
+	sessions := map[bucket]map[primitive.ObjectID]bool{}
+	for _, w := range workouts {
+		week := models.StartOfWeek(w.Performed_at, query.Location)
+		for _, e := range w.Exercises {
+			if e.Exercise_id == nil {
+				continue
+			}
+			exercise, ok := catalog[*e.Exercise_id]
+			if !ok {
+				continue
+			}
+			credit := func(muscles []string, share float64) {
+				for _, muscle := range muscles {
+					key := bucket{week, muscle}
+					volume, ok := byBucket[key]
+					if !ok {
+						volume = &models.MuscleVolume{Week: week, Muscle: muscle}
+						byBucket[key] = volume
+						sessions[key] = map[primitive.ObjectID]bool{}
+					}
+					volume.Sets += float64(workingSets(e)) * share
+					volume.Volume += e.Volume * share
+					sessions[key][w.ID] = true
+				}
+			}
+			credit(exercise.Primary_muscles, 1)
+			credit(exercise.Secondary_muscles, models.SecondaryShare)
+		}
+	}
+
+	volumes := []models.MuscleVolume{}
+	for key, volume := range byBucket {
+		volume.Sessions = len(sessions[key])
+		volume.RoundVolume()
+		volumes = append(volumes, *volume)
+	}
+	models.SortMuscleVolumes(volumes)
+	return volumes, nil
+}
+
+// Trends mirrors the aggregation of the MongoDB repository
+func (r *workoutRepository) Trends(ctx context.Context, uid string, query repository.AnalyticsQuery) ([]models.ExerciseTrend, error) {
+	byKey := map[string]*models.ExerciseTrend{}
+	for _, w := range r.performed(uid, query) {
+		week := models.StartOfWeek(w.Performed_at, query.Location)
+		for _, e := range w.Exercises {
+			if e.Best_e1rm <= 0 || (len(query.Keys) > 0 && !slices.Contains(query.Keys, e.Key)) {
+				continue
+			}
+			trend, ok := byKey[e.Key]
+			if !ok {
+				trend = &models.ExerciseTrend{Key: e.Key, Points: []models.TrendPoint{}}
+				byKey[e.Key] = trend
+			}
+			trend.Exercise = e.Name
+			last := len(trend.Points) - 1
+			if last < 0 || !trend.Points[last].Week.Equal(week) {
+				trend.Points = append(trend.Points, models.TrendPoint{Week: week})
+				last++
+			}
+			point := &trend.Points[last]
+			point.E1rm = max(point.E1rm, e.Best_e1rm)
+			point.Top_weight = max(point.Top_weight, e.Top_weight)
+		}
+	}
+
+	trends := []models.ExerciseTrend{}
+	for _, trend := range byKey {
+		trend.Summarize()
+		trends = append(trends, *trend)
+	}
+	sort.Slice(trends, func(i, j int) bool { return trends[i].Key < trends[j].Key })
+	return trends, nil
+}
+
+// Frequency mirrors the aggregation of the MongoDB repository
+func (r *workoutRepository) Frequency(ctx context.Context, uid string, query repository.AnalyticsQuery) ([]models.WeeklyFrequency, error) {
+	weeks := []models.WeeklyFrequency{}
+	days := map[string]bool{}
+	for _, w := range r.performed(uid, query) {
+		week := models.StartOfWeek(w.Performed_at, query.Location)
+		last := len(weeks) - 1
+		if last < 0 || !weeks[last].Week.Equal(week) {
+			weeks = append(weeks, models.WeeklyFrequency{Week: week})
+			last++
+		}
+		weeks[last].Workouts++
+		weeks[last].Minutes += w.Duration
+		if day := w.Performed_at.In(query.Location).Format(time.DateOnly); !days[day] {
+			days[day] = true
+			weeks[last].Days++
+		}
+	}
+	return weeks, nil
+}
@@ -0,0 +1,136 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type bodyRepository struct {
+	coll *mongo.Collection
+}
+
+// NewBodyRepository returns a BodyRepository backed by the body metric collection
+func NewBodyRepository(coll *mongo.Collection) repository.BodyRepository {
+	return &bodyRepository{coll: coll}
+}
+
+func (r *bodyRepository) List(ctx context.Context, uid string, from *time.Time, to *time.Time) ([]models.BodyMetric, error) {
+	filter := ownerFilter(uid)
+	if from != nil || to != nil {
+		measured := bson.M{}
+		if from != nil {
+			measured["$gte"] = *from
+		}
+		if to != nil {
+			measured["$lt"] = *to
+		}
+		filter["measured_at"] = measured
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "measured_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	metrics := []models.BodyMetric{}
+	if err := cursor.All(ctx, &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func (r *bodyRepository) Get(ctx context.Context, uid string, id string) (*models.BodyMetric, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var metric models.BodyMetric
+	if err := r.coll.FindOne(ctx, filter).Decode(&metric); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &metric, nil
+}
+
+func (r *bodyRepository) Create(ctx context.Context, metric *models.BodyMetric) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if metric.ID.IsZero() {
+		metric.ID = primitive.NewObjectID()
+	}
+	_, err := r.coll.InsertOne(ctx, metric)
+	return err
+}
+
+func (r *bodyRepository) Update(ctx context.Context, uid string, id string, metric models.BodyMetric) (*models.BodyMetric, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// Metrics left out of the replacement are removed rather than kept
+	set := bson.M{"measured_at": metric.Measured_at, "notes": metric.Notes, "updated_at": metric.Updated_at}
+	unset := bson.M{}
+	for _, name := range models.BodyMetrics {
+		if value := metric.Value(name); value != nil {
+			set[name] = *value
+		} else {
+			unset[name] = ""
+		}
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.BodyMetric
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *bodyRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type diaryRepository struct {
+	coll *mongo.Collection
+}
+
+// NewDiaryRepository returns a DiaryRepository backed by the food diary collection
+func NewDiaryRepository(coll *mongo.Collection) repository.DiaryRepository {
+	return &diaryRepository{coll: coll}
+}
+
+func (r *diaryRepository) List(ctx context.Context, uid string, from string, to string) ([]models.FoodEntry, error) {
+	filter := ownerFilter(uid)
+	filter["date"] = bson.M{"$gte": from, "$lte": to}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.FoodEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *diaryRepository) Get(ctx context.Context, uid string, id string) (*models.FoodEntry, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var entry models.FoodEntry
+	if err := r.coll.FindOne(ctx, filter).Decode(&entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *diaryRepository) Create(ctx context.Context, entry *models.FoodEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	_, err := r.coll.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *diaryRepository) Update(ctx context.Context, uid string, id string, entry models.FoodEntry) (*models.FoodEntry, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	set := bson.M{
+		"date":        entry.Date,
+		"meal":        entry.Meal,
+		"name":        entry.Name,
+		"servings":    entry.Servings,
+		"per_serving": entry.Per_serving,
+		"updated_at":  entry.Updated_at,
+	}
+	update := bson.M{"$set": set}
+	if entry.Recipe_id != nil {
+		set["recipe_id"] = *entry.Recipe_id
+	} else {
+		update["$unset"] = bson.M{"recipe_id": ""}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.FoodEntry
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *diaryRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
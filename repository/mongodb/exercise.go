@@ -0,0 +1,182 @@
+package mongodb
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type exerciseRepository struct {
+	coll *mongo.Collection
+}
+
+// NewExerciseRepository returns an ExerciseRepository backed by the exercise collection
+func NewExerciseRepository(coll *mongo.Collection) repository.ExerciseRepository {
+	return &exerciseRepository{coll: coll}
+}
+
+// visibleFilter matches the built-in exercises and the ones uid added
+func visibleFilter(uid string) bson.M {
+	return bson.M{"user_id": bson.M{"$in": bson.A{"", uid}}}
+}
+
+func (r *exerciseRepository) List(ctx context.Context, uid string, query repository.ExerciseQuery) ([]models.Exercise, error) {
+	filter := visibleFilter(uid)
+	if query.Text != "" {
+		filter["key"] = bson.M{"$regex": regexp.QuoteMeta(models.ExerciseKey(query.Text))}
+	}
+	if query.Muscle != "" {
+		filter["$or"] = bson.A{
+			bson.M{"primary_muscles": query.Muscle},
+			bson.M{"secondary_muscles": query.Muscle},
+		}
+	}
+	if query.Equipment != "" {
+		filter["equipment"] = query.Equipment
+	}
+	if query.Pattern != "" {
+		filter["pattern"] = query.Pattern
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "key", Value: 1}, {Key: "user_id", Value: 1}})
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	exercises := []models.Exercise{}
+	if err := cursor.All(ctx, &exercises); err != nil {
+		return nil, err
+	}
+	return exercises, nil
+}
+
+func (r *exerciseRepository) Get(ctx context.Context, uid string, id string) (*models.Exercise, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrNotFound
+	}
+	filter := visibleFilter(uid)
+	filter["_id"] = objID
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var exercise models.Exercise
+	if err := r.coll.FindOne(ctx, filter).Decode(&exercise); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &exercise, nil
+}
+
+func (r *exerciseRepository) Find(ctx context.Context, uid string, ids []primitive.ObjectID) ([]models.Exercise, error) {
+	if len(ids) == 0 {
+		return []models.Exercise{}, nil
+	}
+	filter := visibleFilter(uid)
+	filter["_id"] = bson.M{"$in": ids}
+	return findAll[models.Exercise](ctx, r.coll, filter)
+}
+
+// taken reports whether uid can already see an exercise with key other than id
+func (r *exerciseRepository) taken(ctx context.Context, uid string, key string, id primitive.ObjectID) (bool, error) {
+	filter := visibleFilter(uid)
+	filter["key"] = key
+	filter["_id"] = bson.M{"$ne": id}
+	count, err := r.coll.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	return count > 0, err
+}
+
+func (r *exerciseRepository) Create(ctx context.Context, exercise *models.Exercise) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if exercise.ID.IsZero() {
+		exercise.ID = primitive.NewObjectID()
+	}
+	if taken, err := r.taken(ctx, exercise.User_id, exercise.Key, exercise.ID); err != nil || taken {
+		if taken {
+			return repository.ErrDuplicate
+		}
+		return err
+	}
+	// The unique index on user_id and key catches a concurrent duplicate
+	if _, err := r.coll.InsertOne(ctx, exercise); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *exerciseRepository) Update(ctx context.Context, uid string, id string, exercise models.Exercise) (*models.Exercise, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if taken, err := r.taken(ctx, uid, exercise.Key, filter["_id"].(primitive.ObjectID)); err != nil || taken {
+		if taken {
+			return nil, repository.ErrDuplicate
+		}
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"name":              exercise.Name,
+		"key":               exercise.Key,
+		"primary_muscles":   exercise.Primary_muscles,
+		"secondary_muscles": exercise.Secondary_muscles,
+		"equipment":         exercise.Equipment,
+		"pattern":           exercise.Pattern,
+		"updated_at":        exercise.Updated_at,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.Exercise
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, repository.ErrDuplicate
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *exerciseRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package mongodb
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type foodRepository struct {
+	coll *mongo.Collection
+}
+
+// NewFoodRepository returns a FoodRepository backed by the foods collection
+func NewFoodRepository(coll *mongo.Collection) repository.FoodRepository {
+	return &foodRepository{coll: coll}
+}
+
+func (r *foodRepository) Search(ctx context.Context, text string, limit int) ([]models.Food, error) {
+	filter := bson.M{}
+	if key := models.FoodKey(text); key != "" {
+		filter["keys"] = bson.M{"$regex": regexp.QuoteMeta(key)}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	foods := []models.Food{}
+	if err := cursor.All(ctx, &foods); err != nil {
+		return nil, err
+	}
+	return foods, nil
+}
+
+func (r *foodRepository) Match(ctx context.Context, items []string) (map[string]models.Food, error) {
+	// Fetch every food known by some run of words of some item, then pick per item
+	candidates := bson.A{}
+	for _, item := range items {
+		for _, candidate := range models.FoodCandidates(item) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	matches := map[string]models.Food{}
+	if len(candidates) == 0 {
+		return matches, nil
+	}
+
+	foods, err := findAll[models.Food](ctx, r.coll, bson.M{"keys": bson.M{"$in": candidates}})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if food, ok := models.MatchFood(item, foods); ok {
+			matches[item] = food
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,256 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type gymRepository struct {
+	coll *mongo.Collection
+}
+
+// NewGymRepository returns a GymRepository backed by the gym collection
+func NewGymRepository(coll *mongo.Collection) repository.GymRepository {
+	return &gymRepository{coll: coll}
+}
+
+func (r *gymRepository) List(ctx context.Context, uid string) ([]models.Gym, error) {
+	return findAll[models.Gym](ctx, r.coll, ownerFilter(uid))
+}
+
+func (r *gymRepository) Get(ctx context.Context, uid string, id string) (*models.Gym, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+	return r.findOne(ctx, filter)
+}
+
+func (r *gymRepository) Active(ctx context.Context, uid string) (*models.Gym, error) {
+	return r.findOne(ctx, bson.M{"user_id": uid, "active": true})
+}
+
+func (r *gymRepository) findOne(ctx context.Context, filter bson.M) (*models.Gym, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var plan models.Gym
+	if err := r.coll.FindOne(ctx, filter).Decode(&plan); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *gymRepository) Create(ctx context.Context, plan *models.Gym) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	first := false
+	if !plan.Active {
+		count, err := r.coll.CountDocuments(ctx, ownerFilter(plan.User_id))
+		if err != nil {
+			return err
+		}
+		first = count == 0
+		plan.Active = first
+	}
+	if plan.Active {
+		if err := r.deactivateAll(ctx, plan.User_id); err != nil {
+			return err
+		}
+	}
+
+	if plan.ID.IsZero() {
+		plan.ID = primitive.NewObjectID()
+	}
+	_, err := r.coll.InsertOne(ctx, plan)
+	if first && mongo.IsDuplicateKeyError(err) {
+		// Another first plan was created at the same time and became the active one
+		plan.Active = false
+		_, err = r.coll.InsertOne(ctx, plan)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+func (r *gymRepository) Update(ctx context.Context, uid string, id string, plan models.Gym) (*models.Gym, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"name":       plan.Name,
+		"monday":     plan.Monday,
+		"tuesday":    plan.Tuesday,
+		"wednesday":  plan.Wednesday,
+		"thursday":   plan.Thursday,
+		"friday":     plan.Friday,
+		"saturday":   plan.Saturday,
+		"sunday":     plan.Sunday,
+		"exercises":  plan.Exercises,
+		"program":    plan.Program,
+		"updated_at": time.Now(),
+	}, "$inc": bson.M{"revision": 1}}
+	return r.findOneAndUpdate(ctx, filter, update)
+}
+
+// programRetries bounds how often UpdateProgram retries after losing a race
+const programRetries = 3
+
+func (r *gymRepository) UpdateProgram(ctx context.Context, uid string, id string, fn func(plan *models.Gym) bool) (*models.Gym, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	for attempt := 0; attempt < programRetries; attempt++ {
+		var plan models.Gym
+		if err := r.coll.FindOne(ctx, filter).Decode(&plan); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, repository.ErrNotFound
+			}
+			return nil, err
+		}
+
+		if !fn(&plan) {
+			return &plan, nil
+		}
+
+		// The revision guards against overwriting a program someone else saved meanwhile.
+		// Plans that were not saved since revisions were introduced carry none yet.
+		guarded := bson.M{"_id": filter["_id"], "user_id": uid, "revision": plan.Revision}
+		if plan.Revision == 0 {
+			guarded["revision"] = bson.M{"$in": bson.A{0, nil}}
+		}
+		plan.Revision++
+		plan.Updated_at = time.Now()
+		update := bson.M{"$set": bson.M{
+			"monday":     plan.Monday,
+			"tuesday":    plan.Tuesday,
+			"wednesday":  plan.Wednesday,
+			"thursday":   plan.Thursday,
+			"friday":     plan.Friday,
+			"saturday":   plan.Saturday,
+			"sunday":     plan.Sunday,
+			"exercises":  plan.Exercises,
+			"program":    plan.Program,
+			"revision":   plan.Revision,
+			"updated_at": plan.Updated_at,
+		}}
+
+		result, err := r.coll.UpdateOne(ctx, guarded, update)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 1 {
+			return &plan, nil
+		}
+	}
+	return nil, repository.ErrConflict
+}
+
+func (r *gymRepository) Activate(ctx context.Context, uid string, id string) (*models.Gym, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if _, err := r.findOne(ctx, filter); err != nil {
+		return nil, err
+	}
+	previous, err := r.findOne(ctx, bson.M{"user_id": uid, "active": true})
+	if err != nil && err != repository.ErrNotFound {
+		return nil, err
+	}
+	// The unique index on active plans only allows the switch in this order
+	if err := r.deactivateAll(ctx, uid); err != nil {
+		return nil, err
+	}
+	plan, err := r.findOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"active": true, "updated_at": time.Now()}})
+	if err == nil {
+		return plan, nil
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		// Another activation won the race, so the user follows that plan
+		return nil, repository.ErrConflict
+	}
+	if previous != nil {
+		err = errors.Join(err, r.reactivate(ctx, previous.ID))
+	}
+	return nil, err
+}
+
+// reactivate marks a plan active again after switching away from it failed, so the
+// user is not left without one. A plan activated in the meantime is left alone.
+func (r *gymRepository) reactivate(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+	defer cancel()
+
+	_, err := r.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"active": true, "updated_at": time.Now()}})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *gymRepository) deactivateAll(ctx context.Context, uid string) error {
+	_, err := r.coll.UpdateMany(ctx,
+		bson.M{"user_id": uid, "active": true},
+		bson.M{"$set": bson.M{"active": false, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *gymRepository) findOneAndUpdate(ctx context.Context, filter bson.M, update bson.M) (*models.Gym, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var plan models.Gym
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&plan); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *gymRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/database"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// queryTimeout bounds every single database call
+const queryTimeout = 100 * time.Second
+
+// New returns MongoDB backed repositories over the initialized collections
+func New(db database.DBCollections) repository.Repositories {
+	return repository.Repositories{
+		Todos:     NewTodoRepository(db.TodoCollection),
+		Projects:  NewProjectRepository(db.ProjectCollection),
+		Recipes:   NewRecipeRepository(db.CalorieCollection),
+		Users:     NewUserRepository(db.UserCollection, db.RevokedTokenCollection),
+		Gym:       NewGymRepository(db.GymCollection),
+		Workouts:  NewWorkoutRepository(db.WorkoutCollection, db.ExerciseCollection),
+		Exercises: NewExerciseRepository(db.ExerciseCollection),
+		Body:      NewBodyRepository(db.BodyCollection),
+		Foods:     NewFoodRepository(db.FoodCollection),
+		Diary:     NewDiaryRepository(db.DiaryCollection),
+	}
+}
+
+// ownedFilter scopes a lookup by document id to the documents owned by uid
+func ownedFilter(id string, uid string) (bson.M, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, repository.ErrNotFound
+	}
+	return bson.M{"_id": objID, "user_id": uid}, nil
+}
+
+// ownerFilter scopes a collection wide query to the documents owned by uid
+func ownerFilter(uid string) bson.M {
+	return bson.M{"user_id": uid}
+}
+
+// findAll decodes every document matching filter into a slice of T
+func findAll[T any](ctx context.Context, coll *mongo.Collection, filter interface{}) ([]T, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []T{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
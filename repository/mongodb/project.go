@@ -0,0 +1,153 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type projectRepository struct {
+	coll *mongo.Collection
+}
+
+// NewProjectRepository returns a ProjectRepository backed by the project collection
+func NewProjectRepository(coll *mongo.Collection) repository.ProjectRepository {
+	return &projectRepository{coll: coll}
+}
+
+func (r *projectRepository) List(ctx context.Context, uid string) ([]models.Project, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "order", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.coll.Find(ctx, ownerFilter(uid), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	projects := []models.Project{}
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (r *projectRepository) Get(ctx context.Context, uid string, id string) (*models.Project, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var project models.Project
+	if err := r.coll.FindOne(ctx, filter).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *projectRepository) Create(ctx context.Context, project *models.Project) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var last models.Project
+	opts := options.FindOne().SetSort(bson.D{{Key: "order", Value: -1}})
+	err := r.coll.FindOne(ctx, ownerFilter(project.User_id), opts).Decode(&last)
+	switch err {
+	case nil:
+		project.Order = last.Order + 1
+	case mongo.ErrNoDocuments:
+		project.Order = 0
+	default:
+		return err
+	}
+
+	if project.ID.IsZero() {
+		project.ID = primitive.NewObjectID()
+	}
+	_, err = r.coll.InsertOne(ctx, project)
+	return err
+}
+
+func (r *projectRepository) Update(ctx context.Context, uid string, id string, patch models.ProjectPatch) (*models.Project, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	set := bson.M{"updated_at": time.Now()}
+	update := bson.M{"$set": set}
+	if patch.Name != nil {
+		set["name"] = *patch.Name
+	}
+	if patch.Color != nil {
+		if *patch.Color == "" {
+			update["$unset"] = bson.M{"color": ""}
+		} else {
+			set["color"] = *patch.Color
+		}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var project models.Project
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&project); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (r *projectRepository) Reorder(ctx context.Context, uid string, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	now := time.Now()
+	writes := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "user_id": uid}).
+			SetUpdate(bson.M{"$set": bson.M{"order": i, "updated_at": now}})
+	}
+	_, err := r.coll.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *projectRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package mongodb
+
+import (
+	"context"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type recipeRepository struct {
+	coll *mongo.Collection
+}
+
+// NewRecipeRepository returns a RecipeRepository backed by the calorie tracker collection
+func NewRecipeRepository(coll *mongo.Collection) repository.RecipeRepository {
+	return &recipeRepository{coll: coll}
+}
+
+func (r *recipeRepository) List(ctx context.Context, uid string) ([]models.CalorieTracker, error) {
+	return findAll[models.CalorieTracker](ctx, r.coll, ownerFilter(uid))
+}
+
+func (r *recipeRepository) Get(ctx context.Context, uid string, id string) (*models.CalorieTracker, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var recipe models.CalorieTracker
+	if err := r.coll.FindOne(ctx, filter).Decode(&recipe); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+func (r *recipeRepository) Create(ctx context.Context, recipe *models.CalorieTracker) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if recipe.ID.IsZero() {
+		recipe.ID = primitive.NewObjectID()
+	}
+	_, err := r.coll.InsertOne(ctx, recipe)
+	return err
+}
+
+func (r *recipeRepository) Update(ctx context.Context, uid string, id string, recipe models.CalorieTracker) (int64, error) {
+	return r.set(ctx, uid, id, bson.M{
+		"dish":         recipe.Dish,
+		"ingredients":  recipe.Ingredients,
+		"instructions": recipe.Instructions,
+		"servings":     recipe.Servings,
+		"calories":     recipe.Calories,
+		"fat":          recipe.Fat,
+		"nutrition":    recipe.Nutrition,
+	})
+}
+
+// ingredientRetries bounds how often UpdateIngredients retries after losing a race
+const ingredientRetries = 3
+
+func (r *recipeRepository) UpdateIngredients(ctx context.Context, uid string, id string, fn func(recipe *models.CalorieTracker) error) (*models.CalorieTracker, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	for attempt := 0; attempt < ingredientRetries; attempt++ {
+		var recipe models.CalorieTracker
+		if err := r.coll.FindOne(ctx, filter).Decode(&recipe); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, repository.ErrNotFound
+			}
+			return nil, err
+		}
+
+		if err := fn(&recipe); err != nil {
+			return nil, err
+		}
+
+		// Recipes saved before ingredients were structured carry no revision yet
+		guarded := bson.M{"_id": filter["_id"], "user_id": uid, "revision": recipe.Revision}
+		if recipe.Revision == 0 {
+			guarded["revision"] = bson.M{"$in": bson.A{0, nil}}
+		}
+		recipe.Revision++
+		update := bson.M{"$set": bson.M{
+			"ingredients": recipe.Ingredients,
+			"calories":    recipe.Calories,
+			"fat":         recipe.Fat,
+			"nutrition":   recipe.Nutrition,
+			"revision":    recipe.Revision,
+		}}
+
+		result, err := r.coll.UpdateOne(ctx, guarded, update)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 1 {
+			return &recipe, nil
+		}
+	}
+	return nil, repository.ErrConflict
+}
+
+// set changes fields of a recipe. Every write bumps the revision so a concurrent
+// UpdateIngredients never saves a list read before it.
+func (r *recipeRepository) set(ctx context.Context, uid string, id string, set bson.M) (int64, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.UpdateOne(ctx, filter, bson.M{"$set": set, "$inc": bson.M{"revision": 1}})
+	if err != nil {
+		return 0, err
+	}
+	if result.MatchedCount == 0 {
+		return 0, repository.ErrNotFound
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *recipeRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *recipeRepository) DeleteAll(ctx context.Context, uid string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteMany(ctx, ownerFilter(uid))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
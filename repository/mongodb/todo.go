@@ -0,0 +1,491 @@
+package mongodb
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type todoRepository struct {
+	coll *mongo.Collection
+}
+
+// NewTodoRepository returns a TodoRepository backed by the todo collection
+func NewTodoRepository(coll *mongo.Collection) repository.TodoRepository {
+	return &todoRepository{coll: coll}
+}
+
+func (r *todoRepository) List(ctx context.Context, uid string, query repository.TodoQuery) (repository.TodoPage, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = repository.DefaultTodoLimit
+	}
+
+	direction := 1
+	if query.Sort.Desc {
+		direction = -1
+	}
+	sort := bson.D{{Key: "_id", Value: direction}}
+	if query.Sort.Field != repository.SortCreated {
+		sort = bson.D{{Key: query.Sort.Field, Value: direction}, {Key: "_id", Value: direction}}
+	}
+
+	// Fetch one extra todo to learn whether another page follows
+	opts := options.Find().SetSort(sort).SetLimit(int64(limit + 1))
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cursor, err := r.coll.Find(ctx, todoFilter(uid, query), opts)
+	if err != nil {
+		return repository.TodoPage{}, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []models.ToDoList{}
+	if err := cursor.All(ctx, &items); err != nil {
+		return repository.TodoPage{}, err
+	}
+
+	page := repository.TodoPage{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.Next_cursor = repository.CursorAfter(page.Items[limit-1], query.Sort).Encode()
+	}
+	return page, nil
+}
+
+// todoFilter builds the filter for a listing. Every clause starts with user_id so
+// the compound indexes created in database.createIndexes serve the query.
+func todoFilter(uid string, query repository.TodoQuery) bson.M {
+	clauses := bson.A{ownerFilter(uid)}
+
+	if len(query.Statuses) > 0 {
+		clauses = append(clauses, bson.M{"status": bson.M{"$in": query.Statuses}})
+	}
+	if len(query.Priorities) > 0 {
+		clauses = append(clauses, bson.M{"priority": bson.M{"$in": query.Priorities}})
+	}
+	if query.Due_after != nil || query.Due_before != nil {
+		due := bson.M{}
+		if query.Due_after != nil {
+			due["$gte"] = *query.Due_after
+		}
+		if query.Due_before != nil {
+			due["$lt"] = *query.Due_before
+		}
+		clauses = append(clauses, bson.M{"due_at": due})
+	}
+	if words := repository.SearchWords(query.Text); len(words) > 0 {
+		// Quoting every word makes the text search require all of them
+		clauses = append(clauses, bson.M{"$text": bson.M{"$search": `"` + strings.Join(words, `" "`) + `"`}})
+	}
+	if query.Project != nil {
+		clauses = append(clauses, bson.M{"project_id": *query.Project})
+	} else if query.No_project {
+		clauses = append(clauses, bson.M{"project_id": nil})
+	}
+	if len(query.Tags) > 0 {
+		clauses = append(clauses, bson.M{"tags": bson.M{"$all": query.Tags}})
+	}
+	if query.Cursor != nil {
+		clauses = append(clauses, cursorFilter(*query.Cursor))
+	}
+
+	if len(clauses) == 1 {
+		return ownerFilter(uid)
+	}
+	return bson.M{"$and": clauses}
+}
+
+// cursorFilter selects the todos that sort strictly after the cursor. MongoDB sorts
+// missing values before everything else, so they come first ascending and last descending.
+func cursorFilter(cursor repository.TodoCursor) bson.M {
+	after := "$gt"
+	if cursor.Desc {
+		after = "$lt"
+	}
+
+	if cursor.Sort == repository.SortCreated {
+		return bson.M{"_id": bson.M{after: cursor.ID}}
+	}
+
+	field := cursor.Sort
+	var value interface{}
+	switch {
+	case cursor.Level != nil:
+		value = *cursor.Level
+	case cursor.Time != nil:
+		value = *cursor.Time
+	}
+
+	if cursor.Null {
+		sameKey := bson.M{field: nil, "_id": bson.M{after: cursor.ID}}
+		if cursor.Desc {
+			return sameKey
+		}
+		return bson.M{"$or": bson.A{sameKey, bson.M{field: bson.M{"$ne": nil}}}}
+	}
+
+	or := bson.A{
+		bson.M{field: bson.M{after: value}},
+		bson.M{field: value, "_id": bson.M{after: cursor.ID}},
+	}
+	if cursor.Desc {
+		or = append(or, bson.M{field: nil})
+	}
+	return bson.M{"$or": or}
+}
+
+func (r *todoRepository) Create(ctx context.Context, todo *models.ToDoList) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if todo.ID.IsZero() {
+		todo.ID = primitive.NewObjectID()
+	}
+	_, err := r.coll.InsertOne(ctx, todo)
+	return err
+}
+
+func (r *todoRepository) Get(ctx context.Context, uid string, id string) (*models.ToDoList, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var todo models.ToDoList
+	if err := r.coll.FindOne(ctx, filter).Decode(&todo); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// CreateOccurrence relies on the unique series index created in database.createIndexes
+func (r *todoRepository) CreateOccurrence(ctx context.Context, todo *models.ToDoList) (bool, error) {
+	if err := r.Create(ctx, todo); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *todoRepository) Update(ctx context.Context, uid string, id string, patch models.TodoPatch) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	update := todoUpdate(patch, time.Now())
+	if update == nil {
+		return r.exists(ctx, filter)
+	}
+
+	result, err := r.coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// todoUpdate translates a patch into an update document, or nil if nothing changes
+func todoUpdate(patch models.TodoPatch, now time.Time) bson.M {
+	set := bson.M{}
+	unset := bson.M{}
+
+	if patch.Task != nil {
+		set["task"] = *patch.Task
+	}
+	if patch.Priority != nil {
+		set["priority"] = *patch.Priority
+	}
+	if patch.Clear_due {
+		unset["due_at"] = ""
+		unset["reminders"] = ""
+	} else if patch.Due_at != nil {
+		set["due_at"] = *patch.Due_at
+	}
+	if patch.Reminders != nil && !patch.Clear_due {
+		if len(*patch.Reminders) == 0 {
+			unset["reminders"] = ""
+		} else {
+			set["reminders"] = *patch.Reminders
+		}
+	}
+
+	if patch.Clear_project {
+		unset["project_id"] = ""
+	} else if patch.Project_id != nil {
+		set["project_id"] = *patch.Project_id
+	}
+	if patch.Tags != nil {
+		if tags := models.NormalizeTags(*patch.Tags); tags == nil {
+			unset["tags"] = ""
+		} else {
+			set["tags"] = tags
+		}
+	}
+
+	if patch.Clear_recurrence {
+		unset["recurrence"] = ""
+	} else if patch.Recurrence != nil {
+		set["recurrence"] = *patch.Recurrence
+	}
+
+	if len(set) == 0 && len(unset) == 0 {
+		return nil
+	}
+
+	set["updated_at"] = now
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func (r *todoRepository) Transition(ctx context.Context, uid string, id string, to models.TodoStatus) (*models.ToDoList, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// Matching on the allowed source states makes check and write one atomic step
+	filter["status"] = bson.M{"$in": models.TransitionSources(to)}
+
+	now := time.Now()
+	set := bson.M{"status": to, "updated_at": now}
+	update := bson.M{"$set": set}
+	switch to {
+	case models.StatusDone:
+		set["completed_at"] = now
+	case models.StatusOpen, models.StatusInProgress:
+		update["$unset"] = bson.M{"completed_at": ""}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var todo models.ToDoList
+	err = r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&todo)
+	if err == nil {
+		return &todo, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// Either the todo does not exist or it is in a state the move is not allowed from
+	delete(filter, "status")
+	var current models.ToDoList
+	if err := r.coll.FindOne(ctx, filter).Decode(&current); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return nil, &models.TransitionError{From: current.Status, To: to}
+}
+
+// subtaskRetries bounds how often UpdateSubtasks retries after losing a race
+const subtaskRetries = 3
+
+func (r *todoRepository) UpdateSubtasks(ctx context.Context, uid string, id string, fn func(todo *models.ToDoList) error) (*models.ToDoList, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	for attempt := 0; attempt < subtaskRetries; attempt++ {
+		var todo models.ToDoList
+		if err := r.coll.FindOne(ctx, filter).Decode(&todo); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, repository.ErrNotFound
+			}
+			return nil, err
+		}
+
+		if err := fn(&todo); err != nil {
+			return nil, err
+		}
+
+		// The revision guards against overwriting a tree someone else saved meanwhile.
+		// Documents that never had their subtasks changed carry no revision yet.
+		guarded := bson.M{"_id": filter["_id"], "user_id": uid, "revision": todo.Revision}
+		if todo.Revision == 0 {
+			guarded["revision"] = bson.M{"$in": bson.A{0, nil}}
+		}
+		todo.Revision++
+		todo.Updated_at = time.Now()
+		update := bson.M{"$set": bson.M{
+			"subtasks":   todo.Subtasks,
+			"revision":   todo.Revision,
+			"updated_at": todo.Updated_at,
+		}}
+
+		result, err := r.coll.UpdateOne(ctx, guarded, update)
+		if err != nil {
+			return nil, err
+		}
+		if result.MatchedCount == 1 {
+			return &todo, nil
+		}
+	}
+	return nil, repository.ErrConflict
+}
+
+func (r *todoRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *todoRepository) DeleteAll(ctx context.Context, uid string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteMany(ctx, ownerFilter(uid))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *todoRepository) Move(ctx context.Context, uid string, ids []primitive.ObjectID, project *primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"project_id": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	if project != nil {
+		update = bson.M{"$set": bson.M{"project_id": *project, "updated_at": time.Now()}}
+	}
+	result, err := r.coll.UpdateMany(ctx, bson.M{"user_id": uid, "_id": bson.M{"$in": ids}}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.MatchedCount, nil
+}
+
+func (r *todoRepository) DetachProject(ctx context.Context, uid string, project primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.UpdateMany(ctx,
+		bson.M{"user_id": uid, "project_id": project},
+		bson.M{"$unset": bson.M{"project_id": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *todoRepository) Tags(ctx context.Context, uid string) ([]models.TagCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: ownerFilter(uid)}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+	cursor, err := r.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tags := []models.TagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *todoRepository) RenameTag(ctx context.Context, uid string, from string, to string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	// An update pipeline drops both names and appends the new one, so a todo
+	// already carrying to does not end up with it twice
+	others := bson.M{"$filter": bson.M{
+		"input": "$tags",
+		"cond":  bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this", bson.A{from, to}}}}},
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tags":       bson.M{"$concatArrays": bson.A{others, bson.A{to}}},
+			"updated_at": time.Now(),
+		}}},
+	}
+	result, err := r.coll.UpdateMany(ctx, bson.M{"user_id": uid, "tags": from}, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (r *todoRepository) RemoveTag(ctx context.Context, uid string, tag string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.UpdateMany(ctx,
+		bson.M{"user_id": uid, "tags": tag},
+		bson.M{"$pull": bson.M{"tags": tag}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// exists reports ErrNotFound unless a document matches filter
+func (r *todoRepository) exists(ctx context.Context, filter bson.M) error {
+	count, err := r.coll.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
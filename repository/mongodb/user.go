@@ -0,0 +1,228 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type userRepository struct {
+	users   *mongo.Collection
+	revoked *mongo.Collection
+}
+
+// NewUserRepository returns a UserRepository backed by the user and revoked token collections
+func NewUserRepository(users *mongo.Collection, revoked *mongo.Collection) repository.UserRepository {
+	return &userRepository{users: users, revoked: revoked}
+}
+
+func (r *userRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var user models.User
+	if err := r.users.FindOne(ctx, filter).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) exists(ctx context.Context, filter bson.M) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	count, err := r.users.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *userRepository) update(ctx context.Context, filter bson.M, update bson.M) (*mongo.UpdateResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	return r.users.UpdateOne(ctx, filter, update)
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *userRepository) FindByUserID(ctx context.Context, uid string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"user_id": uid})
+}
+
+func (r *userRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	return r.exists(ctx, bson.M{"email": email})
+}
+
+func (r *userRepository) PhoneExists(ctx context.Context, phone string) (bool, error) {
+	return r.exists(ctx, bson.M{"phone": phone})
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := r.users.InsertOne(ctx, user)
+	return err
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, uid string, hashedPassword string) error {
+	result, err := r.update(ctx, bson.M{"user_id": uid}, bson.M{
+		"$set": bson.M{
+			"password":   hashedPassword,
+			"updated_at": time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) SetBodyGoal(ctx context.Context, uid string, goal *models.BodyGoal) error {
+	update := bson.M{"$set": bson.M{"body_goal": goal, "updated_at": time.Now()}}
+	if goal == nil {
+		update = bson.M{"$unset": bson.M{"body_goal": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+	result, err := r.update(ctx, bson.M{"user_id": uid}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) SetNutritionTargets(ctx context.Context, uid string, targets *models.Nutrients) error {
+	update := bson.M{"$set": bson.M{"nutrition_targets": targets, "updated_at": time.Now()}}
+	if targets == nil {
+		update = bson.M{"$unset": bson.M{"nutrition_targets": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+	result, err := r.update(ctx, bson.M{"user_id": uid}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) SetNutritionGoal(ctx context.Context, uid string, goal *models.NutritionGoal) error {
+	update := bson.M{"$set": bson.M{"nutrition_goal": goal, "updated_at": time.Now()}}
+	if goal == nil {
+		update = bson.M{"$unset": bson.M{"nutrition_goal": ""}, "$set": bson.M{"updated_at": time.Now()}}
+	}
+	result, err := r.update(ctx, bson.M{"user_id": uid}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) UpdateTokens(ctx context.Context, uid string, token string, refreshToken string, family string) error {
+	result, err := r.update(ctx, bson.M{"user_id": uid}, bson.M{
+		"$set": bson.M{
+			"token":         token,
+			"refresh_token": refreshToken,
+			"token_family":  family,
+			"updated_at":    time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) RotateTokens(ctx context.Context, uid string, presentedRefreshToken string, token string, refreshToken string) (bool, error) {
+	result, err := r.update(ctx, bson.M{"user_id": uid, "refresh_token": presentedRefreshToken}, bson.M{
+		"$set": bson.M{
+			"token":         token,
+			"refresh_token": refreshToken,
+			"updated_at":    time.Now(),
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *userRepository) RevokeTokens(ctx context.Context, uid string) error {
+	_, err := r.update(ctx, bson.M{"user_id": uid}, bson.M{
+		"$set": bson.M{
+			"token":         nil,
+			"refresh_token": nil,
+			"token_family":  "",
+			"updated_at":    time.Now(),
+		},
+	})
+	return err
+}
+
+func (r *userRepository) RevokeAllSessions(ctx context.Context, uid string) error {
+	result, err := r.update(ctx, bson.M{"user_id": uid}, bson.M{
+		"$inc": bson.M{"token_version": 1},
+		"$set": bson.M{
+			"token":         nil,
+			"refresh_token": nil,
+			"token_family":  "",
+			"updated_at":    time.Now(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) RevokeToken(ctx context.Context, jti string, uid string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"jti":        jti,
+			"user_id":    uid,
+			"expires_at": expiresAt,
+		},
+	}
+	_, err := r.revoked.UpdateOne(ctx, bson.M{"jti": jti}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *userRepository) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	count, err := r.revoked.CountDocuments(ctx, bson.M{"jti": jti}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
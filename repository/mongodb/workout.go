@@ -0,0 +1,384 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type workoutRepository struct {
+	coll      *mongo.Collection
+	exercises *mongo.Collection
+}
+
+// NewWorkoutRepository returns a WorkoutRepository backed by the workout collection.
+// Analytics look up the muscles of logged exercises in the exercise collection.
+// Records and analytics group with $top and $dateTrunc, so they need MongoDB 5.2
+// or later.
+func NewWorkoutRepository(coll *mongo.Collection, exercises *mongo.Collection) repository.WorkoutRepository {
+	return &workoutRepository{coll: coll, exercises: exercises}
+}
+
+func (r *workoutRepository) List(ctx context.Context, uid string, query repository.WorkoutQuery) ([]models.Workout, error) {
+	filter := bson.M{"user_id": uid}
+	if performed := performedRange(query.From, query.To); performed != nil {
+		filter["performed_at"] = performed
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = repository.DefaultWorkoutLimit
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "performed_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	workouts := []models.Workout{}
+	if err := cursor.All(ctx, &workouts); err != nil {
+		return nil, err
+	}
+	return workouts, nil
+}
+
+// performedRange builds the performed_at condition for [from, to), or nil without bounds
+func performedRange(from *time.Time, to *time.Time) bson.M {
+	if from == nil && to == nil {
+		return nil
+	}
+	performed := bson.M{}
+	if from != nil {
+		performed["$gte"] = *from
+	}
+	if to != nil {
+		performed["$lt"] = *to
+	}
+	return performed
+}
+
+func (r *workoutRepository) Get(ctx context.Context, uid string, id string) (*models.Workout, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var workout models.Workout
+	if err := r.coll.FindOne(ctx, filter).Decode(&workout); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &workout, nil
+}
+
+func (r *workoutRepository) Create(ctx context.Context, workout *models.Workout) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	if workout.ID.IsZero() {
+		workout.ID = primitive.NewObjectID()
+	}
+	_, err := r.coll.InsertOne(ctx, workout)
+	return err
+}
+
+func (r *workoutRepository) Update(ctx context.Context, uid string, id string, workout models.Workout) (*models.Workout, error) {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"name":             workout.Name,
+		"performed_at":     workout.Performed_at,
+		"duration_minutes": workout.Duration,
+		"notes":            workout.Notes,
+		"plan_id":          workout.Plan_id,
+		"exercises":        workout.Exercises,
+		"updated_at":       workout.Updated_at,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.Workout
+	if err := r.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *workoutRepository) Delete(ctx context.Context, uid string, id string) error {
+	filter, err := ownedFilter(id, uid)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := r.coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *workoutRepository) Records(ctx context.Context, uid string, before *time.Time, keys []string) ([]models.ExerciseRecords, error) {
+	match := bson.M{"user_id": uid}
+	if before != nil {
+		match["performed_at"] = bson.M{"$lt": *before}
+	}
+	exerciseMatch := bson.M{}
+	if len(keys) > 0 {
+		match["exercises.key"] = bson.M{"$in": keys}
+		exerciseMatch["exercises.key"] = bson.M{"$in": keys}
+	}
+
+	// The earliest workout reaching a best holds the record
+	best := func(field string) bson.M {
+		return bson.M{"$top": bson.M{
+			"sortBy": bson.D{{Key: field, Value: -1}, {Key: "performed_at", Value: 1}},
+			"output": bson.M{"value": "$" + field, "workout_id": "$_id.workout", "achieved_at": "$performed_at"},
+		}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$unwind", Value: "$exercises"}},
+		{{Key: "$match", Value: exerciseMatch}},
+		// An exercise logged twice in one workout counts once, as in models.Workout.DetectRecords
+		{{Key: "$group", Value: bson.M{
+			"_id":          bson.M{"workout": "$_id", "key": "$exercises.key"},
+			"name":         bson.M{"$last": "$exercises.name"},
+			"performed_at": bson.M{"$first": "$performed_at"},
+			"weight":       bson.M{"$max": "$exercises.top_weight"},
+			"e1rm":         bson.M{"$max": "$exercises.best_e1rm"},
+			"volume":       bson.M{"$sum": "$exercises.volume"},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$_id.key",
+			"exercise": bson.M{"$top": bson.M{
+				"sortBy": bson.D{{Key: "performed_at", Value: -1}},
+				"output": "$name",
+			}},
+			"best_weight": best("weight"),
+			"best_e1rm":   best("e1rm"),
+			"best_volume": best("volume"),
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cursor, err := r.coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records := []models.ExerciseRecords{}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// aggregate runs pipeline on coll and decodes every result into a slice of T
+func aggregate[T any](ctx context.Context, coll *mongo.Collection, pipeline mongo.Pipeline) ([]T, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	results := []T{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// analyticsMatch selects the workouts of the query
+func analyticsMatch(uid string, query repository.AnalyticsQuery) bson.D {
+	return bson.D{{Key: "$match", Value: bson.M{
+		"user_id":      uid,
+		"performed_at": bson.M{"$gte": query.From, "$lt": query.To},
+	}}}
+}
+
+// weekOf truncates a date expression to the Monday starting its week
+func weekOf(date interface{}, loc *time.Location) bson.M {
+	return bson.M{"$dateTrunc": bson.M{
+		"date":        date,
+		"unit":        "week",
+		"startOfWeek": "monday",
+		"timezone":    timezone(loc),
+	}}
+}
+
+// timezone names loc the way MongoDB date operators expect it, by its IANA name so
+// that weeks and days either side of a DST change are cut at local midnight
+func timezone(loc *time.Location) string {
+	if loc == nil {
+		return "UTC"
+	}
+	return loc.String()
+}
+
+// workingSets counts the sets of an exercise that are neither warm-ups nor empty
+var workingSets = bson.M{"$size": bson.M{"$filter": bson.M{
+	"input": "$exercises.sets",
+	"cond": bson.M{"$and": bson.A{
+		bson.M{"$ne": bson.A{"$$this.warmup", true}},
+		bson.M{"$gt": bson.A{"$$this.reps", 0}},
+	}},
+}}}
+
+func (r *workoutRepository) MuscleVolume(ctx context.Context, uid string, query repository.AnalyticsQuery) ([]models.MuscleVolume, error) {
+	credit := func(field string, share float64) bson.M {
+		return bson.M{"$map": bson.M{
+			"input": "$exercise." + field,
+			"in":    bson.M{"muscle": "$$this", "share": share},
+		}}
+	}
+	pipeline := mongo.Pipeline{
+		analyticsMatch(uid, query),
+		{{Key: "$unwind", Value: "$exercises"}},
+		{{Key: "$match", Value: bson.M{"exercises.exercise_id": bson.M{"$exists": true}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         r.exercises.Name(),
+			"localField":   "exercises.exercise_id",
+			"foreignField": "_id",
+			"as":           "exercise",
+		}}},
+		{{Key: "$unwind", Value: "$exercise"}},
+		{{Key: "$project", Value: bson.M{
+			"week":   weekOf("$performed_at", query.Location),
+			"sets":   workingSets,
+			"volume": "$exercises.volume",
+			"muscles": bson.M{"$concatArrays": bson.A{
+				credit("primary_muscles", 1),
+				credit("secondary_muscles", models.SecondaryShare),
+			}},
+		}}},
+		{{Key: "$unwind", Value: "$muscles"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      bson.M{"week": "$week", "muscle": "$muscles.muscle"},
+			"sets":     bson.M{"$sum": bson.M{"$multiply": bson.A{"$sets", "$muscles.share"}}},
+			"volume":   bson.M{"$sum": bson.M{"$multiply": bson.A{"$volume", "$muscles.share"}}},
+			"sessions": bson.M{"$addToSet": "$_id"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":      0,
+			"week":     "$_id.week",
+			"muscle":   "$_id.muscle",
+			"sets":     1,
+			"volume":   1,
+			"sessions": bson.M{"$size": "$sessions"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "week", Value: 1}, {Key: "muscle", Value: 1}}}},
+	}
+	volumes, err := aggregate[models.MuscleVolume](ctx, r.coll, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	for i := range volumes {
+		volumes[i].Week = volumes[i].Week.In(query.Location)
+		volumes[i].RoundVolume()
+	}
+	return volumes, nil
+}
+
+func (r *workoutRepository) Trends(ctx context.Context, uid string, query repository.AnalyticsQuery) ([]models.ExerciseTrend, error) {
+	exerciseMatch := bson.M{"exercises.best_e1rm": bson.M{"$gt": 0}}
+	if len(query.Keys) > 0 {
+		exerciseMatch["exercises.key"] = bson.M{"$in": query.Keys}
+	}
+	pipeline := mongo.Pipeline{
+		analyticsMatch(uid, query),
+		{{Key: "$unwind", Value: "$exercises"}},
+		{{Key: "$match", Value: exerciseMatch}},
+		{{Key: "$sort", Value: bson.D{{Key: "performed_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        bson.M{"key": "$exercises.key", "week": weekOf("$performed_at", query.Location)},
+			"exercise":   bson.M{"$last": "$exercises.name"},
+			"e1rm":       bson.M{"$max": "$exercises.best_e1rm"},
+			"top_weight": bson.M{"$max": "$exercises.top_weight"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.week", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$_id.key",
+			"exercise": bson.M{"$last": "$exercise"},
+			"points": bson.M{"$push": bson.M{
+				"week":       "$_id.week",
+				"e1rm":       "$e1rm",
+				"top_weight": "$top_weight",
+			}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+	trends, err := aggregate[models.ExerciseTrend](ctx, r.coll, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	for i := range trends {
+		for j := range trends[i].Points {
+			trends[i].Points[j].Week = trends[i].Points[j].Week.In(query.Location)
+		}
+		trends[i].Summarize()
+	}
+	return trends, nil
+}
+
+func (r *workoutRepository) Frequency(ctx context.Context, uid string, query repository.AnalyticsQuery) ([]models.WeeklyFrequency, error) {
+	pipeline := mongo.Pipeline{
+		analyticsMatch(uid, query),
+		{{Key: "$group", Value: bson.M{
+			"_id":      weekOf("$performed_at", query.Location),
+			"workouts": bson.M{"$sum": 1},
+			"minutes":  bson.M{"$sum": "$duration_minutes"},
+			"days": bson.M{"$addToSet": bson.M{"$dateToString": bson.M{
+				"date":     "$performed_at",
+				"format":   "%Y-%m-%d",
+				"timezone": timezone(query.Location),
+			}}},
+		}}},
+		{{Key: "$set", Value: bson.M{"days": bson.M{"$size": "$days"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+	weeks, err := aggregate[models.WeeklyFrequency](ctx, r.coll, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	for i := range weeks {
+		weeks[i].Week = weeks[i].Week.In(query.Location)
+	}
+	return weeks, nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound is returned when a document does not exist or belongs to another user.
+// Both cases look the same to the caller so ids of foreign documents are not leaked.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a document kept changing underneath a read-modify-write
+var ErrConflict = errors.New("document was modified concurrently")
+
+// ErrDuplicate is returned when a document would clash with one that must stay unique
+var ErrDuplicate = errors.New("duplicate document")
+
+// Repositories bundles every store the handlers depend on
+type Repositories struct {
+	Todos     TodoRepository
+	Projects  ProjectRepository
+	Recipes   RecipeRepository
+	Users     UserRepository
+	Gym       GymRepository
+	Workouts  WorkoutRepository
+	Exercises ExerciseRepository
+	Body      BodyRepository
+	Foods     FoodRepository
+	Diary     DiaryRepository
+}
+
+// TodoRepository stores the todos of every user. All methods taking a uid only
+// ever see the documents owned by that user.
+type TodoRepository interface {
+	List(ctx context.Context, uid string, query TodoQuery) (TodoPage, error)
+	Get(ctx context.Context, uid string, id string) (*models.ToDoList, error)
+	Create(ctx context.Context, todo *models.ToDoList) error
+	// CreateOccurrence inserts the next todo of a repeating series. It reports false
+	// without inserting anything when the series already has a todo with that index.
+	CreateOccurrence(ctx context.Context, todo *models.ToDoList) (bool, error)
+	Update(ctx context.Context, uid string, id string, patch models.TodoPatch) error
+	// Transition moves a todo to another lifecycle state and returns it. It fails with a
+	// *models.TransitionError if the todo's current state does not allow the move.
+	Transition(ctx context.Context, uid string, id string, to models.TodoStatus) (*models.ToDoList, error)
+	// UpdateSubtasks loads a todo, lets fn change its subtask tree and saves the tree.
+	// Concurrent changes to the same todo are retried; errors from fn are returned as is.
+	UpdateSubtasks(ctx context.Context, uid string, id string, fn func(todo *models.ToDoList) error) (*models.ToDoList, error)
+	Delete(ctx context.Context, uid string, id string) error
+	DeleteAll(ctx context.Context, uid string) (int64, error)
+
+	// Move files the todos with the given ids under project, or takes them out of
+	// their project when project is nil. Ids of foreign or missing todos are skipped.
+	Move(ctx context.Context, uid string, ids []primitive.ObjectID, project *primitive.ObjectID) (int64, error)
+	// DetachProject takes every todo out of project
+	DetachProject(ctx context.Context, uid string, project primitive.ObjectID) (int64, error)
+
+	// Tags counts the todos carrying each tag, most used first
+	Tags(ctx context.Context, uid string) ([]models.TagCount, error)
+	// RenameTag replaces a tag on every todo, merging it into to where both are present
+	RenameTag(ctx context.Context, uid string, from string, to string) (int64, error)
+	RemoveTag(ctx context.Context, uid string, tag string) (int64, error)
+}
+
+// ProjectRepository stores the projects todos are organized in
+type ProjectRepository interface {
+	// List returns the user's projects in their display order
+	List(ctx context.Context, uid string) ([]models.Project, error)
+	Get(ctx context.Context, uid string, id string) (*models.Project, error)
+	// Create appends the project after the user's existing ones
+	Create(ctx context.Context, project *models.Project) error
+	Update(ctx context.Context, uid string, id string, patch models.ProjectPatch) (*models.Project, error)
+	// Reorder gives each listed project its position in ids as order
+	Reorder(ctx context.Context, uid string, ids []primitive.ObjectID) error
+	Delete(ctx context.Context, uid string, id string) error
+}
+
+// RecipeRepository stores the recipes of the calorie tracker
+type RecipeRepository interface {
+	List(ctx context.Context, uid string) ([]models.CalorieTracker, error)
+	Get(ctx context.Context, uid string, id string) (*models.CalorieTracker, error)
+	Create(ctx context.Context, recipe *models.CalorieTracker) error
+	Update(ctx context.Context, uid string, id string, recipe models.CalorieTracker) (int64, error)
+	// UpdateIngredients loads a recipe, lets fn change its ingredient list and saves the list
+	// together with the recipe's nutrition.
+	// Concurrent changes to the same recipe are retried; errors from fn are returned as is.
+	UpdateIngredients(ctx context.Context, uid string, id string, fn func(recipe *models.CalorieTracker) error) (*models.CalorieTracker, error)
+	Delete(ctx context.Context, uid string, id string) error
+	DeleteAll(ctx context.Context, uid string) (int64, error)
+}
+
+// UserRepository stores user accounts together with their issued tokens and the token denylist
+type UserRepository interface {
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	FindByUserID(ctx context.Context, uid string) (*models.User, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	PhoneExists(ctx context.Context, phone string) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	UpdatePassword(ctx context.Context, uid string, hashedPassword string) error
+	// SetBodyGoal replaces the body goal of the user, a nil goal removes it
+	SetBodyGoal(ctx context.Context, uid string, goal *models.BodyGoal) error
+	// SetNutritionTargets replaces the daily nutrition targets of the user, nil removes them
+	SetNutritionTargets(ctx context.Context, uid string, targets *models.Nutrients) error
+	// SetNutritionGoal replaces the nutrition goal of the user, a nil goal removes it
+	SetNutritionGoal(ctx context.Context, uid string, goal *models.NutritionGoal) error
+
+	// UpdateTokens stores the token pair issued at login and makes family the active refresh family
+	UpdateTokens(ctx context.Context, uid string, token string, refreshToken string, family string) error
+	// RotateTokens replaces the stored token pair only if the stored refresh token is still
+	// presentedRefreshToken. It reports false when another request already rotated it.
+	RotateTokens(ctx context.Context, uid string, presentedRefreshToken string, token string, refreshToken string) (bool, error)
+	// RevokeTokens drops the stored token pair and the active family so the current
+	// refresh family can no longer be used
+	RevokeTokens(ctx context.Context, uid string) error
+	// RevokeAllSessions bumps the user's token version so every token issued so far stops working,
+	// access tokens included
+	RevokeAllSessions(ctx context.Context, uid string) error
+	// RevokeToken puts a token id on the denylist until the token would have expired anyway.
+	// Refresh tokens are put there once rotated, so presenting one again is seen as reuse.
+	RevokeToken(ctx context.Context, jti string, uid string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// GymRepository stores the weekly gym plans of every user
+type GymRepository interface {
+	List(ctx context.Context, uid string) ([]models.Gym, error)
+	Get(ctx context.Context, uid string, id string) (*models.Gym, error)
+	// Active returns the plan the user follows, ErrNotFound if none is marked active
+	Active(ctx context.Context, uid string) (*models.Gym, error)
+	// Create stores a plan. A plan created active, or the user's first plan, becomes
+	// the active one. Create and Activate return ErrConflict when another plan of the
+	// user was activated at the same time.
+	Create(ctx context.Context, plan *models.Gym) error
+	// Update replaces the name, the days, the exercises and the program of a plan
+	Update(ctx context.Context, uid string, id string, plan models.Gym) (*models.Gym, error)
+	// UpdateProgram applies fn to a copy of the stored plan and, when fn reports a
+	// change, saves its program and the schedule generated from it. fn runs again on
+	// a fresh copy when the plan was saved meanwhile; ErrConflict is returned when
+	// that keeps happening.
+	UpdateProgram(ctx context.Context, uid string, id string, fn func(plan *models.Gym) bool) (*models.Gym, error)
+	// Activate marks a plan active and every other plan of the user inactive. When it
+	// fails, the plan that was active before stays active.
+	Activate(ctx context.Context, uid string, id string) (*models.Gym, error)
+	Delete(ctx context.Context, uid string, id string) error
+}
+
+// BodyRepository stores the body measurements of every user
+type BodyRepository interface {
+	// List returns the measurements taken in [from, to), oldest first; nil bounds are open
+	List(ctx context.Context, uid string, from *time.Time, to *time.Time) ([]models.BodyMetric, error)
+	Get(ctx context.Context, uid string, id string) (*models.BodyMetric, error)
+	Create(ctx context.Context, metric *models.BodyMetric) error
+	// Update replaces a measurement and returns it
+	Update(ctx context.Context, uid string, id string, metric models.BodyMetric) (*models.BodyMetric, error)
+	Delete(ctx context.Context, uid string, id string) error
+}
+
+// WorkoutQuery selects the workouts performed in [From, To), newest first
+type WorkoutQuery struct {
+	From  *time.Time
+	To    *time.Time
+	Limit int
+}
+
+// DefaultWorkoutLimit and MaxWorkoutLimit bound the size of a workout listing
+const (
+	DefaultWorkoutLimit = 50
+	MaxWorkoutLimit     = 500
+)
+
+// WorkoutRepository stores the logged workouts of every user
+type WorkoutRepository interface {
+	List(ctx context.Context, uid string, query WorkoutQuery) ([]models.Workout, error)
+	Get(ctx context.Context, uid string, id string) (*models.Workout, error)
+	Create(ctx context.Context, workout *models.Workout) error
+	// Update replaces a workout and returns it
+	Update(ctx context.Context, uid string, id string, workout models.Workout) (*models.Workout, error)
+	Delete(ctx context.Context, uid string, id string) error
+	// Records returns the bests per exercise over the workouts performed before the
+	// given time, or over all workouts when before is nil. A non-empty keys limits
+	// the result to those exercises.
+	Records(ctx context.Context, uid string, before *time.Time, keys []string) ([]models.ExerciseRecords, error)
+	// MuscleVolume returns the weekly work per muscle of the exercises logged from
+	// the catalog, sorted by week and muscle
+	MuscleVolume(ctx context.Context, uid string, query AnalyticsQuery) ([]models.MuscleVolume, error)
+	// Trends returns the weekly best estimated one rep max per exercise, sorted by key
+	Trends(ctx context.Context, uid string, query AnalyticsQuery) ([]models.ExerciseTrend, error)
+	// Frequency returns the workouts, training days and minutes per week
+	Frequency(ctx context.Context, uid string, query AnalyticsQuery) ([]models.WeeklyFrequency, error)
+}
+
+// AnalyticsQuery selects the workouts performed in [From, To). Weeks start on
+// Monday in Location. A non-empty Keys limits exercise analytics to those exercises.
+type AnalyticsQuery struct {
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Keys     []string
+}
+
+// ExerciseQuery filters the exercise catalog. Text matches part of the name,
+// Muscle matches primary and secondary muscles alike; empty fields match anything.
+type ExerciseQuery struct {
+	Text      string
+	Muscle    string
+	Equipment string
+	Pattern   string
+}
+
+// ExerciseRepository stores the exercise catalog: the built-in exercises, which
+// have no owner, and the exercises users add for themselves. Every lookup by uid
+// sees both.
+type ExerciseRepository interface {
+	// List returns the matching exercises sorted by name
+	List(ctx context.Context, uid string, query ExerciseQuery) ([]models.Exercise, error)
+	Get(ctx context.Context, uid string, id string) (*models.Exercise, error)
+	// Find returns the exercises among ids the user can see, in no particular order
+	Find(ctx context.Context, uid string, ids []primitive.ObjectID) ([]models.Exercise, error)
+	// Create and Update return ErrDuplicate when the user can already see an exercise
+	// with the same name
+	Create(ctx context.Context, exercise *models.Exercise) error
+	Update(ctx context.Context, uid string, id string, exercise models.Exercise) (*models.Exercise, error)
+	Delete(ctx context.Context, uid string, id string) error
+}
+
+// FoodRepository reads the food composition database recipe ingredients are
+// matched against
+type FoodRepository interface {
+	// Search returns up to limit foods with text in their name or an alias, sorted by name
+	Search(ctx context.Context, text string, limit int) ([]models.Food, error)
+	// Match finds the food each ingredient item refers to, as models.MatchFood does.
+	// Items no food matches are left out of the result.
+	Match(ctx context.Context, items []string) (map[string]models.Food, error)
+}
+
+// DiaryRepository stores the food diary entries of every user. Dates are
+// YYYY-MM-DD days.
+type DiaryRepository interface {
+	// List returns the entries of the days from one date to another, both included,
+	// oldest first and in the order they were logged within a day
+	List(ctx context.Context, uid string, from string, to string) ([]models.FoodEntry, error)
+	Get(ctx context.Context, uid string, id string) (*models.FoodEntry, error)
+	Create(ctx context.Context, entry *models.FoodEntry) error
+	// Update replaces an entry and returns it
+	Update(ctx context.Context, uid string, id string, entry models.FoodEntry) (*models.FoodEntry, error)
+	Delete(ctx context.Context, uid string, id string) error
+}
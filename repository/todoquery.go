@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Fields a todo listing can be sorted on. Sorting on created_at uses _id, which
+// carries the creation time and exists on every document.
+const (
+	SortCreated  = "created_at"
+	SortUpdated  = "updated_at"
+	SortDue      = "due_at"
+	SortPriority = "priority"
+)
+
+// DefaultTodoLimit and MaxTodoLimit bound the size of a todo page
+const (
+	DefaultTodoLimit = 50
+	MaxTodoLimit     = 200
+)
+
+// ErrInvalidCursor is returned for cursors that were not produced by a listing with the same sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TodoSort orders a todo listing. Todos without a value for Field come first in
+// ascending order and last in descending order; ties are broken by _id.
+type TodoSort struct {
+	Field string
+	Desc  bool
+}
+
+// TodoQuery filters and pages the todos of one user. Zero values mean no filter.
+// A todo matches Text if its task contains every word of it as a whole word, in
+// any case. No_project selects the todos outside every project; a todo matches
+// Tags only if it carries all of them.
+type TodoQuery struct {
+	Statuses   []models.TodoStatus
+	Priorities []models.Priority
+	Due_after  *time.Time
+	Due_before *time.Time
+	Text       string
+	Project    *primitive.ObjectID
+	No_project bool
+	Tags       []string
+	Sort       TodoSort
+	Limit      int
+	Cursor     *TodoCursor
+}
+
+// TodoPage is one page of a todo listing
+type TodoPage struct {
+	Items       []models.ToDoList `json:"items"`
+	Next_cursor string            `json:"next_cursor,omitempty"`
+}
+
+// TodoCursor marks the last todo of a page: its sort key and its id
+type TodoCursor struct {
+	Sort  string             `json:"s"`
+	Desc  bool               `json:"d,omitempty"`
+	Null  bool               `json:"n,omitempty"`
+	Time  *time.Time         `json:"t,omitempty"`
+	Level *int               `json:"l,omitempty"`
+	ID    primitive.ObjectID `json:"id"`
+}
+
+// CursorAfter returns the cursor that continues a listing right after todo
+func CursorAfter(todo models.ToDoList, sort TodoSort) TodoCursor {
+	cursor := TodoCursor{Sort: sort.Field, Desc: sort.Desc, ID: todo.ID}
+	switch sort.Field {
+	case SortDue:
+		cursor.Time = todo.Due_at
+		cursor.Null = todo.Due_at == nil
+	case SortUpdated:
+		if todo.Updated_at.IsZero() {
+			cursor.Null = true
+		} else {
+			updated := todo.Updated_at
+			cursor.Time = &updated
+		}
+	case SortPriority:
+		level := int(todo.Priority)
+		cursor.Level = &level
+	}
+	return cursor
+}
+
+// Encode turns the cursor into the opaque string handed to clients
+func (c TodoCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeTodoCursor parses a cursor produced by Encode for a listing sorted by sort
+func DecodeTodoCursor(s string, sort TodoSort) (*TodoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor TodoCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sort.Field || cursor.Desc != sort.Desc || cursor.ID.IsZero() {
+		return nil, fmt.Errorf("%w: it belongs to a listing with a different sort", ErrInvalidCursor)
+	}
+	return &cursor, nil
+}
+
+// SearchWords splits the text of a query into its lowercased words, the way the
+// text index on todo tasks splits them
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
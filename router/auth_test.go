@@ -0,0 +1,143 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	helper "github.com/khanirfan96/To-do-Fullstack-server/helpers"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository/memory"
+)
+
+// session is the token pair handed out by a login or a refresh
+type session struct {
+	Token         string `json:"token"`
+	Refresh_token string `json:"refresh_token"`
+}
+
+// logIn logs a signed up user in again, starting a new session
+func (a *testAPI) logIn(email string) session {
+	a.t.Helper()
+	var login struct {
+		User session `json:"user"`
+	}
+	a.expect(http.StatusOK, "POST", "/users/login", "", fiber.Map{"email": email, "password": "password1"}, &login)
+	return login.User
+}
+
+// refresh exchanges a refresh token and returns the status and the new session
+func (a *testAPI) refresh(refreshToken string) (int, session) {
+	a.t.Helper()
+	var rotated session
+	status := a.do("POST", "/users/refresh", "", fiber.Map{"refresh_token": refreshToken}, &rotated)
+	return status, rotated
+}
+
+func TestRefresh(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	api.signUp("ada@example.com", "5550001")
+	first := api.logIn("ada@example.com")
+
+	status, second := api.refresh(first.Refresh_token)
+	if status != http.StatusOK || second.Token == "" || second.Refresh_token == first.Refresh_token {
+		t.Fatalf("refresh = %d %+v, want a new token pair", status, second)
+	}
+	api.expect(http.StatusOK, "GET", "/api/gettodo", second.Token, nil, nil)
+
+	// An access token is no refresh token
+	if status, _ := api.refresh(second.Token); status != http.StatusUnauthorized {
+		t.Errorf("refreshing with an access token = %d, want 401", status)
+	}
+
+	// Replaying the rotated token revokes the whole family, including the tokens
+	// handed out by the rotation
+	if status, _ := api.refresh(first.Refresh_token); status != http.StatusUnauthorized {
+		t.Fatalf("replaying a rotated refresh token = %d, want 401", status)
+	}
+	if status, _ := api.refresh(second.Refresh_token); status != http.StatusUnauthorized {
+		t.Errorf("refresh token of a revoked family = %d, want 401", status)
+	}
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", second.Token, nil, nil)
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", first.Token, nil, nil)
+}
+
+func TestRefreshAcrossLogins(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	api.signUp("ada@example.com", "5550001")
+	old := api.logIn("ada@example.com")
+	if status, _ := api.refresh(old.Refresh_token); status != http.StatusOK {
+		t.Fatalf("refresh = %d, want 200", status)
+	}
+
+	// A refresh token of an earlier login that was never rotated was only replaced,
+	// and is turned away without touching the current session
+	replaced := api.logIn("ada@example.com")
+	current := api.logIn("ada@example.com")
+	if status, _ := api.refresh(replaced.Refresh_token); status != http.StatusUnauthorized {
+		t.Fatalf("refresh token of a replaced login = %d, want 401", status)
+	}
+	api.expect(http.StatusOK, "GET", "/api/gettodo", current.Token, nil, nil)
+
+	// A rotated one is reuse, whichever family is active now
+	if status, _ := api.refresh(old.Refresh_token); status != http.StatusUnauthorized {
+		t.Fatalf("replaying a rotated refresh token = %d, want 401", status)
+	}
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", current.Token, nil, nil)
+	if status, _ := api.refresh(current.Refresh_token); status != http.StatusUnauthorized {
+		t.Errorf("refresh token of the current login after reuse = %d, want 401", status)
+	}
+}
+
+func TestRefreshStaleVersion(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	repos := memory.New()
+	api := newTestAPIWith(t, repos)
+	uid, _ := api.signUp("ada@example.com", "5550001")
+
+	// Tokens signed for version 0 and stored as the current session, while the user has
+	// moved on to version 1, fail on their version alone
+	if err := repos.Users.RevokeAllSessions(ctx, uid); err != nil {
+		t.Fatal(err)
+	}
+	family := "stale-family"
+	token, refreshToken, err := helper.GenerateTokensForFamily("ada@example.com", "Test", "User", uid, 0, family)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Users.UpdateTokens(ctx, uid, token, refreshToken, family); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := api.refresh(refreshToken); status != http.StatusUnauthorized {
+		t.Errorf("refresh token of a stale version = %d, want 401", status)
+	}
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", token, nil, nil)
+
+	fresh := api.logIn("ada@example.com")
+	if status, _ := api.refresh(fresh.Refresh_token); status != http.StatusOK {
+		t.Errorf("refresh token of the current version = %d, want 200", status)
+	}
+}
+
+func TestLogoutEverywhere(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	api.signUp("ada@example.com", "5550001")
+	laptop := api.logIn("ada@example.com")
+	phone := api.logIn("ada@example.com")
+	api.expect(http.StatusOK, "GET", "/api/gettodo", laptop.Token, nil, nil)
+
+	api.expect(http.StatusOK, "POST", "/users/logout-all", phone.Token, nil, nil)
+	for name, session := range map[string]session{"laptop": laptop, "phone": phone} {
+		api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", session.Token, nil, nil)
+		if status, _ := api.refresh(session.Refresh_token); status != http.StatusUnauthorized {
+			t.Errorf("refresh token of the %s after logging out everywhere = %d, want 401", name, status)
+		}
+	}
+
+	again := api.logIn("ada@example.com")
+	api.expect(http.StatusOK, "GET", "/api/gettodo", again.Token, nil, nil)
+}
@@ -0,0 +1,85 @@
+package router_test
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// recipeIngredients is a recipe as far as its ingredient list goes
+type recipeIngredients struct {
+	Ingredients []struct {
+		created
+		Item string `json:"item"`
+	} `json:"ingredients"`
+}
+
+func (r recipeIngredients) items() []string {
+	items := make([]string, len(r.Ingredients))
+	for i, ingredient := range r.Ingredients {
+		items[i] = ingredient.Item
+	}
+	return items
+}
+
+func TestIngredients(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var response struct {
+		Recipe created `json:"id"`
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", token, fiber.Map{"dish": "Pancakes", "ingredients": "200 g flour\n2 eggs"}, &response)
+	path := "/" + response.Recipe.ID
+
+	// Ingredients go at the given position, or at the end without one or past it
+	var recipe recipeIngredients
+	api.expect(http.StatusOK, "POST", "/recipe/postingredient"+path, token, fiber.Map{"text": "1 cup milk", "position": 1}, &recipe)
+	api.expect(http.StatusOK, "POST", "/recipe/postingredient"+path, token, fiber.Map{"item": "salt"}, &recipe)
+	api.expect(http.StatusOK, "POST", "/recipe/postingredient"+path, token, fiber.Map{"text": "1 tbsp sugar", "position": 0}, &recipe)
+	api.expect(http.StatusOK, "POST", "/recipe/postingredient"+path, token, fiber.Map{"text": "butter", "position": 99}, &recipe)
+	if want := []string{"sugar", "flour", "milk", "eggs", "salt", "butter"}; !slices.Equal(recipe.items(), want) {
+		t.Fatalf("ingredients = %v, want %v", recipe.items(), want)
+	}
+	api.expect(http.StatusBadRequest, "POST", "/recipe/postingredient"+path, token, fiber.Map{"item": "salt", "position": -1}, nil)
+
+	// An order must name every ingredient exactly once
+	order := make([]string, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		order[len(order)-1-i] = ingredient.ID
+	}
+	duplicated := slices.Clone(order)
+	duplicated[1] = duplicated[0]
+	api.expect(http.StatusBadRequest, "PUT", "/recipe/reorderingredients"+path, token, fiber.Map{"order": duplicated}, nil)
+	api.expect(http.StatusBadRequest, "PUT", "/recipe/reorderingredients"+path, token, fiber.Map{"order": order[1:]}, nil)
+	api.expect(http.StatusBadRequest, "PUT", "/recipe/reorderingredients"+path, token, fiber.Map{"order": append(slices.Clone(order), order[0])}, nil)
+	api.expect(http.StatusOK, "PUT", "/recipe/reorderingredients"+path, token, fiber.Map{"order": order}, &recipe)
+	if want := []string{"butter", "salt", "eggs", "milk", "flour", "sugar"}; !slices.Equal(recipe.items(), want) {
+		t.Fatalf("reordered ingredients = %v, want %v", recipe.items(), want)
+	}
+
+	// Deleting removes only that ingredient, and a second time finds nothing
+	removed := recipe.Ingredients[1].ID
+	api.expect(http.StatusOK, "DELETE", "/recipe/deleteingredient"+path+"/"+removed, token, nil, &recipe)
+	if want := []string{"butter", "eggs", "milk", "flour", "sugar"}; !slices.Equal(recipe.items(), want) {
+		t.Fatalf("ingredients after delete = %v, want %v", recipe.items(), want)
+	}
+	api.expect(http.StatusNotFound, "DELETE", "/recipe/deleteingredient"+path+"/"+removed, token, nil, nil)
+	api.expect(http.StatusBadRequest, "DELETE", "/recipe/deleteingredient"+path+"/not-an-id", token, nil, nil)
+
+	// A recipe holds at most 100 ingredients
+	lines := make([]string, 100)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d g flour", i+1)
+	}
+	var full struct {
+		Recipe created `json:"id"`
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", token, fiber.Map{"dish": "Bread", "ingredients": strings.Join(lines, "\n")}, &full)
+	api.expect(http.StatusBadRequest, "POST", "/recipe/postingredient/"+full.Recipe.ID, token, fiber.Map{"item": "salt"}, nil)
+}
@@ -0,0 +1,125 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestOwnership checks that another user can neither see, change nor delete the
+// documents of a user, and that wiping their own data leaves everyone else's alone
+func TestOwnership(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	aliceID, alice := api.signUp("alice@example.com", "5550001")
+	_, bob := api.signUp("bob@example.com", "5550002")
+
+	var todo created
+	api.expect(http.StatusOK, "POST", "/api/posttodo", alice, fiber.Map{
+		"task":       "Water the plants",
+		"due_at":     time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC),
+		"recurrence": fiber.Map{"rule": "FREQ=WEEKLY"},
+	}, &todo)
+	var recipe struct {
+		Recipe created `json:"id"`
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", alice, fiber.Map{"dish": "Omelette", "ingredients": "3 eggs"}, &recipe)
+	var plan created
+	api.expect(http.StatusOK, "POST", "/gym/postschedule", alice, fiber.Map{"name": "Push pull legs"}, &plan)
+	var entry created
+	api.expect(http.StatusOK, "POST", "/diary/postentry", alice, fiber.Map{
+		"date": "2026-03-02", "meal": "lunch", "name": "Soup", "per_serving": fiber.Map{"calories": 200},
+	}, &entry)
+
+	foreign := []struct {
+		method string
+		path   string
+		body   any
+	}{
+		{"GET", "/api/occurrences/" + todo.ID, nil},
+		{"PUT", "/api/puttodo/" + todo.ID, fiber.Map{"task": "Mine now"}},
+		{"PUT", "/api/completetodo/" + todo.ID, nil},
+		{"PUT", "/api/todostatus/" + todo.ID, fiber.Map{"status": "archived"}},
+		{"POST", "/api/postsubtask/" + todo.ID, fiber.Map{"title": "Sneaky"}},
+		{"DELETE", "/api/deleteonetodo/" + todo.ID, nil},
+
+		{"GET", "/recipe/scale/" + recipe.Recipe.ID + "?servings=4", nil},
+		{"PUT", "/recipe/putrecipe/" + recipe.Recipe.ID, fiber.Map{"dish": "Mine now", "ingredients": "1 egg"}},
+		{"PUT", "/recipe/putingredients/" + recipe.Recipe.ID, fiber.Map{"ingredients": "1 egg"}},
+		{"POST", "/recipe/postingredient/" + recipe.Recipe.ID, fiber.Map{"text": "1 egg"}},
+		{"DELETE", "/recipe/deleterecipe/" + recipe.Recipe.ID, nil},
+
+		{"PUT", "/gym/putschedule/" + plan.ID, fiber.Map{"name": "Mine now"}},
+		{"PUT", "/gym/activateschedule/" + plan.ID, nil},
+		{"DELETE", "/gym/deleteschedule/" + plan.ID, nil},
+
+		{"GET", "/diary/entry/" + entry.ID, nil},
+		{"PUT", "/diary/putentry/" + entry.ID, fiber.Map{"date": "2026-03-02", "meal": "lunch", "name": "Mine now"}},
+		{"DELETE", "/diary/deleteentry/" + entry.ID, nil},
+
+		{"PUT", "/api/change-password/" + aliceID, fiber.Map{"current_password": "password1", "new_password": "password2"}},
+	}
+	for _, request := range foreign {
+		api.expect(http.StatusNotFound, request.method, request.path, bob, request.body, nil)
+	}
+
+	var plans []created
+	api.expect(http.StatusOK, "GET", "/gym/schedule", bob, nil, &plans)
+	if len(plans) != 0 {
+		t.Fatalf("bob sees the gym plans of alice: %+v", plans)
+	}
+	api.expect(http.StatusNotFound, "GET", "/gym/activeschedule", bob, nil, nil)
+
+	api.expect(http.StatusOK, "POST", "/api/posttodo", bob, fiber.Map{"task": "Walk the dog"}, nil)
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", bob, fiber.Map{"dish": "Toast", "ingredients": "2 slices bread"}, nil)
+	var deleted struct {
+		Count int `json:"Count"`
+	}
+	var count int
+	api.expect(http.StatusOK, "DELETE", "/api/deletetodo", bob, nil, &count)
+	api.expect(http.StatusOK, "DELETE", "/recipe/deleterecipe", bob, nil, &deleted)
+	if count != 1 || deleted.Count != 1 {
+		t.Fatalf("bob deleted %d todos and %d recipes, want only their own one of each", count, deleted.Count)
+	}
+
+	// Everything alice owns is still there and unchanged
+	api.expect(http.StatusOK, "GET", "/api/occurrences/"+todo.ID, alice, nil, nil)
+	api.expect(http.StatusOK, "GET", "/recipe/scale/"+recipe.Recipe.ID+"?servings=4", alice, nil, nil)
+	var todos struct {
+		Items []struct {
+			created
+			Task   string `json:"task"`
+			Status string `json:"status"`
+		} `json:"items"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/gettodo", alice, nil, &todos)
+	if len(todos.Items) != 1 || todos.Items[0].ID != todo.ID || todos.Items[0].Task != "Water the plants" || todos.Items[0].Status != "open" {
+		t.Fatalf("todos of alice = %+v", todos.Items)
+	}
+	var recipes []struct {
+		created
+		Dish string `json:"dish"`
+	}
+	api.expect(http.StatusOK, "GET", "/recipe/getrecipe", alice, nil, &recipes)
+	if len(recipes) != 1 || recipes[0].ID != recipe.Recipe.ID || recipes[0].Dish != "Omelette" {
+		t.Fatalf("recipes of alice = %+v", recipes)
+	}
+	var active struct {
+		created
+		Name string `json:"name"`
+	}
+	api.expect(http.StatusOK, "GET", "/gym/activeschedule", alice, nil, &active)
+	if active.ID != plan.ID || active.Name != "Push pull legs" {
+		t.Fatalf("gym plan of alice = %+v", active)
+	}
+	var kept struct {
+		Name string `json:"name"`
+	}
+	api.expect(http.StatusOK, "GET", "/diary/entry/"+entry.ID, alice, nil, &kept)
+	if kept.Name != "Soup" {
+		t.Fatalf("diary entry of alice = %+v", kept)
+	}
+	api.expect(http.StatusOK, "PUT", "/api/change-password/"+aliceID, alice, fiber.Map{"current_password": "password1", "new_password": "password2"}, nil)
+}
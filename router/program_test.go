@@ -0,0 +1,100 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository/memory"
+)
+
+// lockstepGym makes the requests loading a plan wait for each other, so that each
+// has read the plan before either saves it
+type lockstepGym struct {
+	repository.GymRepository
+	readers *sync.WaitGroup
+}
+
+func (g *lockstepGym) Get(ctx context.Context, uid string, id string) (*models.Gym, error) {
+	plan, err := g.GymRepository.Get(ctx, uid, id)
+	if g.readers != nil {
+		g.readers.Done()
+		g.readers.Wait()
+	}
+	return plan, err
+}
+
+func TestProgramProgression(t *testing.T) {
+	t.Parallel()
+	repos := memory.New()
+	gym := &lockstepGym{GymRepository: repos.Gym}
+	repos.Gym = gym
+	api := newTestAPIWith(t, repos)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	template, _ := models.FindProgramTemplate("5x5")
+	weights := fiber.Map{}
+	for _, slot := range template.Slots() {
+		weights[slot.Exercise_id.Hex()] = 60
+	}
+	var plan created
+	api.expect(http.StatusOK, "POST", "/gym/startprogram", token, fiber.Map{"template": "5x5", "weights": weights}, &plan)
+
+	// Two workouts logged at once both count: each squats every prescribed set, so
+	// the squat progresses twice
+	squat := models.CatalogID("Squat").Hex()
+	sets := make([]fiber.Map, 5)
+	for i := range sets {
+		sets[i] = fiber.Map{"reps": 5, "weight": 100}
+	}
+	workout := fiber.Map{
+		"performed_at": "2026-03-02T18:00:00Z",
+		"plan_id":      plan.ID,
+		"exercises":    []fiber.Map{{"exercise_id": squat, "sets": sets}},
+	}
+	var wg sync.WaitGroup
+	statuses := make([]int, 2)
+	gym.readers = &sync.WaitGroup{}
+	gym.readers.Add(len(statuses))
+	for i := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = api.do("POST", "/gym/postworkout", token, workout, nil)
+		}()
+	}
+	wg.Wait()
+	gym.readers = nil
+	for _, status := range statuses {
+		if status != http.StatusOK {
+			t.Fatalf("logging workouts at once answered %v", statuses)
+		}
+	}
+
+	var active struct {
+		Program struct {
+			Sessions_done int `json:"sessions_done"`
+			Lifts         []struct {
+				Exercise string  `json:"exercise"`
+				Weight   float64 `json:"weight"`
+			} `json:"lifts"`
+		} `json:"program"`
+	}
+	api.expect(http.StatusOK, "GET", "/gym/activeschedule", token, nil, &active)
+	if active.Program.Sessions_done != 2 {
+		t.Errorf("sessions done = %d, want 2", active.Program.Sessions_done)
+	}
+	for _, lift := range active.Program.Lifts {
+		want := 60.0
+		if lift.Exercise == "Squat" {
+			want = 65
+		}
+		if lift.Weight != want {
+			t.Errorf("%s is at %v kg, want %v", lift.Exercise, lift.Weight, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// post sends data as the raw request body and decodes the response into out when
+// out is not nil. It returns the status code.
+func (a *testAPI) post(path string, token string, contentType string, data []byte, out any) int {
+	a.t.Helper()
+	req := httptest.NewRequest("POST", path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("token", token)
+	resp, err := a.app.Test(req, -1)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(body, out); err != nil {
+			a.t.Fatalf("POST %s: decoding %s: %v", path, body, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestImportRecipe(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	// A recipe listing more than a recipe holds is imported cut down to size
+	lines := make([]string, 105)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%q", fmt.Sprintf("%d g flour", i+1))
+	}
+	page := fmt.Sprintf(`<html><script type="application/ld+json">{"@context": "https://schema.org",
+		"@graph": [{"@type": ["Recipe"], "name": "Bread", "recipeYield": "Serves 4-6",
+		"recipeIngredient": [%s], "recipeInstructions": [{"@type": "HowToStep", "text": %q}]}]}</script></html>`,
+		strings.Join(lines, ","), strings.Repeat("Knead. ", 1000))
+	var imported struct {
+		Recipe struct {
+			created
+			Servings     int        `json:"servings"`
+			Ingredients  []struct{} `json:"ingredients"`
+			Instructions []string   `json:"instructions"`
+		} `json:"id"`
+		Unmapped []string `json:"unmapped"`
+	}
+	if status := api.post("/recipe/importrecipe", token, "text/html", []byte(page), &imported); status != http.StatusOK {
+		t.Fatalf("importing an oversized recipe answered %d", status)
+	}
+	recipe := imported.Recipe
+	if recipe.ID == "" || recipe.Servings != 4 || len(recipe.Ingredients) != 100 || len(recipe.Instructions) != 1 || len(recipe.Instructions[0]) != 5000 {
+		t.Errorf("imported %d servings, %d ingredients and %d steps", recipe.Servings, len(recipe.Ingredients), len(recipe.Instructions))
+	}
+	if len(imported.Unmapped) != 6 {
+		t.Errorf("unmapped = %v, want the 5 ingredients left out and the step cut short", imported.Unmapped)
+	}
+
+	// Documents without a recipe, and bodies over 4 MB, are turned down
+	if status := api.post("/recipe/importrecipe", token, "application/ld+json", []byte(`{"@type": "Person"}`), nil); status != http.StatusBadRequest {
+		t.Errorf("importing a document without a recipe answered %d", status)
+	}
+	huge := append([]byte(`{"@type": "Recipe", "name": "`), bytes.Repeat([]byte("a"), 4<<20)...)
+	huge = append(huge, `"}`...)
+	if status := api.post("/recipe/importrecipe", token, "application/ld+json", huge, nil); status != http.StatusBadRequest {
+		t.Errorf("importing a body over 4 MB answered %d", status)
+	}
+	if status := api.post("/recipe/importrecipe", token, "application/ld+json", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("importing an empty body answered %d", status)
+	}
+}
@@ -0,0 +1,92 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository/memory"
+)
+
+// flakyOccurrences fails to create the next occurrence of a series while failing is set
+type flakyOccurrences struct {
+	repository.TodoRepository
+	failing bool
+}
+
+func (f *flakyOccurrences) CreateOccurrence(ctx context.Context, todo *models.ToDoList) (bool, error) {
+	if f.failing {
+		return false, errors.New("connection reset")
+	}
+	return f.TodoRepository.CreateOccurrence(ctx, todo)
+}
+
+func TestRecurringTodos(t *testing.T) {
+	t.Parallel()
+	repos := memory.New()
+	todos := &flakyOccurrences{TodoRepository: repos.Todos}
+	repos.Todos = todos
+	api := newTestAPIWith(t, repos)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var todo created
+	api.expect(http.StatusOK, "POST", "/api/posttodo", token, fiber.Map{
+		"task":       "Water the plants",
+		"due_at":     "2026-03-02T09:00:00Z",
+		"recurrence": fiber.Map{"rule": "FREQ=WEEKLY;COUNT=2"},
+	}, &todo)
+
+	// Completing fails to create the next occurrence, and completing again retries it
+	todos.failing = true
+	api.expect(http.StatusInternalServerError, "PUT", "/api/completetodo/"+todo.ID, token, nil, nil)
+	todos.failing = false
+	var completed struct {
+		Status string `json:"status"`
+		Next   *struct {
+			created
+			Due_at string `json:"due_at"`
+		} `json:"next_occurrence"`
+	}
+	api.expect(http.StatusOK, "PUT", "/api/completetodo/"+todo.ID, token, nil, &completed)
+	if completed.Status != "done" || completed.Next == nil || completed.Next.Due_at != "2026-03-09T09:00:00Z" {
+		t.Fatalf("retried completion = %+v, want the occurrence of 2026-03-09", completed)
+	}
+
+	// Once the next occurrence exists, completing again creates no second one
+	completed.Next = nil
+	api.expect(http.StatusOK, "PUT", "/api/completetodo/"+todo.ID, token, nil, &completed)
+	if completed.Next != nil {
+		t.Errorf("completing again created another occurrence: %+v", completed.Next)
+	}
+	var page struct {
+		Items []created `json:"items"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/gettodo", token, nil, &page)
+	if len(page.Items) != 2 {
+		t.Errorf("got %d todos, want the completed one and its next occurrence", len(page.Items))
+	}
+
+	// The series ends after its second todo, so completing it, even twice, creates nothing
+	last := page.Items[0].ID
+	if last == todo.ID {
+		last = page.Items[1].ID
+	}
+	api.expect(http.StatusOK, "PUT", "/api/completetodo/"+last, token, nil, &completed)
+	if completed.Next != nil {
+		t.Errorf("completing the last todo of the series created %+v", completed.Next)
+	}
+	api.expect(http.StatusOK, "PUT", "/api/completetodo/"+last, token, nil, &completed)
+	if completed.Next != nil {
+		t.Errorf("completing the last todo of the series again created %+v", completed.Next)
+	}
+
+	// A todo that does not repeat has nothing to retry
+	var plain created
+	api.expect(http.StatusOK, "POST", "/api/posttodo", token, fiber.Map{"task": "Buy milk"}, &plain)
+	api.expect(http.StatusOK, "PUT", "/api/completetodo/"+plain.ID, token, nil, nil)
+	api.expect(http.StatusConflict, "PUT", "/api/completetodo/"+plain.ID, token, nil, nil)
+}
@@ -3,50 +3,160 @@ package router
 import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/khanirfan96/To-do-Fullstack-server/apperror"
 	controller "github.com/khanirfan96/To-do-Fullstack-server/controller"
 	"github.com/khanirfan96/To-do-Fullstack-server/middleware"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
 )
 
-func Router() *fiber.App {
-	app := fiber.New()
+func Router(repos repository.Repositories) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: apperror.Handler,
+		BodyLimit:    middleware.BodyLimit,
+	})
 
+	app.Use(recover.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
 		AllowHeaders: "*",
 	}))
 
-	app.Post("/users/signup", controller.SignUp())
-	app.Post("/users/login", controller.Login())
+	app.Post("/users/signup", controller.SignUp(repos.Users))
+	app.Post("/users/login", controller.Login(repos.Users))
+	app.Post("/users/refresh", controller.Refresh(repos.Users))
+	app.Post("/users/logout", middleware.Authentication(repos.Users), controller.Logout(repos.Users))
+	app.Post("/users/logout-all", middleware.Authentication(repos.Users), controller.LogoutEverywhere(repos.Users))
 
-	api := app.Group("/api", middleware.Authentication())
-	recipeapi := app.Group("/recipe", middleware.Authentication())
-	gymapi := app.Group("/gym", middleware.Authentication())
+	api := app.Group("/api", middleware.Authentication(repos.Users))
+	recipeapi := app.Group("/recipe", middleware.Authentication(repos.Users))
+	gymapi := app.Group("/gym", middleware.Authentication(repos.Users))
+	bodyapi := app.Group("/body", middleware.Authentication(repos.Users))
+	diaryapi := app.Group("/diary", middleware.Authentication(repos.Users))
 
 	// *********************** changepassword routes ******************************
 
-	api.Put("/change-password/:id", middleware.UpdatePassword)
+	api.Put("/change-password", middleware.UpdatePassword(repos.Users))
+	api.Put("/change-password/:id", middleware.UpdatePassword(repos.Users))
 
 	// *********************** todo routes ******************************
 
-	api.Get("/gettodo", middleware.GetTodo)
-	api.Post("/posttodo", middleware.CreateTodo)
-	api.Put("/puttodo/:id", middleware.UpdateTodo)
-	api.Put("/undotodo/:id", middleware.UndoTodo)
-	api.Delete("/deleteonetodo/:id", middleware.DeleteOneTodo)
-	api.Delete("/deletetodo", middleware.DeleteAllTodo)
+	api.Get("/gettodo", middleware.GetTodo(repos.Todos))
+	api.Post("/posttodo", middleware.CreateTodo(repos.Todos, repos.Projects))
+	api.Put("/puttodo/:id", middleware.UpdateTodo(repos.Todos, repos.Projects))
+	api.Put("/movetodos", middleware.MoveTodos(repos.Todos, repos.Projects))
+	api.Put("/undotodo/:id", middleware.TransitionTodo(repos.Todos, models.StatusOpen))
+	api.Put("/starttodo/:id", middleware.TransitionTodo(repos.Todos, models.StatusInProgress))
+	api.Put("/completetodo/:id", middleware.TransitionTodo(repos.Todos, models.StatusDone))
+	api.Put("/reopentodo/:id", middleware.TransitionTodo(repos.Todos, models.StatusOpen))
+	api.Put("/archivetodo/:id", middleware.TransitionTodo(repos.Todos, models.StatusArchived))
+	api.Put("/todostatus/:id", middleware.SetTodoStatus(repos.Todos))
+	api.Get("/occurrences/:id", middleware.GetOccurrences(repos.Todos))
+	api.Post("/postsubtask/:id", middleware.CreateSubtask(repos.Todos))
+	api.Put("/putsubtask/:id/:subid", middleware.UpdateSubtask(repos.Todos))
+	api.Put("/reordersubtasks/:id", middleware.ReorderSubtasks(repos.Todos))
+	api.Delete("/deletesubtask/:id/:subid", middleware.DeleteSubtask(repos.Todos))
+	api.Delete("/deleteonetodo/:id", middleware.DeleteOneTodo(repos.Todos))
+	api.Delete("/deletetodo", middleware.DeleteAllTodo(repos.Todos))
+
+	// *********************** project routes ******************************
+
+	api.Get("/getprojects", middleware.GetProjects(repos.Projects))
+	api.Post("/postproject", middleware.CreateProject(repos.Projects))
+	api.Put("/putproject/:id", middleware.UpdateProject(repos.Projects))
+	api.Put("/reorderprojects", middleware.ReorderProjects(repos.Projects))
+	api.Delete("/deleteproject/:id", middleware.DeleteProject(repos.Projects, repos.Todos))
+
+	// *********************** tag routes ******************************
+
+	api.Get("/gettags", middleware.GetTags(repos.Todos))
+	api.Put("/puttag/:tag", middleware.RenameTag(repos.Todos))
+	api.Delete("/deletetag/:tag", middleware.DeleteTag(repos.Todos))
 
 	// *********************** recipe routes ******************************
 
-	recipeapi.Get("/getrecipe", middleware.GetRecipe)
-	recipeapi.Post("/postrecipe", middleware.CreateRecipe)
-	recipeapi.Put("/putrecipe/:id", middleware.UpdateRecipe)
-	recipeapi.Put("/putingredients/:id", middleware.UpdateIngredeints)
-	recipeapi.Delete("/deleterecipe/:id", middleware.DeleteOneRecipe)
-	recipeapi.Delete("/deleterecipe", middleware.DeleteAllRecipe)
+	recipeapi.Get("/getrecipe", middleware.GetRecipe(repos.Recipes))
+	recipeapi.Get("/foods", middleware.GetFoods(repos.Foods))
+	recipeapi.Get("/scale/:id", middleware.ScaleRecipe(repos.Recipes, repos.Foods))
+	recipeapi.Post("/shoppinglist", middleware.CreateShoppingList(repos.Recipes, repos.Foods, repos.Projects, repos.Todos))
+	recipeapi.Post("/postrecipe", middleware.CreateRecipe(repos.Recipes, repos.Foods))
+	recipeapi.Post("/importrecipe", middleware.ImportRecipe(repos.Recipes, repos.Foods))
+	recipeapi.Put("/putrecipe/:id", middleware.UpdateRecipe(repos.Recipes, repos.Foods))
+	recipeapi.Put("/putingredients/:id", middleware.UpdateIngredeints(repos.Recipes, repos.Foods))
+	recipeapi.Post("/postingredient/:id", middleware.CreateIngredient(repos.Recipes, repos.Foods))
+	recipeapi.Put("/reorderingredients/:id", middleware.ReorderIngredients(repos.Recipes))
+	recipeapi.Delete("/deleteingredient/:id/:ingredientid", middleware.DeleteIngredient(repos.Recipes, repos.Foods))
+	recipeapi.Delete("/deleterecipe/:id", middleware.DeleteOneRecipe(repos.Recipes))
+	recipeapi.Delete("/deleterecipe", middleware.DeleteAllRecipe(repos.Recipes))
 
 	// *********************** gym routes ******************************
-	gymapi.Get("/schedule", middleware.GetGym)
+
+	gymapi.Get("/schedule", middleware.GetGym(repos.Gym))
+	gymapi.Get("/activeschedule", middleware.GetActiveGym(repos.Gym))
+	gymapi.Post("/postschedule", middleware.CreateGym(repos.Gym, repos.Exercises))
+	gymapi.Put("/putschedule/:id", middleware.UpdateGym(repos.Gym, repos.Exercises))
+	gymapi.Put("/activateschedule/:id", middleware.ActivateGym(repos.Gym))
+	gymapi.Delete("/deleteschedule/:id", middleware.DeleteGym(repos.Gym))
+
+	// *********************** program routes ******************************
+
+	gymapi.Get("/programs", middleware.GetPrograms())
+	gymapi.Post("/startprogram", middleware.StartProgram(repos.Gym, repos.Workouts))
+	gymapi.Get("/nextsession", middleware.GetNextSession(repos.Gym))
+
+	// *********************** exercise routes ******************************
+
+	gymapi.Get("/exercises", middleware.GetExercises(repos.Exercises))
+	gymapi.Get("/exercisefilters", middleware.GetExerciseFilters())
+	gymapi.Get("/exercise/:id", middleware.GetExercise(repos.Exercises))
+	gymapi.Post("/postexercise", middleware.CreateExercise(repos.Exercises))
+	gymapi.Put("/putexercise/:id", middleware.UpdateExercise(repos.Exercises))
+	gymapi.Delete("/deleteexercise/:id", middleware.DeleteExercise(repos.Exercises))
+
+	// *********************** workout routes ******************************
+
+	gymapi.Get("/workouts", middleware.GetWorkouts(repos.Workouts))
+	gymapi.Get("/workout/:id", middleware.GetWorkout(repos.Workouts))
+	gymapi.Post("/postworkout", middleware.CreateWorkout(repos.Workouts, repos.Gym, repos.Exercises))
+	gymapi.Put("/putworkout/:id", middleware.UpdateWorkout(repos.Workouts, repos.Gym, repos.Exercises))
+	gymapi.Delete("/deleteworkout/:id", middleware.DeleteWorkout(repos.Workouts))
+	gymapi.Get("/records", middleware.GetRecords(repos.Workouts))
+
+	// *********************** analytics routes ******************************
+
+	gymapi.Get("/analytics/volume", middleware.GetMuscleVolume(repos.Workouts))
+	gymapi.Get("/analytics/e1rm", middleware.GetStrengthTrends(repos.Workouts))
+	gymapi.Get("/analytics/frequency", middleware.GetTrainingFrequency(repos.Workouts))
+	gymapi.Get("/analytics/balance", middleware.GetMuscleBalance(repos.Workouts))
+
+	// *********************** body routes ******************************
+
+	bodyapi.Get("/metrics", middleware.GetBodyMetrics(repos.Body))
+	bodyapi.Get("/metric/:id", middleware.GetBodyMetric(repos.Body))
+	bodyapi.Post("/postmetric", middleware.CreateBodyMetric(repos.Body))
+	bodyapi.Put("/putmetric/:id", middleware.UpdateBodyMetric(repos.Body))
+	bodyapi.Delete("/deletemetric/:id", middleware.DeleteBodyMetric(repos.Body))
+	bodyapi.Get("/trend", middleware.GetBodyTrend(repos.Body))
+	bodyapi.Get("/goal", middleware.GetBodyGoal(repos.Users, repos.Body))
+	bodyapi.Put("/goal", middleware.SetBodyGoal(repos.Users, repos.Body))
+	bodyapi.Delete("/goal", middleware.DeleteBodyGoal(repos.Users))
+
+	// *********************** diary routes ******************************
+
+	diaryapi.Get("/day", middleware.GetDiaryDay(repos.Diary, repos.Users, repos.Body))
+	diaryapi.Get("/days", middleware.GetDiaryDays(repos.Diary, repos.Users, repos.Body))
+	diaryapi.Get("/entry/:id", middleware.GetFoodEntry(repos.Diary))
+	diaryapi.Post("/postentry", middleware.CreateFoodEntry(repos.Diary, repos.Recipes, repos.Foods))
+	diaryapi.Put("/putentry/:id", middleware.UpdateFoodEntry(repos.Diary, repos.Recipes, repos.Foods))
+	diaryapi.Delete("/deleteentry/:id", middleware.DeleteFoodEntry(repos.Diary))
+	diaryapi.Get("/targets", middleware.GetNutritionTargets(repos.Users))
+	diaryapi.Put("/targets", middleware.SetNutritionTargets(repos.Users))
+	diaryapi.Delete("/targets", middleware.DeleteNutritionTargets(repos.Users))
+	diaryapi.Get("/goal", middleware.GetNutritionGoal(repos.Users, repos.Body))
+	diaryapi.Put("/goal", middleware.SetNutritionGoal(repos.Users, repos.Body))
+	diaryapi.Delete("/goal", middleware.DeleteNutritionGoal(repos.Users))
 
 	return app
 }
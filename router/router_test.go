@@ -0,0 +1,300 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository/memory"
+	"github.com/khanirfan96/To-do-Fullstack-server/router"
+)
+
+// testAPI drives the real router backed by the in-memory repositories
+type testAPI struct {
+	t   *testing.T
+	app *fiber.App
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	return newTestAPIWith(t, memory.New())
+}
+
+// newTestAPIWith drives the router backed by repos, for tests swapping in a
+// repository that misbehaves
+func newTestAPIWith(t *testing.T, repos repository.Repositories) *testAPI {
+	return &testAPI{t: t, app: router.Router(repos)}
+}
+
+// do sends body as JSON and decodes the response into out when out is not nil.
+// It returns the status code.
+func (a *testAPI) do(method string, path string, token string, body any, out any) int {
+	a.t.Helper()
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		payload = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("token", token)
+	}
+	resp, err := a.app.Test(req, -1)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(data, out); err != nil {
+			a.t.Fatalf("%s %s: decoding %s: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// expect sends a request and fails the test unless it answers with status
+func (a *testAPI) expect(status int, method string, path string, token string, body any, out any) {
+	a.t.Helper()
+	if got := a.do(method, path, token, body, out); got != status {
+		a.t.Fatalf("%s %s: got status %d, want %d", method, path, got, status)
+	}
+}
+
+// signUp registers a user, logs them in and returns their user id and access token
+func (a *testAPI) signUp(email string, phone string) (string, string) {
+	a.t.Helper()
+	a.expect(http.StatusOK, "POST", "/users/signup", "", fiber.Map{
+		"first_name": "Test",
+		"last_name":  "User",
+		"password":   "password1",
+		"email":      email,
+		"phone":      phone,
+	}, nil)
+	var login struct {
+		User struct {
+			User_id string `json:"user_id"`
+			Token   string `json:"token"`
+		} `json:"user"`
+	}
+	a.expect(http.StatusOK, "POST", "/users/login", "", fiber.Map{"email": email, "password": "password1"}, &login)
+	if login.User.Token == "" || login.User.User_id == "" {
+		a.t.Fatalf("login of %s returned no token", email)
+	}
+	return login.User.User_id, login.User.Token
+}
+
+type created struct {
+	ID string `json:"_id"`
+}
+
+func TestAuthentication(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	api.expect(http.StatusConflict, "POST", "/users/signup", "", fiber.Map{
+		"first_name": "Test", "last_name": "User", "password": "password1",
+		"email": "ada@example.com", "phone": "5550002",
+	}, nil)
+	api.expect(http.StatusUnauthorized, "POST", "/users/login", "", fiber.Map{"email": "ada@example.com", "password": "wrong-password"}, nil)
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", "", nil, nil)
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", "not-a-token", nil, nil)
+	api.expect(http.StatusOK, "GET", "/api/gettodo", token, nil, nil)
+
+	api.expect(http.StatusOK, "POST", "/users/logout", token, nil, nil)
+	api.expect(http.StatusUnauthorized, "GET", "/api/gettodo", token, nil, nil)
+}
+
+func TestTodos(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var todo struct {
+		created
+		Task   string `json:"task"`
+		Status string `json:"status"`
+	}
+	api.expect(http.StatusOK, "POST", "/api/posttodo", token, fiber.Map{"task": "Buy milk", "tags": []string{"Errands"}}, &todo)
+	if todo.ID == "" || todo.Task != "Buy milk" || todo.Status != "open" {
+		t.Fatalf("created todo = %+v", todo)
+	}
+	api.expect(http.StatusBadRequest, "POST", "/api/posttodo", token, fiber.Map{"task": "Bad", "status": "someday"}, nil)
+
+	api.expect(http.StatusBadRequest, "PUT", "/api/puttodo/"+todo.ID, token, fiber.Map{"task": "  "}, nil)
+	api.expect(http.StatusBadRequest, "PUT", "/api/puttodo/"+todo.ID, token, fiber.Map{"reminders": []int{30}}, nil)
+	api.expect(http.StatusOK, "PUT", "/api/puttodo/"+todo.ID, token, fiber.Map{"due_at": "2026-03-02T09:00:00Z", "reminders": []int{30}}, nil)
+	api.expect(http.StatusOK, "PUT", "/api/puttodo/"+todo.ID, token, fiber.Map{"reminders": []int{60, 10}}, nil)
+	api.expect(http.StatusOK, "PUT", "/api/puttodo/"+todo.ID, token, fiber.Map{"task": " Buy oat milk "}, nil)
+	api.expect(http.StatusOK, "PUT", "/api/completetodo/"+todo.ID, token, nil, &todo)
+	if todo.Status != "done" {
+		t.Fatalf("completed todo has status %q", todo.Status)
+	}
+	api.expect(http.StatusConflict, "PUT", "/api/starttodo/"+todo.ID, token, nil, nil)
+
+	var page struct {
+		Items []struct {
+			Task string   `json:"task"`
+			Tags []string `json:"tags"`
+		} `json:"items"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/gettodo?status=done", token, nil, &page)
+	if len(page.Items) != 1 || page.Items[0].Task != "Buy oat milk" || len(page.Items[0].Tags) != 1 || page.Items[0].Tags[0] != "errands" {
+		t.Fatalf("listed todos = %+v", page.Items)
+	}
+
+	var other created
+	api.expect(http.StatusOK, "POST", "/api/posttodo", token, fiber.Map{"task": "Call the bank"}, &other)
+	for query, want := range map[string]int{"milk": 1, "MILK,+oat": 1, "mil": 0, "milk+bank": 0, "the": 1} {
+		api.expect(http.StatusOK, "GET", "/api/gettodo?q="+query, token, nil, &page)
+		if len(page.Items) != want {
+			t.Errorf("searching for %q found %d todos, want %d", query, len(page.Items), want)
+		}
+	}
+	api.expect(http.StatusOK, "DELETE", "/api/deleteonetodo/"+other.ID, token, nil, nil)
+
+	api.expect(http.StatusOK, "DELETE", "/api/deleteonetodo/"+todo.ID, token, nil, nil)
+	api.expect(http.StatusNotFound, "DELETE", "/api/deleteonetodo/"+todo.ID, token, nil, nil)
+	api.expect(http.StatusOK, "GET", "/api/gettodo", token, nil, &page)
+	if len(page.Items) != 0 {
+		t.Fatalf("todos left after delete: %+v", page.Items)
+	}
+}
+
+func TestRecipes(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var response struct {
+		Recipe struct {
+			created
+			Servings  int `json:"servings"`
+			Nutrition struct {
+				Calories float64 `json:"calories"`
+				Source   string  `json:"source"`
+			} `json:"nutrition"`
+		} `json:"id"`
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", token, fiber.Map{
+		"dish":        "Omelette",
+		"servings":    2,
+		"ingredients": "3 eggs\n20 g butter",
+	}, &response)
+	recipe := response.Recipe
+	if recipe.ID == "" || recipe.Servings != 2 || recipe.Nutrition.Calories <= 0 || recipe.Nutrition.Source != "calculated" {
+		t.Fatalf("created recipe = %+v", recipe)
+	}
+
+	api.expect(http.StatusOK, "PUT", "/recipe/putrecipe/"+recipe.ID, token, fiber.Map{
+		"dish":        "Big omelette",
+		"servings":    2,
+		"ingredients": "4 eggs\n20 g butter",
+	}, nil)
+	var recipes []struct {
+		Dish        string `json:"dish"`
+		Ingredients []struct {
+			Item string `json:"item"`
+		} `json:"ingredients"`
+	}
+	api.expect(http.StatusOK, "GET", "/recipe/getrecipe", token, nil, &recipes)
+	if len(recipes) != 1 || recipes[0].Dish != "Big omelette" || len(recipes[0].Ingredients) != 2 {
+		t.Fatalf("listed recipes = %+v", recipes)
+	}
+
+	api.expect(http.StatusOK, "DELETE", "/recipe/deleterecipe/"+recipe.ID, token, nil, nil)
+	api.expect(http.StatusOK, "GET", "/recipe/getrecipe", token, nil, &recipes)
+	if len(recipes) != 0 {
+		t.Fatalf("recipes left after delete: %+v", recipes)
+	}
+}
+
+func TestDiary(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var entry struct {
+		created
+		Total struct {
+			Calories float64 `json:"calories"`
+		} `json:"total"`
+	}
+	api.expect(http.StatusOK, "POST", "/diary/postentry", token, fiber.Map{
+		"date":        "2026-03-02",
+		"meal":        "breakfast",
+		"name":        "Porridge",
+		"servings":    2,
+		"per_serving": fiber.Map{"calories": 150, "protein": 5},
+	}, &entry)
+	if entry.ID == "" || entry.Total.Calories != 300 {
+		t.Fatalf("created entry = %+v", entry)
+	}
+	api.expect(http.StatusBadRequest, "POST", "/diary/postentry", token, fiber.Map{"date": "2026-03-02", "meal": "brunch", "name": "Toast"}, nil)
+	api.expect(http.StatusOK, "PUT", "/diary/targets", token, fiber.Map{"calories": 2000}, nil)
+
+	var day struct {
+		Date  string `json:"date"`
+		Total struct {
+			Calories float64 `json:"calories"`
+			Protein  float64 `json:"protein"`
+		} `json:"total"`
+		Remaining struct {
+			Calories float64 `json:"calories"`
+		} `json:"remaining"`
+	}
+	api.expect(http.StatusOK, "GET", "/diary/day?date=2026-03-02", token, nil, &day)
+	if day.Date != "2026-03-02" || day.Total.Calories != 300 || day.Total.Protein != 10 || day.Remaining.Calories != 1700 {
+		t.Fatalf("diary day = %+v", day)
+	}
+
+	api.expect(http.StatusOK, "DELETE", "/diary/deleteentry/"+entry.ID, token, nil, nil)
+	api.expect(http.StatusNotFound, "GET", "/diary/entry/"+entry.ID, token, nil, nil)
+}
+
+func TestGym(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var first, second struct {
+		created
+		Name   string `json:"name"`
+		Active bool   `json:"active"`
+	}
+	api.expect(http.StatusOK, "POST", "/gym/postschedule", token, fiber.Map{"name": "Push pull legs", "monday": "Push"}, &first)
+	api.expect(http.StatusOK, "POST", "/gym/postschedule", token, fiber.Map{"name": "Full body"}, &second)
+	if !first.Active || second.Active {
+		t.Fatalf("only the first plan should be active: %+v, %+v", first, second)
+	}
+	api.expect(http.StatusBadRequest, "POST", "/gym/postschedule", token, fiber.Map{"monday": "No name"}, nil)
+
+	api.expect(http.StatusOK, "PUT", "/gym/putschedule/"+second.ID, token, fiber.Map{"name": "Full body 3x"}, nil)
+	api.expect(http.StatusOK, "PUT", "/gym/activateschedule/"+second.ID, token, nil, nil)
+	var active struct {
+		created
+		Name string `json:"name"`
+	}
+	api.expect(http.StatusOK, "GET", "/gym/activeschedule", token, nil, &active)
+	if active.ID != second.ID || active.Name != "Full body 3x" {
+		t.Fatalf("active plan = %+v", active)
+	}
+
+	api.expect(http.StatusOK, "DELETE", "/gym/deleteschedule/"+second.ID, token, nil, nil)
+	api.expect(http.StatusOK, "GET", "/gym/activeschedule", token, nil, &active)
+	if active.ID != first.ID {
+		t.Fatalf("deleting the active plan should promote %s, got %s", first.ID, active.ID)
+	}
+}
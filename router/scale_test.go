@@ -0,0 +1,77 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestScaleRecipe(t *testing.T) {
+	t.Parallel()
+	api := newTestAPI(t)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var response struct {
+		Recipe created `json:"id"`
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", token, fiber.Map{
+		"dish":        "Pancakes",
+		"servings":    2,
+		"ingredients": "200 g flour\n1 cup milk\n2 eggs\n1 cup ground beef",
+	}, &response)
+	path := "/recipe/scale/" + response.Recipe.ID
+
+	type amount struct {
+		Quantity float64 `json:"quantity"`
+		Unit     string  `json:"unit"`
+		Item     string  `json:"item"`
+	}
+	var scaled struct {
+		Servings          int      `json:"servings"`
+		Original_servings int      `json:"original_servings"`
+		Factor            float64  `json:"factor"`
+		Ingredients       []amount `json:"ingredients"`
+		Unconverted       []string `json:"unconverted"`
+	}
+	check := func(query string, want []amount, unconverted int) {
+		t.Helper()
+		scaled.Unconverted = nil
+		api.expect(http.StatusOK, "GET", path+query, token, nil, &scaled)
+		if len(scaled.Ingredients) != len(want) {
+			t.Fatalf("%s: got %+v, want %+v", query, scaled.Ingredients, want)
+		}
+		for i := range want {
+			if scaled.Ingredients[i] != want[i] {
+				t.Errorf("%s: ingredient %d = %+v, want %+v", query, i, scaled.Ingredients[i], want[i])
+			}
+		}
+		if len(scaled.Unconverted) != unconverted {
+			t.Errorf("%s: unconverted = %v, want %d of them", query, scaled.Unconverted, unconverted)
+		}
+	}
+
+	check("?servings=4", []amount{{400, "g", "flour"}, {2, "cup", "milk"}, {4, "", "eggs"}, {2, "cup", "ground beef"}}, 0)
+	if scaled.Servings != 4 || scaled.Original_servings != 2 || scaled.Factor != 2 {
+		t.Errorf("scaled %d servings to %d by %v, want 2 to 4 by 2", scaled.Original_servings, scaled.Servings, scaled.Factor)
+	}
+	check("?servings=3&units=metric", []amount{{300, "g", "flour"}, {355, "ml", "milk"}, {3, "", "eggs"}, {355, "ml", "ground beef"}}, 0)
+	check("?servings=4&units=imperial", []amount{{14, "oz", "flour"}, {2, "cup", "milk"}, {4, "", "eggs"}, {2, "cup", "ground beef"}}, 0)
+	// Milk has a density and is weighed; ground beef has none and stays a volume
+	check("?servings=4&units=imperial&weigh=true", []amount{{14, "oz", "flour"}, {1.07, "lb", "milk"}, {4, "", "eggs"}, {2, "cup", "ground beef"}}, 1)
+	check("?units=metric&weigh=true", []amount{{200, "g", "flour"}, {244, "g", "milk"}, {2, "", "eggs"}, {1, "cup", "ground beef"}}, 1)
+
+	for _, query := range []string{"?servings=0.5", "?servings=1001", "?units=cups", "?weigh=true"} {
+		api.expect(http.StatusBadRequest, "GET", path+query, token, nil, nil)
+	}
+
+	// Scaling only shows the recipe scaled; the stored one keeps its amounts
+	var recipes []struct {
+		Servings    int      `json:"servings"`
+		Ingredients []amount `json:"ingredients"`
+	}
+	api.expect(http.StatusOK, "GET", "/recipe/getrecipe", token, nil, &recipes)
+	if len(recipes) != 1 || recipes[0].Servings != 2 || recipes[0].Ingredients[0] != (amount{200, "g", "flour"}) || recipes[0].Ingredients[1] != (amount{1, "cup", "milk"}) {
+		t.Errorf("stored recipe after scaling = %+v", recipes)
+	}
+}
@@ -0,0 +1,98 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/khanirfan96/To-do-Fullstack-server/models"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository"
+	"github.com/khanirfan96/To-do-Fullstack-server/repository/memory"
+)
+
+// failingTodos creates the first left todos and then fails
+type failingTodos struct {
+	repository.TodoRepository
+	left int
+}
+
+func (f *failingTodos) Create(ctx context.Context, todo *models.ToDoList) error {
+	if f.left == 0 {
+		return errors.New("disk full")
+	}
+	f.left--
+	return f.TodoRepository.Create(ctx, todo)
+}
+
+func TestShoppingList(t *testing.T) {
+	t.Parallel()
+	repos := memory.New()
+	todos := &failingTodos{TodoRepository: repos.Todos, left: -1}
+	repos.Todos = todos
+	api := newTestAPIWith(t, repos)
+	_, token := api.signUp("ada@example.com", "5550001")
+
+	var pancakes, bread struct {
+		Recipe created `json:"id"`
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", token, fiber.Map{"dish": "Pancakes", "servings": 2, "ingredients": "200 g flour\n2 eggs\n1 cup milk"}, &pancakes)
+	api.expect(http.StatusOK, "POST", "/recipe/postrecipe", token, fiber.Map{"dish": "Bread", "ingredients": "500 g flour\n1 egg\n3 cloves garlic"}, &bread)
+
+	type item struct {
+		Item     string   `json:"item"`
+		Quantity float64  `json:"quantity"`
+		Unit     string   `json:"unit"`
+		Recipes  []string `json:"recipes"`
+	}
+	var list struct {
+		Aisles []struct {
+			Aisle string `json:"aisle"`
+			Items []item `json:"items"`
+		} `json:"aisles"`
+		Todos []struct {
+			Task string   `json:"task"`
+			Tags []string `json:"tags"`
+		} `json:"todos"`
+	}
+	body := fiber.Map{
+		"recipes": []fiber.Map{{"id": pancakes.Recipe.ID, "servings": 4}, {"id": bread.Recipe.ID}},
+		"project": "Groceries",
+	}
+	api.expect(http.StatusOK, "POST", "/recipe/shoppinglist", token, body, &list)
+	items := map[string]item{}
+	for _, aisle := range list.Aisles {
+		for _, item := range aisle.Items {
+			items[item.Item] = item
+		}
+	}
+	if flour := items["flour"]; flour.Quantity != 900 || flour.Unit != "g" || len(flour.Recipes) != 2 {
+		t.Errorf("flour = %+v, want 900 g for both recipes", flour)
+	}
+	if eggs := items["eggs"]; eggs.Quantity != 5 || eggs.Unit != "" {
+		t.Errorf("eggs = %+v, want 5", eggs)
+	}
+	if len(list.Todos) != len(items) {
+		t.Errorf("got %d todos for %d items", len(list.Todos), len(items))
+	}
+
+	// A list whose todos cannot all be created leaves no project behind
+	todos.left = 1
+	body["project"] = "Half done"
+	api.expect(http.StatusInternalServerError, "POST", "/recipe/shoppinglist", token, body, nil)
+	var projects []struct {
+		Name string `json:"name"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/getprojects", token, nil, &projects)
+	if len(projects) != 1 || projects[0].Name != "Groceries" {
+		t.Errorf("projects = %+v, want only Groceries", projects)
+	}
+	var page struct {
+		Items []struct{} `json:"items"`
+	}
+	api.expect(http.StatusOK, "GET", "/api/gettodo", token, nil, &page)
+	if len(page.Items) != len(list.Todos) {
+		t.Errorf("%d todos left, want the %d of Groceries", len(page.Items), len(list.Todos))
+	}
+}